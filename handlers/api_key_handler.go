@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/letenk/pokedex/models/domain"
+	"github.com/letenk/pokedex/models/web"
+	"github.com/letenk/pokedex/usecase"
+)
+
+type apiKeyHandler struct {
+	usecase usecase.APIKeyUsecase
+}
+
+func NewHandlerAPIKey(usecase usecase.APIKeyUsecase) *apiKeyHandler {
+	return &apiKeyHandler{usecase}
+}
+
+func (h *apiKeyHandler) FindAll(c *gin.Context) {
+	// Get current user login
+	currentUser := c.MustGet("currentUser").(domain.User)
+
+	// Find all api keys of user login
+	apiKeys, err := h.usecase.FindAll(c.Request.Context(), currentUser.ID)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"bad request",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Create format response
+	response := web.JSONResponseWithData(
+		http.StatusOK,
+		"success",
+		"list of api keys",
+		web.FormatAPIKeysResponse(apiKeys),
+	)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *apiKeyHandler) Create(c *gin.Context) {
+	// Get current user login
+	currentUser := c.MustGet("currentUser").(domain.User)
+
+	// Get payload body
+	var req web.APIKeyCreateRequest
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"create api key failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Create
+	newAPIKey, key, err := h.usecase.Create(c.Request.Context(), currentUser, req)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"create api key failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Create format response, the key is only shown here
+	response := web.JSONResponseWithData(
+		http.StatusCreated,
+		"success",
+		"api key has been created",
+		web.FormatAPIKeyCreatedResponse(newAPIKey, key),
+	)
+	c.JSON(http.StatusCreated, response)
+}
+
+func (h *apiKeyHandler) Revoke(c *gin.Context) {
+	// Get current user login
+	currentUser := c.MustGet("currentUser").(domain.User)
+
+	// Get id api key from path
+	var apiKeyID web.APIKeyURI
+	err := c.ShouldBindUri(&apiKeyID)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"bad request",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	h.revoke(c, currentUser.ID, apiKeyID.ID)
+}
+
+func (h *apiKeyHandler) FindAllByUser(c *gin.Context) {
+	// Get id user from path
+	var userID web.UserURI
+	err := c.ShouldBindUri(&userID)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"bad request",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Find all api keys of user
+	apiKeys, err := h.usecase.FindAll(c.Request.Context(), userID.ID)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"bad request",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Create format response
+	response := web.JSONResponseWithData(
+		http.StatusOK,
+		"success",
+		"list of api keys",
+		web.FormatAPIKeysResponse(apiKeys),
+	)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *apiKeyHandler) RevokeByUser(c *gin.Context) {
+	// Get id user and id api key from path
+	var uri web.UserAPIKeyURI
+	err := c.ShouldBindUri(&uri)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"bad request",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	h.revoke(c, uri.ID, uri.KeyID)
+}
+
+// revoke api key of user and write the response
+func (h *apiKeyHandler) revoke(c *gin.Context, userID string, id string) {
+	apiKey, err := h.usecase.Revoke(c.Request.Context(), userID, id)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusNotFound,
+			"error",
+			"revoke api key failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusNotFound, response)
+		return
+	}
+
+	// Create format response
+	response := web.JSONResponseWithData(
+		http.StatusOK,
+		"success",
+		"api key revoked",
+		web.FormatAPIKeyResponse(apiKey),
+	)
+	c.JSON(http.StatusOK, response)
+}
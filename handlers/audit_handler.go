@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/letenk/pokedex/models/web"
+	"github.com/letenk/pokedex/usecase"
+)
+
+type auditHandler struct {
+	usecase usecase.AuditUsecase
+}
+
+func NewHandlerAudit(usecase usecase.AuditUsecase) *auditHandler {
+	return &auditHandler{usecase}
+}
+
+func (h *auditHandler) FindAll(c *gin.Context) {
+	// Get query
+	var queryParameter web.AuditQueryRequest
+	err := c.ShouldBindQuery(&queryParameter)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"bad request",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Find all audit logs
+	auditLogs, pagination, err := h.usecase.FindAll(c.Request.Context(), queryParameter)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"bad request",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	setPaginationLink(c, pagination)
+
+	// Create format response
+	response := web.JSONResponseWithPagination(
+		http.StatusOK,
+		"success",
+		"list of audit logs",
+		web.FormatAuditLogsResponse(auditLogs),
+		pagination,
+	)
+	c.JSON(http.StatusOK, response)
+}
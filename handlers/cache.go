@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/letenk/pokedex/usecase"
+)
+
+// Time to live of cached response
+const cacheTTL = time.Hour
+
+// getCache read cached value of key into dest, return false when not cached
+func getCache(ctx context.Context, cache usecase.Cache, key string, dest interface{}) bool {
+	value, err := cache.Get(ctx, key)
+	if err != nil {
+		if err != usecase.ErrCacheMiss {
+			log.Println("cannot read cache:", err)
+		}
+		return false
+	}
+
+	return json.Unmarshal(value, dest) == nil
+}
+
+// setCache write value of key before response, failed write is only logged
+func setCache(ctx context.Context, cache usecase.Cache, key string, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		log.Println("cannot encode cache:", err)
+		return
+	}
+
+	err = cache.Set(ctx, key, data, cacheTTL)
+	if err != nil {
+		log.Println("cannot write cache:", err)
+	}
+}
+
+// removeCache remove keys from cache before response, so the next read of every instance get new data
+func removeCache(ctx context.Context, cache usecase.Cache, keys ...string) {
+	err := cache.Delete(ctx, keys...)
+	if err != nil {
+		log.Println("cannot remove cache:", err)
+	}
+}
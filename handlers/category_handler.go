@@ -2,42 +2,29 @@ package handlers
 
 import (
 	"net/http"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/letenk/pokedex/models/domain"
 	"github.com/letenk/pokedex/models/web"
 	"github.com/letenk/pokedex/usecase"
 )
 
 type categoryHandler struct {
-	usecase usecase.CategoryUsecase
+	usecase      usecase.CategoryUsecase
+	cache        usecase.Cache
+	monsterCache usecase.MonsterCacheInvalidator
 }
 
-func NewHandlerCategory(usecase usecase.CategoryUsecase) *categoryHandler {
-	return &categoryHandler{usecase}
+func NewHandlerCategory(usecase usecase.CategoryUsecase, cache usecase.Cache, monsterCache usecase.MonsterCacheInvalidator) *categoryHandler {
+	return &categoryHandler{usecase, cache, monsterCache}
 }
 
 func (h *categoryHandler) FindAll(c *gin.Context) {
-	// Check Authorization
-	// Get current user login
-	currentUser := c.MustGet("currentUser").(domain.User)
-	if currentUser.Role != "admin" {
-		response := web.JSONResponseWithoutData(
-			http.StatusForbidden,
-			"error",
-			"forbidden",
-		)
-		c.JSON(http.StatusForbidden, response)
-		return
-	}
-
 	// Create context
 	ctx := c.Request.Context()
 
 	key := "categories"
-	categories, err := cache.Get(key)
-	if err == notFound {
+	var categories []web.CategoryResponse
+	if !getCache(ctx, h.cache, key, &categories) {
 		// Get all
 		categories, err := h.usecase.FindAll(ctx)
 		if err != nil {
@@ -53,7 +40,7 @@ func (h *categoryHandler) FindAll(c *gin.Context) {
 		formatResponseJSON := web.FormatCategoriesResponse(categories)
 
 		// Cache data
-		go cache.SetWithTTL(key, formatResponseJSON, time.Hour)
+		setCache(ctx, h.cache, key, formatResponseJSON)
 
 		jsonResponse := web.JSONResponseWithData(
 			http.StatusOK,
@@ -73,3 +60,205 @@ func (h *categoryHandler) FindAll(c *gin.Context) {
 	)
 	c.JSON(http.StatusOK, jsonResponse)
 }
+
+func (h *categoryHandler) FindByID(c *gin.Context) {
+	// Get id category from path
+	var categoryID web.CategoryURI
+	err := c.ShouldBindUri(&categoryID)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"bad request",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Find by id
+	category, err := h.usecase.FindByID(c.Request.Context(), categoryID.ID)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"bad request",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	jsonResponse := web.JSONResponseWithData(
+		http.StatusOK,
+		"success",
+		"detail of category",
+		web.FormatCategoryResponse(category),
+	)
+	c.JSON(http.StatusOK, jsonResponse)
+}
+
+func (h *categoryHandler) Create(c *gin.Context) {
+	// Get payload body
+	var req web.CategoryRequest
+	err := c.ShouldBind(&req)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"create category failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Create
+	newCategory, err := h.usecase.Create(c.Request.Context(), req)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"create category failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Remove cache
+	removeCache(c.Request.Context(), h.cache, "categories")
+
+	response := web.JSONResponseWithData(
+		http.StatusCreated,
+		"success",
+		"Category has been created",
+		web.FormatCategoryResponse(newCategory),
+	)
+	c.JSON(http.StatusCreated, response)
+}
+
+func (h *categoryHandler) Update(c *gin.Context) {
+	// Get id category from path
+	var categoryID web.CategoryURI
+	err := c.ShouldBindUri(&categoryID)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"update category failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Get payload body
+	var req web.CategoryRequest
+	err = c.ShouldBind(&req)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"update category failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Rename
+	categoryUpdated, err := h.usecase.Update(c.Request.Context(), categoryID.ID, req)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"update category failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Remove cache, category name is also in response of monsters
+	removeCache(c.Request.Context(), h.cache, "categories")
+	h.monsterCache.InvalidateMonsters(c.Request.Context())
+
+	response := web.JSONResponseWithData(
+		http.StatusOK,
+		"success",
+		"Update category success",
+		web.FormatCategoryResponse(categoryUpdated),
+	)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *categoryHandler) Delete(c *gin.Context) {
+	// Get id category from path
+	var categoryID web.CategoryURI
+	err := c.ShouldBindUri(&categoryID)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"delete category failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Get query, category for reassign the monsters
+	var req web.CategoryDeleteRequest
+	err = c.ShouldBindQuery(&req)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"delete category failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Delete
+	_, err = h.usecase.Delete(c.Request.Context(), categoryID.ID, req.ReassignTo)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"delete category failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Remove cache, monsters can be moved into other category
+	removeCache(c.Request.Context(), h.cache, "categories")
+	h.monsterCache.InvalidateMonsters(c.Request.Context())
+
+	response := web.JSONResponseWithoutData(
+		http.StatusOK,
+		"success",
+		"category deleted",
+	)
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/letenk/pokedex/models/web"
+)
+
+// Max age of public response in cache of client and CDN
+const publicMaxAge = "public, max-age=60"
+
+// Response of user login must not be stored by shared cache, client always revalidate with ETag
+const privateNoCache = "private, no-cache"
+
+// conditionalResponse describe validators of a GET response
+type conditionalResponse struct {
+	// Last time resource or any embedded entity is modified, zero when it cannot be known
+	// then Last-Modified is not sent and If-Modified-Since is not honoured
+	LastModified time.Time
+	// Public is true when response is same for every client (guest)
+	Public bool
+}
+
+// writeConditionalJSON write response with ETag, Last-Modified and Cache-Control,
+// answer 304 Not Modified without body when the client has the same representation
+func writeConditionalJSON(c *gin.Context, cond conditionalResponse, response interface{}) {
+	etag, body, err := responseETag(response)
+	if err != nil {
+		log.Println("cannot encode response:", err)
+		response := web.JSONResponseWithoutData(
+			http.StatusInternalServerError,
+			"error",
+			"internal server error",
+		)
+		c.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	c.Header("ETag", etag)
+	// Body depends on credential of user login (catched)
+	c.Header("Vary", "Authorization, X-API-Key")
+	if cond.Public {
+		c.Header("Cache-Control", publicMaxAge)
+	} else {
+		c.Header("Cache-Control", privateNoCache)
+	}
+
+	// Header date only has precision of second
+	lastModified := cond.LastModified.UTC().Truncate(time.Second)
+	if !cond.LastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.Format(http.TimeFormat))
+	}
+
+	if notModified(c.Request, etag, lastModified) {
+		c.Status(http.StatusNotModified)
+		c.Writer.WriteHeaderNow()
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// responseETag encode response, return strong ETag from hash of body and the body
+func responseETag(response interface{}) (string, []byte, error) {
+	body, err := json.Marshal(response)
+	if err != nil {
+		return "", nil, err
+	}
+
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, body, nil
+}
+
+// matchIfMatch evaluate header `If-Match` against current ETag, If-Match use strong comparison
+func matchIfMatch(ifMatch string, etag string) bool {
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified evaluate If-None-Match, If-Modified-Since is only evaluated when If-None-Match is absent (RFC 7232 section 6)
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	ifNoneMatch := r.Header.Get("If-None-Match")
+	if ifNoneMatch != "" {
+		for _, tag := range strings.Split(ifNoneMatch, ",") {
+			tag = strings.TrimSpace(tag)
+			// If-None-Match use weak comparison
+			if tag == "*" || (tag != "" && strings.TrimPrefix(tag, "W/") == etag) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if lastModified.IsZero() {
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	return !lastModified.After(since)
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/letenk/pokedex/models/web"
+	"github.com/letenk/pokedex/usecase"
+)
+
+type evolutionHandler struct {
+	usecase      usecase.EvolutionUsecase
+	monsterCache usecase.MonsterCacheInvalidator
+}
+
+func NewHandlerEvolution(usecase usecase.EvolutionUsecase, monsterCache usecase.MonsterCacheInvalidator) *evolutionHandler {
+	return &evolutionHandler{usecase, monsterCache}
+}
+
+func (h *evolutionHandler) Create(c *gin.Context) {
+	// Get payload body
+	var req web.EvolutionCreateRequest
+	err := c.ShouldBind(&req)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"create evolution failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Create, with cycle detection
+	newEvolution, err := h.usecase.Create(c.Request.Context(), req)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"create evolution failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Remove cache of monsters, evolution chain is in the detail
+	h.monsterCache.InvalidateMonsters(c.Request.Context())
+
+	response := web.JSONResponseWithData(
+		http.StatusCreated,
+		"success",
+		"Evolution has been created",
+		web.FormatEvolutionResponse(newEvolution),
+	)
+	c.JSON(http.StatusCreated, response)
+}
+
+func (h *evolutionHandler) Update(c *gin.Context) {
+	// Get id evolution from path
+	var evolutionID web.EvolutionURI
+	err := c.ShouldBindUri(&evolutionID)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"update evolution failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Get payload body
+	var req web.EvolutionTriggerRequest
+	err = c.ShouldBind(&req)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"update evolution failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Update trigger
+	evolutionUpdated, err := h.usecase.Update(c.Request.Context(), evolutionID.ID, req)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"update evolution failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Remove cache of monsters, evolution chain is in the detail
+	h.monsterCache.InvalidateMonsters(c.Request.Context())
+
+	response := web.JSONResponseWithData(
+		http.StatusOK,
+		"success",
+		"Update evolution success",
+		web.FormatEvolutionResponse(evolutionUpdated),
+	)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *evolutionHandler) Delete(c *gin.Context) {
+	// Get id evolution from path
+	var evolutionID web.EvolutionURI
+	err := c.ShouldBindUri(&evolutionID)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"delete evolution failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Delete
+	_, err = h.usecase.Delete(c.Request.Context(), evolutionID.ID)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"delete evolution failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Remove cache of monsters, evolution chain is in the detail
+	h.monsterCache.InvalidateMonsters(c.Request.Context())
+
+	response := web.JSONResponseWithoutData(
+		http.StatusOK,
+		"success",
+		"evolution deleted",
+	)
+	c.JSON(http.StatusOK, response)
+}
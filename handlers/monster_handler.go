@@ -1,9 +1,11 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"mime/multipart"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -40,19 +42,18 @@ func validateUploadFiles(fileHeader *multipart.FileHeader) (bool, string) {
 	return true, "ok"
 }
 
+// optionalCurrentUser get user login, return empty user when guest
+func optionalCurrentUser(c *gin.Context) domain.User {
+	currentUser, ok := c.Get("currentUser")
+	if !ok {
+		return domain.User{}
+	}
+	return currentUser.(domain.User)
+}
+
 func (h *monsterHandler) Create(c *gin.Context) {
-	// Check Authorization
 	// Get current user login
 	currentUser := c.MustGet("currentUser").(domain.User)
-	if currentUser.Role != "admin" {
-		response := web.JSONResponseWithoutData(
-			http.StatusForbidden,
-			"error",
-			"forbidden",
-		)
-		c.JSON(http.StatusForbidden, response)
-		return
-	}
 
 	// Get payload body
 	var req web.MonsterCreateRequest
@@ -104,7 +105,7 @@ func (h *monsterHandler) Create(c *gin.Context) {
 	fileName := fmt.Sprintf(`%s_%v_%s`, currentUser.ID, nowRFC3339, fileHeader.Filename)
 
 	// Create new user
-	newMonster, err := h.usecase.Create(c.Request.Context(), req, file, fileName)
+	_, err = h.usecase.Create(c.Request.Context(), req, file, fileName)
 	if err != nil {
 		errorMessage := gin.H{"errors": err.Error()}
 		response := web.JSONResponseWithData(
@@ -117,13 +118,6 @@ func (h *monsterHandler) Create(c *gin.Context) {
 		return
 	}
 
-	// Cache for get detail
-	formatResponseJSON := web.FormatMonsterResponseDetail(newMonster)
-	key := fmt.Sprintf("monster_id_%s", newMonster.ID)
-	go cache.SetWithTTL(key, formatResponseJSON, time.Hour)
-	// Remove cache
-	go cache.Remove("monsters")
-
 	// Create format response
 	response := web.JSONResponseWithoutData(
 		http.StatusCreated,
@@ -134,92 +128,101 @@ func (h *monsterHandler) Create(c *gin.Context) {
 }
 
 func (h *monsterHandler) FindAll(c *gin.Context) {
+	// Get current user login, empty when guest
+	currentUser := optionalCurrentUser(c)
+
 	// Get query
 	var queryParameter web.MonsterQueryRequest
-	err := c.Bind(&queryParameter)
+	err := c.ShouldBind(&queryParameter)
 	if err != nil {
-		response := web.JSONResponseWithoutData(
-			http.StatusInternalServerError,
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
 			"error",
-			"internal server error",
+			"bad request",
+			errorMessage,
 		)
-		c.JSON(http.StatusInternalServerError, response)
+		c.JSON(http.StatusBadRequest, response)
 		return
 	}
+	queryParameter.UserID = currentUser.ID
 
-	if queryParameter.Name != "" || queryParameter.Catched != "" || queryParameter.Sort != "" || queryParameter.Order != "" || len(queryParameter.Types) != 0 {
-		// Find all montser
-		monsters, err := h.usecase.FindAll(c.Request.Context(), queryParameter)
-		if err != nil {
-			errorMessage := gin.H{"errors": err.Error()}
-			response := web.JSONResponseWithData(
-				http.StatusBadRequest,
-				"error",
-				"bad request",
-				errorMessage,
-			)
-			c.JSON(http.StatusBadRequest, response)
-			return
-		}
-
-		formatResponseJSON := web.FormatMonsterResponseList(monsters)
-
-		// Create format response
+	// Find all montser
+	monsters, pagination, err := h.usecase.FindAll(c.Request.Context(), queryParameter)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
 		response := web.JSONResponseWithData(
-			http.StatusOK,
-			"success",
-			"List of monsters",
-			formatResponseJSON,
+			http.StatusBadRequest,
+			"error",
+			"bad request",
+			errorMessage,
 		)
-
-		c.JSON(http.StatusOK, response)
+		c.JSON(http.StatusBadRequest, response)
 		return
 	}
 
-	key := "monsters"
-	monsters, err := cache.Get(key)
-	if err == notFound {
-		// Find all montser
-		monsters, err := h.usecase.FindAll(c.Request.Context(), queryParameter)
-		if err != nil {
-			errorMessage := gin.H{"errors": err.Error()}
-			response := web.JSONResponseWithData(
-				http.StatusBadRequest,
-				"error",
-				"bad request",
-				errorMessage,
-			)
-			c.JSON(http.StatusBadRequest, response)
-			return
-		}
+	setPaginationLink(c, pagination)
 
-		formatResponseJSON := web.FormatMonsterResponseList(monsters)
+	// Create format response
+	response := web.JSONResponseWithPagination(
+		http.StatusOK,
+		"success",
+		"List of monsters",
+		web.FormatMonsterResponseList(monsters),
+		pagination,
+	)
 
-		// Cache data
-		go cache.SetWithTTL(key, formatResponseJSON, time.Hour)
+	// Deleted monster leave no trace in the page, so the list has no Last-Modified and only revalidate with ETag
+	writeConditionalJSON(c, conditionalResponse{
+		Public: currentUser.ID == "",
+	}, response)
+}
 
-		// Create format response
-		response := web.JSONResponseWithData(
-			http.StatusOK,
-			"success",
-			"List of monsters",
-			formatResponseJSON,
-		)
+// setPaginationLink set header `Link` with url of first, prev, next and last page
+func setPaginationLink(c *gin.Context, pagination web.Pagination) {
+	var links []string
+
+	addLink := func(rel string, page int, cursor string) {
+		url := *c.Request.URL
+		query := url.Query()
+		query.Del("page")
+		query.Del("cursor")
+		if page != 0 {
+			query.Set("page", strconv.Itoa(page))
+		}
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+		url.RawQuery = query.Encode()
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, url.RequestURI(), rel))
+	}
 
-		c.JSON(http.StatusOK, response)
-		return
+	if pagination.Page != 0 {
+		// Offset mode
+		addLink("first", 1, "")
+		if pagination.Page > 1 {
+			addLink("prev", pagination.Page-1, "")
+		}
+		if pagination.Page < pagination.TotalPages {
+			addLink("next", pagination.Page+1, "")
+		}
+		if pagination.TotalPages > 0 {
+			addLink("last", pagination.TotalPages, "")
+		}
+	} else if pagination.NextCursor != "" {
+		// Cursor mode
+		addLink("next", 0, pagination.NextCursor)
 	}
 
-	jsonResponse := web.JSONResponseWithData(
-		http.StatusOK,
-		"success",
-		"List of monsters",
-		monsters,
-	)
-	c.JSON(http.StatusOK, jsonResponse)
+	if len(links) != 0 {
+		c.Header("Link", strings.Join(links, ", "))
+	}
 }
 
 func (h *monsterHandler) FindByID(c *gin.Context) {
+	// Get current user login, empty when guest
+	currentUser := optionalCurrentUser(c)
+
 	var monsterID web.MosterURI
 	err := c.ShouldBindUri(&monsterID)
 	if err != nil {
@@ -232,65 +235,155 @@ func (h *monsterHandler) FindByID(c *gin.Context) {
 		return
 	}
 
-	// Get from cache
-	key := fmt.Sprintf("monster_id_%s", monsterID.ID)
-	monsters, err := cache.Get(key)
-	if err == notFound {
-		// Find by id monster from database
-		monsters, err := h.usecase.FindByID(c.Request.Context(), monsterID.ID)
-		if err != nil {
-			errorMessage := gin.H{"errors": err.Error()}
+	// Find by id monster, catched is relative to user login
+	monster, err := h.usecase.FindByID(c.Request.Context(), monsterID.ID, currentUser.ID)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"bad request",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Create format response
+	response := monsterDetailResponse(monster)
+
+	// Catched is changed by release without any trace, so response of user login only revalidate with ETag
+	cond := conditionalResponse{Public: currentUser.ID == ""}
+	if cond.Public {
+		cond.LastModified = monsterLastModified(monster)
+	}
+	writeConditionalJSON(c, cond, response)
+}
+
+// monsterLastModified return last update of monster and every entity in the detail,
+// removed type and evolution step touch the monsters, so they are also seen here
+func monsterLastModified(monster domain.Monster) time.Time {
+	lastModified := monster.UpdatedAt
+	latest := func(t time.Time) {
+		if t.After(lastModified) {
+			lastModified = t
+		}
+	}
+
+	latest(monster.Category.UpdatedAt)
+	for _, t := range monster.Types {
+		latest(t.UpdatedAt)
+	}
+	for _, evolution := range monster.Evolutions {
+		latest(evolution.UpdatedAt)
+		latest(evolution.FromMonster.UpdatedAt)
+		latest(evolution.ToMonster.UpdatedAt)
+	}
+
+	return lastModified
+}
+
+// monsterDetailResponse return response of find by id monster, ETag of If-Match is computed from this response
+func monsterDetailResponse(monster domain.Monster) web.ResponseWithData {
+	return web.JSONResponseWithData(
+		http.StatusOK,
+		"success",
+		"profile detail of monsters",
+		web.FormatMonsterResponseDetail(monster),
+	)
+}
+
+// expectedVersion return version of monster expected by request, from header `If-Match` or field version.
+// Response is written and false is returned when the precondition is missing or failed
+func (h *monsterHandler) expectedVersion(c *gin.Context, ID string, userID string, version *uint, message string) (*uint, bool) {
+	ifMatch := c.GetHeader("If-Match")
+	if ifMatch == "" {
+		if version == nil {
+			errorMessage := gin.H{"errors": "header If-Match or field version is required"}
 			response := web.JSONResponseWithData(
-				http.StatusBadRequest,
+				http.StatusPreconditionRequired,
 				"error",
-				"bad request",
+				message,
 				errorMessage,
 			)
-			c.JSON(http.StatusBadRequest, response)
-			return
+			c.JSON(http.StatusPreconditionRequired, response)
+			return nil, false
 		}
+		return version, true
+	}
+
+	// Compare with ETag of current representation for the user login
+	currentMonster, err := h.usecase.FindByID(c.Request.Context(), ID, userID)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			message,
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return nil, false
+	}
 
-		// Cache for get detail
-		formatResponseJSON := web.FormatMonsterResponseDetail(monsters)
-		key := fmt.Sprintf("monster_id_%s", monsterID.ID)
-		go cache.SetWithTTL(key, formatResponseJSON, time.Hour)
+	etag, _, err := responseETag(monsterDetailResponse(currentMonster))
+	if err != nil || !matchIfMatch(ifMatch, etag) {
+		responseMonsterConflict(c, http.StatusPreconditionFailed, currentMonster)
+		return nil, false
+	}
+
+	return &currentMonster.Version, true
+}
 
-		// Create format response
+// responseVersionConflict write response 412 or 409 with current representation when version is stale,
+// return false when error is not a version conflict
+func (h *monsterHandler) responseVersionConflict(c *gin.Context, err error, ID string, userID string) bool {
+	if !errors.Is(err, domain.ErrMonsterVersionConflict) {
+		return false
+	}
+
+	// Stale If-Match is precondition failed, stale field version is conflict
+	status := http.StatusConflict
+	if c.GetHeader("If-Match") != "" {
+		status = http.StatusPreconditionFailed
+	}
+
+	currentMonster, findErr := h.usecase.FindByID(c.Request.Context(), ID, userID)
+	if findErr != nil {
+		errorMessage := gin.H{"errors": findErr.Error()}
 		response := web.JSONResponseWithData(
-			http.StatusOK,
-			"success",
-			"profile detail of monsters",
-			formatResponseJSON,
+			http.StatusBadRequest,
+			"error",
+			"bad request",
+			errorMessage,
 		)
+		c.JSON(http.StatusBadRequest, response)
+		return true
+	}
 
-		c.JSON(http.StatusOK, response)
-		return
+	responseMonsterConflict(c, status, currentMonster)
+	return true
+}
+
+// responseMonsterConflict write current representation of monster with its ETag, so client can retry
+func responseMonsterConflict(c *gin.Context, status int, currentMonster domain.Monster) {
+	etag, _, err := responseETag(monsterDetailResponse(currentMonster))
+	if err == nil {
+		c.Header("ETag", etag)
 	}
 
-	// Create format response
 	response := web.JSONResponseWithData(
-		http.StatusOK,
-		"success",
-		"profile detail of monsters",
-		monsters,
+		status,
+		"error",
+		domain.ErrMonsterVersionConflict.Error(),
+		web.FormatMonsterResponseDetail(currentMonster),
 	)
-
-	c.JSON(http.StatusOK, response)
+	c.JSON(status, response)
 }
 
 func (h *monsterHandler) Update(c *gin.Context) {
-	// Check Authorization
 	// Get current user login
 	currentUser := c.MustGet("currentUser").(domain.User)
-	if currentUser.Role != "admin" {
-		response := web.JSONResponseWithoutData(
-			http.StatusForbidden,
-			"error",
-			"forbidden",
-		)
-		c.JSON(http.StatusForbidden, response)
-		return
-	}
 
 	// Get id monster from path
 	var monsterID web.MosterURI
@@ -357,8 +450,18 @@ func (h *monsterHandler) Update(c *gin.Context) {
 		fileName = fmt.Sprintf(`%s_%v_%s`, currentUser.ID, nowRFC3339, fileHeader.Filename)
 	}
 
+	// Version of monster known by client
+	version, ok := h.expectedVersion(c, monsterID.ID, currentUser.ID, reqUpdate.Version, "update monster failed")
+	if !ok {
+		return
+	}
+	reqUpdate.Version = version
+
 	// Update
 	monsterUpdated, err := h.usecase.Update(c.Request.Context(), monsterID.ID, reqUpdate, file, fileName)
+	if h.responseVersionConflict(c, err, monsterID.ID, currentUser.ID) {
+		return
+	}
 	if err != nil {
 		errorMessage := gin.H{"errors": err.Error()}
 		response := web.JSONResponseWithData(
@@ -371,14 +474,7 @@ func (h *monsterHandler) Update(c *gin.Context) {
 		return
 	}
 
-	// Cache for get detail
 	formatResponseJSON := web.FormatMonsterResponseDetail(monsterUpdated)
-	key := fmt.Sprintf("monster_id_%s", monsterID.ID)
-	// Remove cache
-	go cache.Remove(key)
-	go cache.SetWithTTL(key, formatResponseJSON, time.Hour)
-	// Remove cache
-	go cache.Remove("monsters")
 
 	// Create format response
 	response := web.JSONResponseWithData(
@@ -392,18 +488,8 @@ func (h *monsterHandler) Update(c *gin.Context) {
 }
 
 func (h *monsterHandler) UpdateMarkMonsterCaptured(c *gin.Context) {
-	// Check Authorization
 	// Get current user login
 	currentUser := c.MustGet("currentUser").(domain.User)
-	if currentUser.Role != "user" {
-		response := web.JSONResponseWithoutData(
-			http.StatusForbidden,
-			"error",
-			"forbidden",
-		)
-		c.JSON(http.StatusForbidden, response)
-		return
-	}
 
 	// Get id monster from path
 	var monsterID web.MosterURI
@@ -434,8 +520,18 @@ func (h *monsterHandler) UpdateMarkMonsterCaptured(c *gin.Context) {
 		return
 	}
 
+	// Version of monster known by client
+	version, ok := h.expectedVersion(c, monsterID.ID, currentUser.ID, reqUpdate.Version, "update monster captured failed")
+	if !ok {
+		return
+	}
+	reqUpdate.Version = version
+
 	// Update
-	_, err = h.usecase.UpdateMarkMonsterCaptured(c.Request.Context(), monsterID.ID, reqUpdate)
+	_, err = h.usecase.UpdateMarkMonsterCaptured(c.Request.Context(), monsterID.ID, currentUser.ID, reqUpdate)
+	if h.responseVersionConflict(c, err, monsterID.ID, currentUser.ID) {
+		return
+	}
 	if err != nil {
 		errorMessage := gin.H{"errors": err.Error()}
 		response := web.JSONResponseWithData(
@@ -448,12 +544,6 @@ func (h *monsterHandler) UpdateMarkMonsterCaptured(c *gin.Context) {
 		return
 	}
 
-	key := fmt.Sprintf("monster_id_%s", monsterID.ID)
-	// Remove cache
-	go cache.Remove(key)
-	// Remove cache
-	go cache.Remove("monsters")
-
 	msgSuccess := fmt.Sprintf("monster with id %s updated", monsterID.ID)
 	// Create format response
 	response := web.JSONResponseWithoutData(
@@ -466,19 +556,6 @@ func (h *monsterHandler) UpdateMarkMonsterCaptured(c *gin.Context) {
 }
 
 func (h *monsterHandler) Delete(c *gin.Context) {
-	// Check Authorization
-	// Get current user login
-	currentUser := c.MustGet("currentUser").(domain.User)
-	if currentUser.Role != "admin" {
-		response := web.JSONResponseWithoutData(
-			http.StatusForbidden,
-			"error",
-			"forbidden",
-		)
-		c.JSON(http.StatusForbidden, response)
-		return
-	}
-
 	// Get id monster from path
 	var monsterID web.MosterURI
 	err := c.ShouldBindUri(&monsterID)
@@ -506,12 +583,6 @@ func (h *monsterHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	key := fmt.Sprintf("monster_id_%s", monsterID.ID)
-	// Remove cache
-	go cache.Remove(key)
-	// Remove cache
-	go cache.Remove("monsters")
-
 	// Create format response
 	response := web.JSONResponseWithoutData(
 		http.StatusOK,
@@ -521,3 +592,84 @@ func (h *monsterHandler) Delete(c *gin.Context) {
 
 	c.JSON(http.StatusOK, response)
 }
+
+func (h *monsterHandler) FindTrash(c *gin.Context) {
+	// Get query
+	var queryParameter web.MonsterTrashQueryRequest
+	err := c.ShouldBindQuery(&queryParameter)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"bad request",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Find monsters in trash
+	monsters, pagination, err := h.usecase.FindTrash(c.Request.Context(), queryParameter)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"bad request",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	setPaginationLink(c, pagination)
+
+	// Create format response
+	response := web.JSONResponseWithPagination(
+		http.StatusOK,
+		"success",
+		"List of monsters in trash",
+		web.FormatMonsterTrashResponse(monsters),
+		pagination,
+	)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *monsterHandler) Restore(c *gin.Context) {
+	// Get id monster from path
+	var monsterID web.MosterURI
+	err := c.ShouldBindUri(&monsterID)
+	if err != nil {
+		response := web.JSONResponseWithoutData(
+			http.StatusInternalServerError,
+			"error",
+			"internal server error",
+		)
+		c.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	// Restore from trash
+	monster, err := h.usecase.Restore(c.Request.Context(), monsterID.ID)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"restore monster failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Create format response
+	response := web.JSONResponseWithData(
+		http.StatusOK,
+		"success",
+		"monster restored",
+		web.FormatMonsterResponseDetail(monster),
+	)
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/letenk/pokedex/models/web"
+	"github.com/letenk/pokedex/usecase"
+)
+
+const (
+	// Cookie for keep state of login between redirect to provider and callback
+	oidcStateCookie     = "oidc_state"
+	oidcStateCookiePath = "/api/v1/oidc"
+	oidcStateCookieAge  = 600
+)
+
+type oidcHandler struct {
+	usecase usecase.OIDCUsecase
+}
+
+func NewHandlerOIDC(usecase usecase.OIDCUsecase) *oidcHandler {
+	return &oidcHandler{usecase}
+}
+
+func (h *oidcHandler) Login(c *gin.Context) {
+	// Create url of provider and state
+	authURL, signedState, err := h.usecase.Start(c.Request.Context())
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"login failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Cookie is sent back by browser on redirect from provider, so same site must be lax
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, signedState, oidcStateCookieAge, oidcStateCookiePath, "", isSecureRequest(c), true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+func (h *oidcHandler) Callback(c *gin.Context) {
+	var req web.OIDCCallbackRequest
+	err := c.ShouldBindQuery(&req)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"login failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// State can only be used once
+	req.SignedState, _ = c.Cookie(oidcStateCookie)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, "", -1, oidcStateCookiePath, "", isSecureRequest(c), true)
+
+	// Login with user of the identity
+	authToken, err := h.usecase.Callback(c.Request.Context(), req)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusUnauthorized,
+			"error",
+			"login failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusUnauthorized, response)
+		return
+	}
+
+	// Second factor is needed, exchange the mfa token on `/login/mfa`
+	if authToken.MFAToken != "" {
+		response := web.JSONResponseWithData(
+			http.StatusOK,
+			"success",
+			"mfa required",
+			web.FormatMFAPendingResponse(authToken),
+		)
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	// Create format response
+	response := web.JSONResponseWithData(
+		http.StatusOK,
+		"success",
+		"login success",
+		web.FormatTokenResponse(authToken),
+	)
+	c.JSON(http.StatusOK, response)
+}
+
+// isSecureRequest check request is https, directly or behind proxy
+func isSecureRequest(c *gin.Context) bool {
+	return c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/letenk/pokedex/models/web"
+	"github.com/letenk/pokedex/usecase"
+)
+
+type typeEffectivenessHandler struct {
+	usecase usecase.TypeEffectivenessUsecase
+}
+
+func NewHandlerTypeEffectiveness(usecase usecase.TypeEffectivenessUsecase) *typeEffectivenessHandler {
+	return &typeEffectivenessHandler{usecase}
+}
+
+func (h *typeEffectivenessHandler) Matchups(c *gin.Context) {
+	// Get id type from path
+	var typeID web.TypeURI
+	err := c.ShouldBindUri(&typeID)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"bad request",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Find matchups
+	types, effectiveness, err := h.usecase.Matchups(c.Request.Context(), typeID.ID)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"bad request",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	jsonResponse := web.JSONResponseWithData(
+		http.StatusOK,
+		"success",
+		"matchups of type",
+		web.FormatTypeMatchupsResponse(types, effectiveness),
+	)
+	c.JSON(http.StatusOK, jsonResponse)
+}
+
+func (h *typeEffectivenessHandler) Save(c *gin.Context) {
+	// Get id attacker and defender type from path
+	var matchupID web.TypeMatchupURI
+	err := c.ShouldBindUri(&matchupID)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"save matchup failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Get payload body
+	var req web.TypeEffectivenessRequest
+	err = c.ShouldBind(&req)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"save matchup failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Save
+	effectiveness, err := h.usecase.Save(c.Request.Context(), matchupID.ID, matchupID.DefenderID, req)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"save matchup failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := web.JSONResponseWithData(
+		http.StatusOK,
+		"success",
+		"Matchup has been saved",
+		web.FormatTypeEffectivenessResponse(effectiveness),
+	)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *typeEffectivenessHandler) Delete(c *gin.Context) {
+	// Get id attacker and defender type from path
+	var matchupID web.TypeMatchupURI
+	err := c.ShouldBindUri(&matchupID)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"delete matchup failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Delete
+	_, err = h.usecase.Delete(c.Request.Context(), matchupID.ID, matchupID.DefenderID)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"delete matchup failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	response := web.JSONResponseWithoutData(
+		http.StatusOK,
+		"success",
+		"matchup deleted",
+	)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *typeEffectivenessHandler) Weaknesses(c *gin.Context) {
+	// Get id monster from path
+	var monsterID web.MosterURI
+	err := c.ShouldBindUri(&monsterID)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"bad request",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Combine multipliers for all types of monster
+	monster, weaknesses, err := h.usecase.Weaknesses(c.Request.Context(), monsterID.ID)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"bad request",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	jsonResponse := web.JSONResponseWithData(
+		http.StatusOK,
+		"success",
+		"weaknesses of monster",
+		web.FormatMonsterWeaknessesResponse(monster, weaknesses),
+	)
+	c.JSON(http.StatusOK, jsonResponse)
+}
@@ -2,48 +2,29 @@ package handlers
 
 import (
 	"net/http"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/jellydator/ttlcache/v2"
-	"github.com/letenk/pokedex/models/domain"
 	"github.com/letenk/pokedex/models/web"
 	"github.com/letenk/pokedex/usecase"
 )
 
 type typeHandler struct {
-	usecase usecase.TypeUsecase
+	usecase      usecase.TypeUsecase
+	cache        usecase.Cache
+	monsterCache usecase.MonsterCacheInvalidator
 }
 
-func NewHandlerType(usecase usecase.TypeUsecase) *typeHandler {
-	return &typeHandler{usecase}
+func NewHandlerType(usecase usecase.TypeUsecase, cache usecase.Cache, monsterCache usecase.MonsterCacheInvalidator) *typeHandler {
+	return &typeHandler{usecase, cache, monsterCache}
 }
 
-var (
-	cache    ttlcache.SimpleCache = ttlcache.NewCache()
-	notFound                      = ttlcache.ErrNotFound
-)
-
 func (h *typeHandler) FindAll(c *gin.Context) {
-	// Check Authorization
-	// Get current user login
-	currentUser := c.MustGet("currentUser").(domain.User)
-	if currentUser.Role != "admin" {
-		response := web.JSONResponseWithoutData(
-			http.StatusForbidden,
-			"error",
-			"forbidden",
-		)
-		c.JSON(http.StatusForbidden, response)
-		return
-	}
-
 	// Create context
 	ctx := c.Request.Context()
 
 	key := "types"
-	types, err := cache.Get(key)
-	if err == notFound {
+	var types []web.TypeResponse
+	if !getCache(ctx, h.cache, key, &types) {
 		// Get all data from db
 		types, err := h.usecase.FindAll(ctx)
 		if err != nil {
@@ -59,7 +40,7 @@ func (h *typeHandler) FindAll(c *gin.Context) {
 		formatResponseJSON := web.FormatTypesResponse(types)
 
 		// Cache data
-		go cache.SetWithTTL(key, formatResponseJSON, time.Hour)
+		setCache(ctx, h.cache, key, formatResponseJSON)
 
 		jsonResponse := web.JSONResponseWithData(
 			http.StatusOK,
@@ -79,3 +60,205 @@ func (h *typeHandler) FindAll(c *gin.Context) {
 	)
 	c.JSON(http.StatusOK, jsonResponse)
 }
+
+func (h *typeHandler) FindByID(c *gin.Context) {
+	// Get id type from path
+	var typeID web.TypeURI
+	err := c.ShouldBindUri(&typeID)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"bad request",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Find by id
+	types, err := h.usecase.FindByID(c.Request.Context(), typeID.ID)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"bad request",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	jsonResponse := web.JSONResponseWithData(
+		http.StatusOK,
+		"success",
+		"detail of type",
+		web.FormatTypeResponse(types),
+	)
+	c.JSON(http.StatusOK, jsonResponse)
+}
+
+func (h *typeHandler) Create(c *gin.Context) {
+	// Get payload body
+	var req web.TypeRequest
+	err := c.ShouldBind(&req)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"create type failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Create
+	newType, err := h.usecase.Create(c.Request.Context(), req)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"create type failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Remove cache
+	removeCache(c.Request.Context(), h.cache, "types")
+
+	response := web.JSONResponseWithData(
+		http.StatusCreated,
+		"success",
+		"Type has been created",
+		web.FormatTypeResponse(newType),
+	)
+	c.JSON(http.StatusCreated, response)
+}
+
+func (h *typeHandler) Update(c *gin.Context) {
+	// Get id type from path
+	var typeID web.TypeURI
+	err := c.ShouldBindUri(&typeID)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"update type failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Get payload body
+	var req web.TypeRequest
+	err = c.ShouldBind(&req)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"update type failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Rename
+	typeUpdated, err := h.usecase.Update(c.Request.Context(), typeID.ID, req)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"update type failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Remove cache, type name is also in response of monsters
+	removeCache(c.Request.Context(), h.cache, "types")
+	h.monsterCache.InvalidateMonsters(c.Request.Context())
+
+	response := web.JSONResponseWithData(
+		http.StatusOK,
+		"success",
+		"Update type success",
+		web.FormatTypeResponse(typeUpdated),
+	)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *typeHandler) Delete(c *gin.Context) {
+	// Get id type from path
+	var typeID web.TypeURI
+	err := c.ShouldBindUri(&typeID)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"delete type failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Get query, cascade for remove the type from monsters
+	var req web.TypeDeleteRequest
+	err = c.ShouldBindQuery(&req)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"delete type failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Delete
+	_, err = h.usecase.Delete(c.Request.Context(), typeID.ID, req.Cascade)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"delete type failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Remove cache, type can be removed from monsters
+	removeCache(c.Request.Context(), h.cache, "types")
+	h.monsterCache.InvalidateMonsters(c.Request.Context())
+
+	response := web.JSONResponseWithoutData(
+		http.StatusOK,
+		"success",
+		"type deleted",
+	)
+	c.JSON(http.StatusOK, response)
+}
@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/letenk/pokedex/models/domain"
 	"github.com/letenk/pokedex/models/web"
 	"github.com/letenk/pokedex/usecase"
 )
@@ -33,8 +36,15 @@ func (h *userHandler) Login(c *gin.Context) {
 		return
 	}
 
-	// Login
-	token, err := h.usecase.Login(c.Request.Context(), req)
+	// Login, failed attempts are counted per username and per ip
+	req.IP = c.ClientIP()
+	authToken, err := h.usecase.Login(c.Request.Context(), req)
+
+	// Too many failed attempts
+	if responseLoginLocked(c, err) {
+		return
+	}
+
 	if err != nil {
 		errorMessage := gin.H{"errors": err.Error()}
 		response := web.JSONResponseWithData(
@@ -47,13 +57,874 @@ func (h *userHandler) Login(c *gin.Context) {
 		return
 	}
 
-	fieldToken := gin.H{"token": token}
+	// Second factor is needed, exchange the mfa token on `/login/mfa`
+	if authToken.MFAToken != "" {
+		response := web.JSONResponseWithData(
+			http.StatusOK,
+			"success",
+			"mfa required",
+			web.FormatMFAPendingResponse(authToken),
+		)
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	// Create format response
+	response := web.JSONResponseWithData(
+		http.StatusOK,
+		"success",
+		"login success",
+		web.FormatTokenResponse(authToken),
+	)
+	c.JSON(http.StatusOK, response)
+}
+
+// responseLoginLocked write response 429 with header `Retry-After` when error is LoginLockedError
+func responseLoginLocked(c *gin.Context, err error) bool {
+	var lockedErr *usecase.LoginLockedError
+	if !errors.As(err, &lockedErr) {
+		return false
+	}
+
+	c.Header("Retry-After", strconv.Itoa(lockedErr.RetryAfterSeconds()))
+	errorMessage := gin.H{"errors": err.Error()}
+	response := web.JSONResponseWithData(
+		http.StatusTooManyRequests,
+		"error",
+		"login failed",
+		errorMessage,
+	)
+	c.JSON(http.StatusTooManyRequests, response)
+	return true
+}
+
+func (h *userHandler) LoginMFA(c *gin.Context) {
+	var req web.MFALoginRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"login failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Exchange mfa token and code
+	authToken, recoveryCodes, err := h.usecase.LoginMFA(c.Request.Context(), req)
+
+	// Too many failed code
+	if responseLoginLocked(c, err) {
+		return
+	}
+
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusUnauthorized,
+			"error",
+			"login failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusUnauthorized, response)
+		return
+	}
+
+	// Recovery codes only exist when MFA is enabled by this login
+	formatter := web.FormatTokenResponse(authToken)
+	formatter.RecoveryCodes = recoveryCodes
+
 	// Create format response
 	response := web.JSONResponseWithData(
 		http.StatusOK,
 		"success",
 		"login success",
-		fieldToken,
+		formatter,
+	)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) EnrollMFAWithToken(c *gin.Context) {
+	var req web.MFAEnrollLoginRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"enroll mfa failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Enroll
+	enroll, err := h.usecase.EnrollMFAWithToken(c.Request.Context(), req)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusUnauthorized,
+			"error",
+			"enroll mfa failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusUnauthorized, response)
+		return
+	}
+
+	// Create format response
+	response := web.JSONResponseWithData(
+		http.StatusOK,
+		"success",
+		"scan the otpauth uri and login with the code",
+		enroll,
+	)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) Register(c *gin.Context) {
+	var req web.UserRegisterRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"register failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Register
+	newUser, err := h.usecase.Register(c.Request.Context(), req)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"register failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Create format response
+	response := web.JSONResponseWithData(
+		http.StatusCreated,
+		"success",
+		"register success",
+		web.FormatUserResponse(newUser),
+	)
+	c.JSON(http.StatusCreated, response)
+}
+
+func (h *userHandler) Me(c *gin.Context) {
+	// Get current user login
+	currentUser := c.MustGet("currentUser").(domain.User)
+
+	// Create format response
+	response := web.JSONResponseWithData(
+		http.StatusOK,
+		"success",
+		"profile of user",
+		web.FormatUserResponse(currentUser),
+	)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) UpdateProfile(c *gin.Context) {
+	// Get current user login
+	currentUser := c.MustGet("currentUser").(domain.User)
+
+	var req web.UserUpdateRequest
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"update profile failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Update
+	userUpdated, err := h.usecase.UpdateProfile(c.Request.Context(), currentUser.ID, req)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"update profile failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Create format response
+	response := web.JSONResponseWithData(
+		http.StatusOK,
+		"success",
+		"update profile success",
+		web.FormatUserResponse(userUpdated),
+	)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) ChangePassword(c *gin.Context) {
+	// Get current user login
+	currentUser := c.MustGet("currentUser").(domain.User)
+
+	var req web.UserChangePasswordRequest
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"change password failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Change password
+	_, err = h.usecase.ChangePassword(c.Request.Context(), currentUser.ID, req)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"change password failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Create format response
+	response := web.JSONResponseWithoutData(
+		http.StatusOK,
+		"success",
+		"change password success",
+	)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) RefreshToken(c *gin.Context) {
+	var req web.TokenRefreshRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"refresh token failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Rotate refresh token
+	authToken, err := h.usecase.RefreshToken(c.Request.Context(), req.RefreshToken)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusUnauthorized,
+			"error",
+			"refresh token failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusUnauthorized, response)
+		return
+	}
+
+	// Create format response
+	response := web.JSONResponseWithData(
+		http.StatusOK,
+		"success",
+		"refresh token success",
+		web.FormatTokenResponse(authToken),
+	)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) Logout(c *gin.Context) {
+	// Get current user login and claim of the token
+	currentUser := c.MustGet("currentUser").(domain.User)
+	currentClaim := c.MustGet("currentClaim").(usecase.Claim)
+
+	var req web.LogoutRequest
+	// Body is optional, without refresh token only access token is revoked
+	if c.Request.ContentLength != 0 {
+		err := c.ShouldBindJSON(&req)
+		if err != nil {
+			errors := web.FormatValidationError(err)
+			errorMessage := gin.H{"errors": errors}
+			response := web.JSONResponseWithData(
+				http.StatusBadRequest,
+				"error",
+				"logout failed",
+				errorMessage,
+			)
+			c.JSON(http.StatusBadRequest, response)
+			return
+		}
+	}
+
+	// Logout
+	_, err := h.usecase.Logout(c.Request.Context(), currentUser.ID, req.RefreshToken, currentClaim)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"logout failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Create format response
+	response := web.JSONResponseWithoutData(
+		http.StatusOK,
+		"success",
+		"logout success",
 	)
 	c.JSON(http.StatusOK, response)
 }
+
+func (h *userHandler) FindAll(c *gin.Context) {
+	// Get query
+	var queryParameter web.UserQueryRequest
+	err := c.ShouldBindQuery(&queryParameter)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"bad request",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Find all users
+	users, pagination, err := h.usecase.FindAll(c.Request.Context(), queryParameter)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"bad request",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	setPaginationLink(c, pagination)
+
+	// Create format response
+	response := web.JSONResponseWithPagination(
+		http.StatusOK,
+		"success",
+		"list of users",
+		web.FormatUsersResponse(users),
+		pagination,
+	)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) FindByID(c *gin.Context) {
+	// Get id user from path
+	var userID web.UserURI
+	err := c.ShouldBindUri(&userID)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"bad request",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Find by id
+	user, err := h.usecase.FindOneByID(c.Request.Context(), userID.ID)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusNotFound,
+			"error",
+			"not found",
+			errorMessage,
+		)
+		c.JSON(http.StatusNotFound, response)
+		return
+	}
+
+	// Create format response
+	response := web.JSONResponseWithData(
+		http.StatusOK,
+		"success",
+		"detail of user",
+		web.FormatUserResponse(user),
+	)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) Create(c *gin.Context) {
+	var req web.UserCreateRequest
+
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"create user failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Create
+	newUser, err := h.usecase.Create(c.Request.Context(), req)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"create user failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Create format response
+	response := web.JSONResponseWithData(
+		http.StatusCreated,
+		"success",
+		"user has been created",
+		web.FormatUserResponse(newUser),
+	)
+	c.JSON(http.StatusCreated, response)
+}
+
+func (h *userHandler) ChangeRole(c *gin.Context) {
+	// Get id user from path
+	var userID web.UserURI
+	err := c.ShouldBindUri(&userID)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"bad request",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	var req web.UserRoleRequest
+	err = c.ShouldBindJSON(&req)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"change role failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Change role
+	userUpdated, err := h.usecase.ChangeRole(c.Request.Context(), userID.ID, req)
+	if err != nil {
+		// Last admin can't be removed
+		status := http.StatusBadRequest
+		if errors.Is(err, domain.ErrLastAdmin) {
+			status = http.StatusConflict
+		}
+
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			status,
+			"error",
+			"change role failed",
+			errorMessage,
+		)
+		c.JSON(status, response)
+		return
+	}
+
+	// Create format response
+	response := web.JSONResponseWithData(
+		http.StatusOK,
+		"success",
+		"change role success",
+		web.FormatUserResponse(userUpdated),
+	)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) ResetPassword(c *gin.Context) {
+	// Get id user from path
+	var userID web.UserURI
+	err := c.ShouldBindUri(&userID)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"bad request",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	var req web.UserResetPasswordRequest
+	err = c.ShouldBindJSON(&req)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"reset password failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Reset password
+	_, err = h.usecase.ResetPassword(c.Request.Context(), userID.ID, req)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"reset password failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Create format response
+	response := web.JSONResponseWithoutData(
+		http.StatusOK,
+		"success",
+		"reset password success",
+	)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) Deactivate(c *gin.Context) {
+	// Get current user login
+	currentUser := c.MustGet("currentUser").(domain.User)
+
+	// Get id user from path
+	var userID web.UserURI
+	err := c.ShouldBindUri(&userID)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"bad request",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Deactivate
+	userUpdated, err := h.usecase.Deactivate(c.Request.Context(), userID.ID, currentUser.ID)
+	if err != nil {
+		// Last admin can't be removed
+		status := http.StatusBadRequest
+		if errors.Is(err, domain.ErrLastAdmin) {
+			status = http.StatusConflict
+		}
+
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			status,
+			"error",
+			"deactivate user failed",
+			errorMessage,
+		)
+		c.JSON(status, response)
+		return
+	}
+
+	// Create format response
+	response := web.JSONResponseWithData(
+		http.StatusOK,
+		"success",
+		"user deactivated",
+		web.FormatUserResponse(userUpdated),
+	)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) Activate(c *gin.Context) {
+	// Get id user from path
+	var userID web.UserURI
+	err := c.ShouldBindUri(&userID)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"bad request",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Activate
+	userUpdated, err := h.usecase.Activate(c.Request.Context(), userID.ID)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"activate user failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Create format response
+	response := web.JSONResponseWithData(
+		http.StatusOK,
+		"success",
+		"user activated",
+		web.FormatUserResponse(userUpdated),
+	)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) Unlock(c *gin.Context) {
+	// Get id user from path
+	var userID web.UserURI
+	err := c.ShouldBindUri(&userID)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"bad request",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Unlock
+	user, err := h.usecase.Unlock(c.Request.Context(), userID.ID)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"unlock user failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Create format response
+	response := web.JSONResponseWithData(
+		http.StatusOK,
+		"success",
+		"user unlocked",
+		web.FormatUserResponse(user),
+	)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) EnrollMFA(c *gin.Context) {
+	// Get current user login
+	currentUser := c.MustGet("currentUser").(domain.User)
+
+	var req web.MFAEnrollRequest
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"enroll mfa failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Enroll, password is asked again
+	enroll, err := h.usecase.EnrollMFA(c.Request.Context(), currentUser.ID, req)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"enroll mfa failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Create format response
+	response := web.JSONResponseWithData(
+		http.StatusOK,
+		"success",
+		"scan the otpauth uri and verify the code",
+		enroll,
+	)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) ConfirmMFA(c *gin.Context) {
+	// Get current user login
+	currentUser := c.MustGet("currentUser").(domain.User)
+
+	var req web.MFACodeRequest
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"verify mfa failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Enable MFA with the first code
+	recoveryCodes, err := h.usecase.ConfirmMFA(c.Request.Context(), currentUser.ID, req)
+	if err != nil {
+		if responseLoginLocked(c, err) {
+			return
+		}
+
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"verify mfa failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Create format response, recovery codes are only shown here
+	response := web.JSONResponseWithData(
+		http.StatusOK,
+		"success",
+		"mfa enabled",
+		web.MFARecoveryCodesResponse{RecoveryCodes: recoveryCodes},
+	)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) DisableMFA(c *gin.Context) {
+	// Get current user login
+	currentUser := c.MustGet("currentUser").(domain.User)
+
+	var req web.MFADisableRequest
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		errors := web.FormatValidationError(err)
+		errorMessage := gin.H{"errors": errors}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"disable mfa failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Disable
+	_, err = h.usecase.DisableMFA(c.Request.Context(), currentUser.ID, req)
+	if err != nil {
+		errorMessage := gin.H{"errors": err.Error()}
+		response := web.JSONResponseWithData(
+			http.StatusBadRequest,
+			"error",
+			"disable mfa failed",
+			errorMessage,
+		)
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	// Create format response
+	response := web.JSONResponseWithoutData(
+		http.StatusOK,
+		"success",
+		"mfa disabled",
+	)
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *userHandler) JWKS(c *gin.Context) {
+	// Response is not wrapped, other services read it as JSON Web Key Set
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, web.JWKSResponse{Keys: h.usecase.JSONWebKeys()})
+}
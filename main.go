@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 
 	"github.com/letenk/pokedex/router"
+	"github.com/letenk/pokedex/usecase"
 	"github.com/letenk/pokedex/util"
 )
 
@@ -19,8 +21,15 @@ func main() {
 	// Open connection to postgres
 	db := util.SetupDB(config.DB_SOURCE)
 
-	// Setup Router
-	router := router.SetupRouter(db)
+	// Cache of responses, redis is shared by every instance
+	cache, err := usecase.NewCache(config)
+	if err != nil {
+		log.Fatal("cannot create cache:", err)
+	}
+
+	// Setup Router and background jobs
+	router, startJobs := router.SetupRouterWithJobs(db, config, cache)
+	startJobs(context.Background())
 	app_port := fmt.Sprintf(":%s", config.APP_PORT)
 	router.Run(app_port)
 }
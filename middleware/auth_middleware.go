@@ -1,61 +1,27 @@
 package middleware
 
 import (
-	"context"
 	"errors"
-	"log"
 	"net/http"
 	"strings"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
+	"github.com/letenk/pokedex/models/domain"
 	"github.com/letenk/pokedex/models/web"
 	"github.com/letenk/pokedex/usecase"
-	"github.com/letenk/pokedex/util"
 )
 
-// Function for auth middleware
-func AuthMiddleware(userUsecase usecase.UserUsecase) gin.HandlerFunc {
+// Function for auth middleware, user is authenticated by header `Authorization` or `X-API-Key`
+func AuthMiddleware(userUsecase usecase.UserUsecase, apiKeyUsecase usecase.APIKeyUsecase) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Get header with name `Authorization`
-		authHeader := c.GetHeader("Authorization")
-
-		// If inside authHeader doesn't have `Bearer`
-		if !strings.Contains(authHeader, "Bearer") {
-			// Create format response
-			response := web.JSONResponseWithoutData(http.StatusUnauthorized, "error", "unauthorized")
-			// Stop process and return response
-			c.AbortWithStatusJSON(http.StatusUnauthorized, response)
+		// Get user from api key
+		if c.GetHeader("X-API-Key") != "" {
+			authenticateAPIKey(c, apiKeyUsecase)
 			return
 		}
 
-		// If there is, create new variable with empty string value
-		tokenString := ""
-		// Split authHeader with white space
-		arrayToken := strings.Split(authHeader, " ")
-		// If length arrayToken is same the 2
-		if len(arrayToken) == 2 {
-			// Get arrayToken with index 1 / only token jwt
-			tokenString = arrayToken[1]
-		}
-
-		// Parse token
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			_, ok := token.Method.(*jwt.SigningMethodHMAC)
-
-			if !ok {
-				return nil, errors.New("invalid token")
-			}
-
-			// Load Config
-			config, err := util.LoadConfig("../.")
-			if err != nil {
-				log.Fatal("cannot load config:", err)
-			}
-
-			return []byte(config.JWT_SECRET_KEY), nil
-		})
-
+		// Get user from token
+		user, claim, err := authenticate(c, userUsecase)
 		// If error
 		if err != nil {
 			// Create format response
@@ -65,22 +31,29 @@ func AuthMiddleware(userUsecase usecase.UserUsecase) gin.HandlerFunc {
 			return
 		}
 
-		// Get payload token
-		claim, ok := token.Claims.(jwt.MapClaims)
-		// If not `ok` and token invalid
-		if !ok || !token.Valid {
-			// Create format response
-			response := web.JSONResponseWithoutData(http.StatusUnauthorized, "error", "unauthorized")
-			// Stop process and return response
-			c.AbortWithStatusJSON(http.StatusUnauthorized, response)
+		// Set user to context with name `currentUser`, and claim of token with name `currentClaim`
+		c.Set("currentUser", user)
+		c.Set("currentClaim", claim)
+		setAuditActor(c, user)
+	}
+}
+
+// Function for optional auth middleware, guest can pass without header `Authorization` and `X-API-Key`
+func OptionalAuthMiddleware(userUsecase usecase.UserUsecase, apiKeyUsecase usecase.APIKeyUsecase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get user from api key
+		if c.GetHeader("X-API-Key") != "" {
+			authenticateAPIKey(c, apiKeyUsecase)
 			return
 		}
 
-		// Get payload `user_id` and convert to `string`
-		userId := claim["user_id"].(string)
+		// If guest, continue without `currentUser`
+		if c.GetHeader("Authorization") == "" {
+			return
+		}
 
-		// Find user on db with service
-		user, err := userUsecase.FindOneByID(context.Background(), userId)
+		// Get user from token
+		user, claim, err := authenticate(c, userUsecase)
 		// If error
 		if err != nil {
 			// Create format response
@@ -90,7 +63,68 @@ func AuthMiddleware(userUsecase usecase.UserUsecase) gin.HandlerFunc {
 			return
 		}
 
-		// Set user to context with name `currentUser`
+		// Set user to context with name `currentUser`, and claim of token with name `currentClaim`
 		c.Set("currentUser", user)
+		c.Set("currentClaim", claim)
+		setAuditActor(c, user)
+	}
+}
+
+// Function for session middleware, api key can not manage account and keys of the owner, must be used after auth middleware
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("currentAPIKey"); ok {
+			response := web.JSONResponseWithoutData(http.StatusForbidden, "error", "forbidden")
+			c.AbortWithStatusJSON(http.StatusForbidden, response)
+			return
+		}
+	}
+}
+
+// authenticateAPIKey get owner of key from header `X-API-Key`, the key is set to context with name `currentAPIKey`
+func authenticateAPIKey(c *gin.Context, apiKeyUsecase usecase.APIKeyUsecase) {
+	user, apiKey, err := apiKeyUsecase.Authenticate(c.Request.Context(), c.GetHeader("X-API-Key"))
+	// If error
+	if err != nil {
+		// Create format response
+		response := web.JSONResponseWithoutData(http.StatusUnauthorized, "error", "unauthorized")
+		// Stop process and return response
+		c.AbortWithStatusJSON(http.StatusUnauthorized, response)
+		return
 	}
+
+	// Api key doesn't have claim of token
+	c.Set("currentUser", user)
+	c.Set("currentClaim", usecase.Claim{})
+	c.Set("currentAPIKey", apiKey)
+	setAuditActor(c, user)
+}
+
+// setAuditActor set user login to context of request, so the write of usecase is recorded with the user as actor
+func setAuditActor(c *gin.Context, user domain.User) {
+	c.Request = c.Request.WithContext(usecase.ContextWithAuditActor(c.Request.Context(), user))
+}
+
+// authenticate get user login from header `Authorization`
+func authenticate(c *gin.Context, userUsecase usecase.UserUsecase) (domain.User, usecase.Claim, error) {
+	// Get header with name `Authorization`
+	authHeader := c.GetHeader("Authorization")
+
+	// If inside authHeader doesn't have `Bearer`
+	if !strings.Contains(authHeader, "Bearer") {
+		return domain.User{}, usecase.Claim{}, errors.New("unauthorized")
+	}
+
+	// If there is, create new variable with empty string value
+	tokenString := ""
+	// Split authHeader with white space
+	arrayToken := strings.Split(authHeader, " ")
+	// If length arrayToken is same the 2
+	if len(arrayToken) == 2 {
+		// Get arrayToken with index 1 / only token jwt
+		tokenString = arrayToken[1]
+	}
+
+	// Parse token, check denylist and find user on db with usecase
+	return userUsecase.Authenticate(c.Request.Context(), tokenString)
 }
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/letenk/pokedex/models/domain"
+	"github.com/letenk/pokedex/models/web"
+	"github.com/letenk/pokedex/usecase"
+)
+
+// Function for permission middleware, must be used after auth middleware
+func RequirePermission(permissionUsecase usecase.PermissionUsecase, permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get current user login
+		currentUser := c.MustGet("currentUser").(domain.User)
+
+		// Check role of user has the permission
+		ok, err := permissionUsecase.HasPermission(c.Request.Context(), currentUser.Role, permission)
+		if err != nil {
+			response := web.JSONResponseWithoutData(http.StatusInternalServerError, "error", "internal server error")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, response)
+			return
+		}
+
+		// Api key is only permitted to the scopes
+		if apiKey, exists := c.Get("currentAPIKey"); exists && ok {
+			ok = apiKey.(domain.APIKey).HasScope(permission)
+		}
+
+		// If not permitted
+		if !ok {
+			response := web.JSONResponseWithoutData(http.StatusForbidden, "error", "forbidden")
+			c.AbortWithStatusJSON(http.StatusForbidden, response)
+			return
+		}
+	}
+}
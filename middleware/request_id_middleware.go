@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+	"github.com/letenk/pokedex/usecase"
+)
+
+// Max length of request id from client, longer id is replaced
+const maxRequestIDLength = 64
+
+// Function for request id middleware, id from header `X-Request-ID` is used when valid, else new id is generated.
+// The id is returned on header `X-Request-ID` and saved in audit log.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader("X-Request-ID")
+		if !validRequestID(requestID) {
+			b := make([]byte, 16)
+			rand.Read(b)
+			requestID = hex.EncodeToString(b)
+		}
+
+		// Set request id to context with name `requestID`
+		c.Set("requestID", requestID)
+		c.Request = c.Request.WithContext(usecase.ContextWithRequestID(c.Request.Context(), requestID))
+		c.Header("X-Request-ID", requestID)
+	}
+}
+
+// validRequestID only allow printable ascii without space, so the id is safe for log
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+
+	for _, r := range requestID {
+		if r <= ' ' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,40 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// APIKey is long-lived key for service access, only hash of the key is saved
+type APIKey struct {
+	ID         string
+	UserID     string
+	Name       string
+	Prefix     string // First characters of the key, for recognize the key without the secret
+	KeyHash    string
+	Scopes     string // Permissions separated by space, must be owned by role of the user
+	ExpiresAt  time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// ScopeList return scopes of key as slice
+func (k APIKey) ScopeList() []string {
+	return strings.Fields(k.Scopes)
+}
+
+// HasScope check the key is allowed to use the permission
+func (k APIKey) HasScope(permission string) bool {
+	for _, scope := range k.ScopeList() {
+		if scope == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Actions of audit log
+const (
+	AuditActionCreate         = "create"
+	AuditActionUpdate         = "update"
+	AuditActionDelete         = "delete"
+	AuditActionCapture        = "capture"
+	AuditActionRelease        = "release"
+	AuditActionRegister       = "register"
+	AuditActionLogin          = "login"
+	AuditActionLoginFailed    = "login_failed"
+	AuditActionLogout         = "logout"
+	AuditActionPasswordChange = "password_change"
+	AuditActionPasswordReset  = "password_reset"
+	AuditActionRoleChange     = "role_change"
+	AuditActionDeactivate     = "deactivate"
+	AuditActionActivate       = "activate"
+	AuditActionUnlock         = "unlock"
+	AuditActionMFAEnable      = "mfa_enable"
+	AuditActionMFADisable     = "mfa_disable"
+	AuditActionRevoke         = "revoke"
+	AuditActionRestore        = "restore"
+	AuditActionPurge          = "purge"
+)
+
+// Entities of audit log
+const (
+	AuditEntityMonster           = "monster"
+	AuditEntityCategory          = "category"
+	AuditEntityType              = "type"
+	AuditEntityTypeEffectiveness = "type_effectiveness"
+	AuditEntityEvolution         = "evolution"
+	AuditEntityUser              = "user"
+	AuditEntityAPIKey            = "api_key"
+)
+
+// AuditLog is record of a write operation, actor is not a relation so the record is kept as it was
+type AuditLog struct {
+	ID            string
+	ActorID       string // Empty when the actor is unknown, e.g. failed login of unknown username
+	ActorUsername string
+	Action        string
+	Entity        string
+	EntityID      string
+	Changes       AuditChanges
+	RequestID     string
+	CreatedAt     time.Time
+}
+
+// AuditChange is value of one field before and after the write
+type AuditChange struct {
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+// AuditChanges is changed fields by name, saved as jsonb
+type AuditChanges map[string]AuditChange
+
+func (c AuditChanges) Value() (driver.Value, error) {
+	if c == nil {
+		return nil, nil
+	}
+
+	value, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return string(value), nil
+}
+
+func (c *AuditChanges) Scan(value interface{}) error {
+	switch value := value.(type) {
+	case nil:
+		*c = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(value, c)
+	case string:
+		return json.Unmarshal([]byte(value), c)
+	default:
+		return errors.New("changes of audit log must be json")
+	}
+}
@@ -0,0 +1,24 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrEvolutionCycle is returned when the new evolution makes a monster evolve into its own ancestor
+var ErrEvolutionCycle = errors.New("evolution creates a cycle")
+
+// Evolution is a step of evolution from a monster into other monster
+type Evolution struct {
+	ID            string
+	FromMonsterID string
+	FromMonster   Monster
+	ToMonsterID   string
+	ToMonster     Monster
+	Trigger       string  // level, item or condition
+	Level         *int    // Minimum level, for trigger level
+	Item          *string // Item used, for trigger item
+	Condition     *string // Free text condition, e.g. high friendship
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
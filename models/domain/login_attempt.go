@@ -0,0 +1,10 @@
+package domain
+
+import "time"
+
+// LoginAttempt is counter of failed login by key, e.g. `username:admin` or `ip:127.0.0.1`
+type LoginAttempt struct {
+	Key          string `gorm:"primaryKey"`
+	Failures     int
+	LastFailedAt time.Time
+}
@@ -0,0 +1,16 @@
+package domain
+
+import "time"
+
+// MFARecoveryCode is one time code for login when authenticator is lost, only hash of the code is saved
+type MFARecoveryCode struct {
+	ID        string
+	UserID    string
+	CodeHash  string
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (MFARecoveryCode) TableName() string {
+	return "mfa_recovery_codes"
+}
@@ -1,9 +1,15 @@
 package domain
 
 import (
+	"errors"
 	"time"
+
+	"gorm.io/gorm"
 )
 
+// ErrMonsterVersionConflict is returned when monster is changed after the version known by the request
+var ErrMonsterVersionConflict = errors.New("monster has been modified by another request, reload and try again")
+
 type Monster struct {
 	ID          string
 	Name        string
@@ -15,12 +21,15 @@ type Monster struct {
 	Attack      uint16
 	Defends     uint16
 	Speed       uint16
-	Catched     bool
+	Catched     bool `gorm:"-"` // Captured by current user, filled from table user_monsters
 	ImageName   string
 	ImageURL    string
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
-	TypeID      []string `gorm:"-"`                       // Ignore as field column
-	Types       []Type   `gorm:"many2many:monster_types"` // Relation many to many to category
-	Category    Category // Relation one to many to category
+	Version     uint           `gorm:"default:1"` // Incremented on every update, for optimistic concurrency
+	DeletedAt   gorm.DeletedAt // Monster in trash, purged after retention period
+	TypeID      []string       `gorm:"-"`                       // Ignore as field column
+	Types       []Type         `gorm:"many2many:monster_types"` // Relation many to many to category
+	Category    Category       // Relation one to many to category
+	Evolutions  []Evolution    `gorm:"-"` // Full evolution chain of monster, filled from table evolutions
 }
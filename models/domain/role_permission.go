@@ -0,0 +1,21 @@
+package domain
+
+// Permissions which can be granted to a role
+const (
+	PermissionCategoryRead   = "category:read"
+	PermissionCategoryWrite  = "category:write"
+	PermissionTypeRead       = "type:read"
+	PermissionTypeWrite      = "type:write"
+	PermissionMonsterWrite   = "monster:write"
+	PermissionMonsterCapture = "monster:capture"
+	PermissionMonsterTrash   = "monster:trash"
+	PermissionEvolutionWrite = "evolution:write"
+	PermissionUserManage     = "user:manage"
+	PermissionAuditRead      = "audit:read"
+)
+
+// RolePermission is a permission granted to a role
+type RolePermission struct {
+	Role       string
+	Permission string
+}
@@ -0,0 +1,33 @@
+package domain
+
+import "time"
+
+// RefreshToken is persisted refresh token, only hash of the token is saved
+type RefreshToken struct {
+	ID        string
+	UserID    string
+	FamilyID  string `gorm:"default:uuid_generate_v4()"` // All refresh tokens rotated from the same login
+	TokenHash string
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+// RevokedToken is denylist of access token by jti, kept until the token expires
+type RevokedToken struct {
+	JTI       string `gorm:"column:jti;primaryKey"`
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// AuthToken is pair of access token and refresh token
+type AuthToken struct {
+	AccessToken           string
+	AccessTokenExpiresAt  time.Time
+	RefreshToken          string
+	RefreshTokenExpiresAt time.Time
+	// Set instead of the tokens when login need second factor, must be exchanged with code
+	MFAToken              string
+	MFATokenExpiresAt     time.Time
+	MFAEnrollmentRequired bool
+}
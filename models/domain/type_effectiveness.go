@@ -0,0 +1,32 @@
+package domain
+
+import "time"
+
+// TypeEffectiveness is multiplier of damage from attacker type to defender type
+type TypeEffectiveness struct {
+	AttackerTypeID string
+	AttackerType   Type
+	DefenderTypeID string
+	DefenderType   Type
+	Multiplier     float64
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+func (TypeEffectiveness) TableName() string {
+	return "type_effectiveness"
+}
+
+// TypeMultiplier is combined multiplier of attacker type against a monster
+type TypeMultiplier struct {
+	Type       Type
+	Multiplier float64
+}
+
+// MonsterWeaknesses is attacker types split by combined multiplier against a monster,
+// type with multiplier 1 is in none of them
+type MonsterWeaknesses struct {
+	Weaknesses  []TypeMultiplier // Multiplier more than 1
+	Resistances []TypeMultiplier // Multiplier between 0 and 1
+	Immunities  []TypeMultiplier // Multiplier 0
+}
@@ -1,6 +1,12 @@
 package domain
 
-import "time"
+import (
+	"errors"
+	"time"
+)
+
+// ErrLastAdmin is returned when role change or deactivation leaves no active user who can manage users
+var ErrLastAdmin = errors.New("at least one active user with permission user:manage is required")
 
 type User struct {
 	ID        string
@@ -10,4 +16,11 @@ type User struct {
 	Role      string
 	CreatedAt time.Time
 	UpdatedAt time.Time
+	// Deactivated user can not login, and the token is rejected
+	DeactivatedAt *time.Time
+	// Secret of TOTP, MFA is only active after the first code is verified
+	MFASecret    string     `gorm:"column:mfa_secret"`
+	MFAEnabledAt *time.Time `gorm:"column:mfa_enabled_at"`
+	// Step of the last code used, code can not be used twice
+	MFALastStep int64 `gorm:"column:mfa_last_step"`
 }
@@ -0,0 +1,24 @@
+package domain
+
+import "time"
+
+// UserIdentity link user to account of external identity provider, subject is unique per issuer
+type UserIdentity struct {
+	ID        string
+	UserID    string
+	Issuer    string
+	Subject   string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// ExternalIdentity is verified claims of user login with external identity provider
+type ExternalIdentity struct {
+	Issuer   string
+	Subject  string
+	Username string
+	Fullname string
+	Email    string
+	// Nil when the provider doesn't send claim of groups
+	Groups []string
+}
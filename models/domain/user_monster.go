@@ -0,0 +1,10 @@
+package domain
+
+import "time"
+
+// UserMonster is a monster captured by a user
+type UserMonster struct {
+	UserID    string
+	MonsterID string
+	CreatedAt time.Time
+}
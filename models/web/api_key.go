@@ -0,0 +1,79 @@
+package web
+
+import (
+	"time"
+
+	"github.com/letenk/pokedex/models/domain"
+)
+
+type APIKeyURI struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+type UserAPIKeyURI struct {
+	ID    string `uri:"id" binding:"required,uuid"`
+	KeyID string `uri:"key_id" binding:"required,uuid"`
+}
+
+type APIKeyCreateRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1,dive,required"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,min=1,max=365"`
+}
+
+type APIKeyResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type APIKeyCreatedResponse struct {
+	APIKeyResponse
+	// Plain key, only shown once on create
+	Key string `json:"key"`
+}
+
+// Format for handle single response api key, hash of key is never returned
+func FormatAPIKeyResponse(apiKey domain.APIKey) APIKeyResponse {
+	formatter := APIKeyResponse{
+		ID:         apiKey.ID,
+		Name:       apiKey.Name,
+		Prefix:     apiKey.Prefix,
+		Scopes:     apiKey.ScopeList(),
+		ExpiresAt:  apiKey.ExpiresAt,
+		LastUsedAt: apiKey.LastUsedAt,
+		RevokedAt:  apiKey.RevokedAt,
+		CreatedAt:  apiKey.CreatedAt,
+	}
+	return formatter
+}
+
+// Format for handle multiples response api key
+func FormatAPIKeysResponse(apiKeys []domain.APIKey) []APIKeyResponse {
+	if len(apiKeys) == 0 {
+		return []APIKeyResponse{}
+	}
+
+	var formatters []APIKeyResponse
+
+	for _, data := range apiKeys {
+		formatter := FormatAPIKeyResponse(data)
+		formatters = append(formatters, formatter)
+	}
+
+	return formatters
+}
+
+// Format for handle response of new api key with the plain key
+func FormatAPIKeyCreatedResponse(apiKey domain.APIKey, key string) APIKeyCreatedResponse {
+	formatter := APIKeyCreatedResponse{
+		APIKeyResponse: FormatAPIKeyResponse(apiKey),
+		Key:            key,
+	}
+	return formatter
+}
@@ -0,0 +1,60 @@
+package web
+
+import (
+	"time"
+
+	"github.com/letenk/pokedex/models/domain"
+)
+
+type AuditQueryRequest struct {
+	ActorID  string     `form:"actor_id" binding:"omitempty,uuid"`
+	Entity   string     `form:"entity"`
+	EntityID string     `form:"entity_id"`
+	Action   string     `form:"action"`
+	From     *time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To       *time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Page     int        `form:"page"`
+	Limit    int        `form:"limit"`
+}
+
+type AuditLogResponse struct {
+	ID            string              `json:"id"`
+	ActorID       string              `json:"actor_id"`
+	ActorUsername string              `json:"actor_username"`
+	Action        string              `json:"action"`
+	Entity        string              `json:"entity"`
+	EntityID      string              `json:"entity_id"`
+	Changes       domain.AuditChanges `json:"changes"`
+	RequestID     string              `json:"request_id"`
+	CreatedAt     time.Time           `json:"created_at"`
+}
+
+// Format for handle single response audit log
+func FormatAuditLogResponse(auditLog domain.AuditLog) AuditLogResponse {
+	formatter := AuditLogResponse{
+		ID:            auditLog.ID,
+		ActorID:       auditLog.ActorID,
+		ActorUsername: auditLog.ActorUsername,
+		Action:        auditLog.Action,
+		Entity:        auditLog.Entity,
+		EntityID:      auditLog.EntityID,
+		Changes:       auditLog.Changes,
+		RequestID:     auditLog.RequestID,
+		CreatedAt:     auditLog.CreatedAt,
+	}
+	return formatter
+}
+
+// Format for handle multiples response audit log
+func FormatAuditLogsResponse(auditLogs []domain.AuditLog) []AuditLogResponse {
+	if len(auditLogs) == 0 {
+		return []AuditLogResponse{}
+	}
+
+	var formatters []AuditLogResponse
+	for _, auditLog := range auditLogs {
+		formatters = append(formatters, FormatAuditLogResponse(auditLog))
+	}
+
+	return formatters
+}
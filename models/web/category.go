@@ -2,6 +2,18 @@ package web
 
 import "github.com/letenk/pokedex/models/domain"
 
+type CategoryURI struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+type CategoryRequest struct {
+	Name string `json:"name" form:"name" binding:"required"`
+}
+
+type CategoryDeleteRequest struct {
+	ReassignTo string `form:"reassign_to" binding:"omitempty,uuid"`
+}
+
 type CategoryResponse struct {
 	ID   string `json:"id"`
 	Name string `json:"name"`
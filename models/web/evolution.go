@@ -0,0 +1,74 @@
+package web
+
+import "github.com/letenk/pokedex/models/domain"
+
+type EvolutionURI struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+type EvolutionTriggerRequest struct {
+	Trigger   string `json:"trigger" form:"trigger" binding:"required,oneof=level item condition"`
+	Level     *int   `json:"level" form:"level" binding:"omitempty,gte=1"`
+	Item      string `json:"item" form:"item"`
+	Condition string `json:"condition" form:"condition"`
+}
+
+type EvolutionCreateRequest struct {
+	FromMonsterID string `json:"from_monster_id" form:"from_monster_id" binding:"required,uuid"`
+	ToMonsterID   string `json:"to_monster_id" form:"to_monster_id" binding:"required,uuid"`
+	EvolutionTriggerRequest
+}
+
+type EvolutionMonsterResponse struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	ImageURL string `json:"image_url"`
+}
+
+type EvolutionResponse struct {
+	ID        string                   `json:"id"`
+	From      EvolutionMonsterResponse `json:"from"`
+	To        EvolutionMonsterResponse `json:"to"`
+	Trigger   string                   `json:"trigger"`
+	Level     *int                     `json:"level,omitempty"`
+	Item      *string                  `json:"item,omitempty"`
+	Condition *string                  `json:"condition,omitempty"`
+}
+
+// Format for handle single response evolution
+func FormatEvolutionResponse(evolution domain.Evolution) EvolutionResponse {
+	formatter := EvolutionResponse{
+		ID: evolution.ID,
+		From: EvolutionMonsterResponse{
+			ID:       evolution.FromMonster.ID,
+			Name:     evolution.FromMonster.Name,
+			ImageURL: evolution.FromMonster.ImageURL,
+		},
+		To: EvolutionMonsterResponse{
+			ID:       evolution.ToMonster.ID,
+			Name:     evolution.ToMonster.Name,
+			ImageURL: evolution.ToMonster.ImageURL,
+		},
+		Trigger:   evolution.Trigger,
+		Level:     evolution.Level,
+		Item:      evolution.Item,
+		Condition: evolution.Condition,
+	}
+	return formatter
+}
+
+// Format for handle multiples response evolution
+func FormatEvolutionsResponse(evolutions []domain.Evolution) []EvolutionResponse {
+	if len(evolutions) == 0 {
+		return []EvolutionResponse{}
+	}
+
+	var formatters []EvolutionResponse
+
+	for _, data := range evolutions {
+		formatter := FormatEvolutionResponse(data)
+		formatters = append(formatters, formatter)
+	}
+
+	return formatters
+}
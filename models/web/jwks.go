@@ -0,0 +1,20 @@
+package web
+
+// JSONWebKey is public key of token signer, RFC 7517
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Curve string `json:"crv,omitempty"`
+	X     string `json:"x,omitempty"`
+}
+
+// JWKSResponse is response of endpoint `/.well-known/jwks.json`, not wrapped because it is read by other services
+type JWKSResponse struct {
+	Keys []JSONWebKey `json:"keys"`
+}
@@ -2,6 +2,7 @@ package web
 
 import (
 	"mime/multipart"
+	"time"
 
 	"github.com/letenk/pokedex/models/domain"
 )
@@ -11,11 +12,30 @@ type MosterURI struct {
 }
 
 type MonsterQueryRequest struct {
-	Name    string   `form:"name"`
-	Types   []string `form:"types"`
-	Catched string   `form:"catched"`
-	Sort    string   `form:"sort"`
-	Order   string   `form:"order"`
+	Name         string   `form:"name"`
+	Types        []string `form:"types" binding:"dive,uuid"`
+	TypesMode    string   `form:"types_mode"` // any (default) or all of types
+	ExcludeTypes []string `form:"exclude_types" binding:"dive,uuid"`
+	Catched      string   `form:"catched"`
+	CategoryID   string   `form:"category_id" binding:"omitempty,uuid"`
+	HpMin        *float64 `form:"hp_min"`
+	HpMax        *float64 `form:"hp_max"`
+	AttackMin    *float64 `form:"attack_min"`
+	AttackMax    *float64 `form:"attack_max"`
+	DefendsMin   *float64 `form:"defends_min"`
+	DefendsMax   *float64 `form:"defends_max"`
+	SpeedMin     *float64 `form:"speed_min"`
+	SpeedMax     *float64 `form:"speed_max"`
+	WeightMin    *float64 `form:"weight_min"`
+	WeightMax    *float64 `form:"weight_max"`
+	LengthMin    *float64 `form:"length_min"`
+	LengthMax    *float64 `form:"length_max"`
+	Sort         string   `form:"sort"`
+	Order        string   `form:"order"`
+	Page         int      `form:"page"`
+	Limit        int      `form:"limit"`
+	Cursor       string   `form:"cursor"`
+	UserID       string   `form:"-"` // User login, for filter and mark catched
 }
 
 type MonsterCreateRequest struct {
@@ -42,12 +62,20 @@ type MonsterUpdateRequest struct {
 	Attack      string   `json:"attack" form:"attack"`
 	Defends     string   `json:"defends" form:"defends"`
 	Speed       string   `json:"speed" form:"speed"`
-	Catched     string   `json:"catched" form:"catched"`
 	TypeID      []string `json:"type_id" form:"type_id"`
+	// Version of monster known by client, required when header `If-Match` is not sent
+	Version *uint `json:"version" form:"version"`
 }
 
 type MonsterUpdateRequestMonsterCapture struct {
 	Catched bool `json:"catched" form:"catched"`
+	// Version of monster known by client, required when header `If-Match` is not sent
+	Version *uint `json:"version" form:"version"`
+}
+
+type MonsterTrashQueryRequest struct {
+	Page  int `form:"page"`
+	Limit int `form:"limit"`
 }
 
 type MonstersResponseList struct {
@@ -57,6 +85,7 @@ type MonstersResponseList struct {
 	Catched    bool                  `json:"catched"`
 	ImageURL   string                `json:"image_url"`
 	Types      []MonsterTypeResponse `json:"types"`
+	Version    uint                  `json:"version"`
 }
 
 type MonsterResponseDetail struct {
@@ -73,6 +102,8 @@ type MonsterResponseDetail struct {
 	Catched     bool                  `json:"catched"`
 	ImageURL    string                `json:"image_url"`
 	Types       []MonsterTypeResponse `json:"types"`
+	Evolutions  []EvolutionResponse   `json:"evolution_chain"`
+	Version     uint                  `json:"version"`
 }
 
 type MonsterTypeResponse struct {
@@ -94,6 +125,7 @@ func FormatMonsterResponseList(monsters []domain.Monster) []MonstersResponseList
 		formatter.CategoryID = data.Category.Name
 		formatter.Catched = data.Catched
 		formatter.ImageURL = data.ImageURL
+		formatter.Version = data.Version
 
 		monsterTypes := []MonsterTypeResponse{}
 		for _, t := range data.Types {
@@ -125,6 +157,7 @@ func FormatMonsterResponseDetail(monster domain.Monster) MonsterResponseDetail {
 	formatter.Speed = monster.Speed
 	formatter.Catched = monster.Catched
 	formatter.ImageURL = monster.ImageURL
+	formatter.Version = monster.Version
 
 	monsterTypes := []MonsterTypeResponse{}
 	for _, t := range monster.Types {
@@ -133,6 +166,33 @@ func FormatMonsterResponseDetail(monster domain.Monster) MonsterResponseDetail {
 		monsterTypes = append(monsterTypes, typeResponse)
 	}
 	formatter.Types = monsterTypes
+	formatter.Evolutions = FormatEvolutionsResponse(monster.Evolutions)
 
 	return formatter
 }
+
+type MonsterTrashResponse struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	CategoryID string    `json:"category_name"`
+	ImageURL   string    `json:"image_url"`
+	DeletedAt  time.Time `json:"deleted_at"`
+}
+
+// Format for handle response list monsters in trash
+func FormatMonsterTrashResponse(monsters []domain.Monster) []MonsterTrashResponse {
+	formatters := make([]MonsterTrashResponse, 0, len(monsters))
+
+	for _, data := range monsters {
+		formatter := MonsterTrashResponse{}
+		formatter.ID = data.ID
+		formatter.Name = data.Name
+		formatter.CategoryID = data.Category.Name
+		formatter.ImageURL = data.ImageURL
+		formatter.DeletedAt = data.DeletedAt.Time
+
+		formatters = append(formatters, formatter)
+	}
+
+	return formatters
+}
@@ -0,0 +1,10 @@
+package web
+
+type OIDCCallbackRequest struct {
+	Code             string `form:"code"`
+	State            string `form:"state"`
+	Error            string `form:"error"`
+	ErrorDescription string `form:"error_description"`
+	// State signed by server, set by handler from cookie
+	SignedState string `form:"-"`
+}
@@ -0,0 +1,9 @@
+package web
+
+type Pagination struct {
+	Total      int64  `json:"total"`
+	Limit      int    `json:"limit"`
+	Page       int    `json:"page,omitempty"`
+	TotalPages int    `json:"total_pages,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
@@ -2,6 +2,18 @@ package web
 
 import "github.com/letenk/pokedex/models/domain"
 
+type TypeURI struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+type TypeRequest struct {
+	Name string `json:"name" form:"name" binding:"required"`
+}
+
+type TypeDeleteRequest struct {
+	Cascade bool `form:"cascade"`
+}
+
 type TypeResponse struct {
 	ID   string `json:"id"`
 	Name string `json:"name"`
@@ -0,0 +1,113 @@
+package web
+
+import "github.com/letenk/pokedex/models/domain"
+
+type TypeMatchupURI struct {
+	ID         string `uri:"id" binding:"required,uuid"`
+	DefenderID string `uri:"defender_id" binding:"required,uuid"`
+}
+
+type TypeEffectivenessRequest struct {
+	Multiplier *float64 `json:"multiplier" form:"multiplier" binding:"required,gte=0"`
+}
+
+type TypeEffectivenessResponse struct {
+	Attacker   TypeResponse `json:"attacker"`
+	Defender   TypeResponse `json:"defender"`
+	Multiplier float64      `json:"multiplier"`
+}
+
+type TypeMultiplierResponse struct {
+	ID         string  `json:"id"`
+	Name       string  `json:"name"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+type TypeMatchupsResponse struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Multiplier when the type attack other types
+	Offense []TypeMultiplierResponse `json:"offense"`
+	// Multiplier when the type is attacked by other types
+	Defense []TypeMultiplierResponse `json:"defense"`
+}
+
+type MonsterWeaknessesResponse struct {
+	ID          string                   `json:"id"`
+	Name        string                   `json:"name"`
+	Types       []MonsterTypeResponse    `json:"types"`
+	Weaknesses  []TypeMultiplierResponse `json:"weaknesses"`
+	Resistances []TypeMultiplierResponse `json:"resistances"`
+	Immunities  []TypeMultiplierResponse `json:"immunities"`
+}
+
+// Format for handle single response type effectiveness
+func FormatTypeEffectivenessResponse(effectiveness domain.TypeEffectiveness) TypeEffectivenessResponse {
+	formatter := TypeEffectivenessResponse{
+		Attacker:   FormatTypeResponse(effectiveness.AttackerType),
+		Defender:   FormatTypeResponse(effectiveness.DefenderType),
+		Multiplier: effectiveness.Multiplier,
+	}
+	return formatter
+}
+
+// Format for handle response matchups of type
+func FormatTypeMatchupsResponse(types domain.Type, effectiveness []domain.TypeEffectiveness) TypeMatchupsResponse {
+	formatter := TypeMatchupsResponse{
+		ID:      types.ID,
+		Name:    types.Name,
+		Offense: []TypeMultiplierResponse{},
+		Defense: []TypeMultiplierResponse{},
+	}
+
+	// Matchup of type against itself is on both of list
+	for _, e := range effectiveness {
+		if e.AttackerTypeID == types.ID {
+			formatter.Offense = append(formatter.Offense, TypeMultiplierResponse{
+				ID:         e.DefenderType.ID,
+				Name:       e.DefenderType.Name,
+				Multiplier: e.Multiplier,
+			})
+		}
+		if e.DefenderTypeID == types.ID {
+			formatter.Defense = append(formatter.Defense, TypeMultiplierResponse{
+				ID:         e.AttackerType.ID,
+				Name:       e.AttackerType.Name,
+				Multiplier: e.Multiplier,
+			})
+		}
+	}
+
+	return formatter
+}
+
+// Format for handle response weaknesses of monster
+func FormatMonsterWeaknessesResponse(monster domain.Monster, weaknesses domain.MonsterWeaknesses) MonsterWeaknessesResponse {
+	formatter := MonsterWeaknessesResponse{
+		ID:          monster.ID,
+		Name:        monster.Name,
+		Types:       []MonsterTypeResponse{},
+		Weaknesses:  formatTypeMultipliers(weaknesses.Weaknesses),
+		Resistances: formatTypeMultipliers(weaknesses.Resistances),
+		Immunities:  formatTypeMultipliers(weaknesses.Immunities),
+	}
+
+	for _, t := range monster.Types {
+		formatter.Types = append(formatter.Types, MonsterTypeResponse{Name: t.Name})
+	}
+
+	return formatter
+}
+
+// formatTypeMultipliers format multipliers, empty list when there is none
+func formatTypeMultipliers(multipliers []domain.TypeMultiplier) []TypeMultiplierResponse {
+	formatter := []TypeMultiplierResponse{}
+	for _, m := range multipliers {
+		formatter = append(formatter, TypeMultiplierResponse{
+			ID:         m.Type.ID,
+			Name:       m.Type.Name,
+			Multiplier: m.Multiplier,
+		})
+	}
+	return formatter
+}
@@ -1,6 +1,172 @@
 package web
 
+import (
+	"time"
+
+	"github.com/letenk/pokedex/models/domain"
+)
+
 type UserLoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	// IP of client, set by handler for count failed login per ip
+	IP string `json:"-"`
+}
+
+type UserRegisterRequest struct {
+	Fullname string `json:"fullname" binding:"required"`
+	Username string `json:"username" binding:"required,alphanum,min=3,max=30"`
+	Password string `json:"password" binding:"required,min=8,max=72"`
+}
+
+type UserUpdateRequest struct {
+	Fullname string `json:"fullname"`
+	Username string `json:"username" binding:"omitempty,alphanum,min=3,max=30"`
+}
+
+type UserChangePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=8,max=72"`
+}
+
+type TokenRefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type TokenResponse struct {
+	Token                 string `json:"token"`
+	TokenType             string `json:"token_type"`
+	ExpiresIn             int64  `json:"expires_in"`
+	RefreshToken          string `json:"refresh_token"`
+	RefreshTokenExpiresIn int64  `json:"refresh_token_expires_in"`
+	// Only when MFA is enabled on login, shown once
+	RecoveryCodes []string `json:"recovery_codes,omitempty"`
+}
+
+type UserURI struct {
+	ID string `uri:"id" binding:"required,uuid"`
+}
+
+type UserQueryRequest struct {
+	Search string `form:"search"`
+	Role   string `form:"role"`
+	Active *bool  `form:"active"`
+	Page   int    `form:"page"`
+	Limit  int    `form:"limit"`
+}
+
+type UserCreateRequest struct {
+	Fullname string `json:"fullname" binding:"required"`
+	Username string `json:"username" binding:"required,alphanum,min=3,max=30"`
+	Password string `json:"password" binding:"required,min=8,max=72"`
+	Role     string `json:"role" binding:"required"`
+}
+
+type UserRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+type UserResetPasswordRequest struct {
+	Password string `json:"password" binding:"required,min=8,max=72"`
+}
+
+type UserResponse struct {
+	ID         string `json:"id"`
+	Fullname   string `json:"fullname"`
+	Username   string `json:"username"`
+	Role       string `json:"role"`
+	Active     bool   `json:"active"`
+	MFAEnabled bool   `json:"mfa_enabled"`
+}
+
+// Format for handle single response user, password is never returned
+func FormatUserResponse(user domain.User) UserResponse {
+	formatter := UserResponse{
+		ID:         user.ID,
+		Fullname:   user.Fullname,
+		Username:   user.Username,
+		Role:       user.Role,
+		Active:     user.DeactivatedAt == nil,
+		MFAEnabled: user.MFAEnabledAt != nil,
+	}
+	return formatter
+}
+
+// Format for handle multiples response user
+func FormatUsersResponse(users []domain.User) []UserResponse {
+	if len(users) == 0 {
+		return []UserResponse{}
+	}
+
+	var formatters []UserResponse
+
+	for _, data := range users {
+		formatter := FormatUserResponse(data)
+		formatters = append(formatters, formatter)
+	}
+
+	return formatters
+}
+
+// Format for handle response token, expires in seconds
+func FormatTokenResponse(authToken domain.AuthToken) TokenResponse {
+	formatter := TokenResponse{
+		Token:                 authToken.AccessToken,
+		TokenType:             "Bearer",
+		ExpiresIn:             int64(time.Until(authToken.AccessTokenExpiresAt).Seconds()),
+		RefreshToken:          authToken.RefreshToken,
+		RefreshTokenExpiresIn: int64(time.Until(authToken.RefreshTokenExpiresAt).Seconds()),
+	}
+	return formatter
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type MFAEnrollRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+type MFADisableRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+type MFAEnrollLoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+}
+
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	// Code of TOTP or recovery code
+	Code string `json:"code" binding:"required"`
+}
+
+type MFAEnrollResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+type MFAPendingResponse struct {
+	MFAToken           string `json:"mfa_token"`
+	ExpiresIn          int64  `json:"expires_in"`
+	EnrollmentRequired bool   `json:"enrollment_required"`
+}
+
+type MFARecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// Format for handle response of login which need second factor
+func FormatMFAPendingResponse(authToken domain.AuthToken) MFAPendingResponse {
+	formatter := MFAPendingResponse{
+		MFAToken:           authToken.MFAToken,
+		ExpiresIn:          int64(time.Until(authToken.MFATokenExpiresAt).Seconds()),
+		EnrollmentRequired: authToken.MFAEnrollmentRequired,
+	}
+	return formatter
 }
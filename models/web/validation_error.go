@@ -10,8 +10,14 @@ func FormatValidationError(err error) []string {
 	// Create variable err with data type slice string
 	var errors []string
 
+	// Error is not from validator, e.g. invalid json
+	validationErrors, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return []string{err.Error()}
+	}
+
 	// Process iteration errors
-	for _, e := range err.(validator.ValidationErrors) {
+	for _, e := range validationErrors {
 		// Append every error message to var errors
 		errors = append(errors, e.Error())
 	}
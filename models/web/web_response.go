@@ -7,6 +7,14 @@ type ResponseWithData struct {
 	Data    interface{} `json:"data"`
 }
 
+type ResponseWithPagination struct {
+	Code       int         `json:"code"`
+	Status     string      `json:"status"`
+	Message    string      `json:"message"`
+	Data       interface{} `json:"data"`
+	Pagination Pagination  `json:"pagination"`
+}
+
 type ResponseWithoutData struct {
 	Code    int    `json:"code"`
 	Status  string `json:"status"`
@@ -24,6 +32,18 @@ func JSONResponseWithData(code int, status string, message string, data interfac
 	return jsonResponse
 }
 
+func JSONResponseWithPagination(code int, status string, message string, data interface{}, pagination Pagination) ResponseWithPagination {
+	jsonResponse := ResponseWithPagination{
+		Code:       code,
+		Status:     status,
+		Message:    message,
+		Data:       data,
+		Pagination: pagination,
+	}
+
+	return jsonResponse
+}
+
 func JSONResponseWithoutData(code int, status string, message string) ResponseWithoutData {
 	jsonResponse := ResponseWithoutData{
 		Code:    code,
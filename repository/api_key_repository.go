@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/letenk/pokedex/models/domain"
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository interface {
+	FindByUserID(ctx context.Context, userID string) ([]domain.APIKey, error)
+	FindByID(ctx context.Context, id string) (domain.APIKey, error)
+	FindByHash(ctx context.Context, keyHash string) (domain.APIKey, error)
+	Create(ctx context.Context, apiKey domain.APIKey) (domain.APIKey, error)
+	Revoke(ctx context.Context, apiKey domain.APIKey) (domain.APIKey, error)
+	UpdateLastUsed(ctx context.Context, id string, lastUsedAt time.Time) error
+}
+
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) *apiKeyRepository {
+	return &apiKeyRepository{db}
+}
+
+func (r *apiKeyRepository) FindByUserID(ctx context.Context, userID string) ([]domain.APIKey, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	var apiKeys []domain.APIKey
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at desc").Find(&apiKeys).Error
+	if err != nil {
+		return apiKeys, err
+	}
+
+	return apiKeys, nil
+}
+
+func (r *apiKeyRepository) FindByID(ctx context.Context, id string) (domain.APIKey, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	var apiKey domain.APIKey
+	err := r.db.WithContext(ctx).Where("id = ?", id).Find(&apiKey).Error
+	if err != nil {
+		return apiKey, err
+	}
+
+	return apiKey, nil
+}
+
+func (r *apiKeyRepository) FindByHash(ctx context.Context, keyHash string) (domain.APIKey, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	var apiKey domain.APIKey
+	err := r.db.WithContext(ctx).Where("key_hash = ?", keyHash).Find(&apiKey).Error
+	if err != nil {
+		return apiKey, err
+	}
+
+	return apiKey, nil
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, apiKey domain.APIKey) (domain.APIKey, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	err := r.db.WithContext(ctx).Create(&apiKey).Error
+	if err != nil {
+		return apiKey, err
+	}
+
+	return apiKey, nil
+}
+
+func (r *apiKeyRepository) Revoke(ctx context.Context, apiKey domain.APIKey) (domain.APIKey, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	err := r.db.WithContext(ctx).Model(&domain.APIKey{}).Where("id = ?", apiKey.ID).Update("revoked_at", now).Error
+	if err != nil {
+		return apiKey, err
+	}
+	apiKey.RevokedAt = &now
+
+	return apiKey, nil
+}
+
+// UpdateLastUsed only update column last_used_at, so updated_at is kept
+func (r *apiKeyRepository) UpdateLastUsed(ctx context.Context, id string, lastUsedAt time.Time) error {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	return r.db.WithContext(ctx).Model(&domain.APIKey{}).Where("id = ?", id).UpdateColumn("last_used_at", lastUsedAt).Error
+}
@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/letenk/pokedex/models/domain"
+	"github.com/letenk/pokedex/models/web"
+	"gorm.io/gorm"
+)
+
+type AuditRepository interface {
+	FindAll(ctx context.Context, reqQuery web.AuditQueryRequest) ([]domain.AuditLog, web.Pagination, error)
+	Create(ctx context.Context, auditLog domain.AuditLog) (domain.AuditLog, error)
+}
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) *auditRepository {
+	return &auditRepository{db}
+}
+
+func (r *auditRepository) FindAll(ctx context.Context, reqQuery web.AuditQueryRequest) ([]domain.AuditLog, web.Pagination, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	var auditLogs []domain.AuditLog
+	var pagination web.Pagination
+
+	// Validate pagination
+	limit := reqQuery.Limit
+	if limit == 0 {
+		limit = defaultLimit
+	}
+	if limit < 1 || limit > maxLimit {
+		return auditLogs, pagination, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+	}
+	if reqQuery.Page < 0 {
+		return auditLogs, pagination, errors.New("page must be greater than 0")
+	}
+	if reqQuery.From != nil && reqQuery.To != nil && reqQuery.From.After(*reqQuery.To) {
+		return auditLogs, pagination, errors.New("from must be before to")
+	}
+
+	db := r.db.WithContext(ctx).Model(&domain.AuditLog{})
+
+	if reqQuery.ActorID != "" {
+		db = db.Where("actor_id = ?", reqQuery.ActorID)
+	}
+	if reqQuery.Entity != "" {
+		db = db.Where("entity = ?", reqQuery.Entity)
+	}
+	if reqQuery.EntityID != "" {
+		db = db.Where("entity_id = ?", reqQuery.EntityID)
+	}
+	if reqQuery.Action != "" {
+		db = db.Where("action = ?", reqQuery.Action)
+	}
+
+	// Time range, from is inclusive and to is exclusive
+	if reqQuery.From != nil {
+		db = db.Where("created_at >= ?", *reqQuery.From)
+	}
+	if reqQuery.To != nil {
+		db = db.Where("created_at < ?", *reqQuery.To)
+	}
+
+	// New session, so db can be used for count and find
+	db = db.Session(&gorm.Session{})
+
+	var total int64
+	err := db.Count(&total).Error
+	if err != nil {
+		return auditLogs, pagination, err
+	}
+
+	page := reqQuery.Page
+	if page == 0 {
+		page = 1
+	}
+
+	// Newest first
+	err = db.Order("created_at desc").Order("id").Offset((page - 1) * limit).Limit(limit).Find(&auditLogs).Error
+	if err != nil {
+		return auditLogs, pagination, err
+	}
+
+	pagination.Total = total
+	pagination.Limit = limit
+	pagination.Page = page
+	pagination.TotalPages = int((total + int64(limit) - 1) / int64(limit))
+
+	return auditLogs, pagination, nil
+}
+
+func (r *auditRepository) Create(ctx context.Context, auditLog domain.AuditLog) (domain.AuditLog, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	err := r.db.WithContext(ctx).Create(&auditLog).Error
+	if err != nil {
+		return auditLog, err
+	}
+
+	return auditLog, nil
+}
@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/letenk/pokedex/models/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CaptureRepository interface {
+	Create(ctx context.Context, capture domain.UserMonster) (domain.UserMonster, error)
+	Delete(ctx context.Context, capture domain.UserMonster) (bool, error)
+	FindByUserID(ctx context.Context, userID string, monsterIDs []string) ([]domain.UserMonster, error)
+}
+
+type captureRepository struct {
+	db *gorm.DB
+}
+
+func NewCaptureRepository(db *gorm.DB) *captureRepository {
+	return &captureRepository{db}
+}
+
+func (r *captureRepository) Create(ctx context.Context, capture domain.UserMonster) (domain.UserMonster, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	// Captured twice is not error, keep the first capture
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&capture).Error
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == "23503" {
+				return capture, errors.New("invalid user id or monster id")
+			}
+		}
+		return capture, err
+	}
+
+	return capture, nil
+}
+
+func (r *captureRepository) Delete(ctx context.Context, capture domain.UserMonster) (bool, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	err := r.db.WithContext(ctx).Where("user_id = ? AND monster_id = ?", capture.UserID, capture.MonsterID).Delete(&domain.UserMonster{}).Error
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (r *captureRepository) FindByUserID(ctx context.Context, userID string, monsterIDs []string) ([]domain.UserMonster, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	var captures []domain.UserMonster
+
+	db := r.db.WithContext(ctx).Where("user_id = ?", userID)
+
+	// Only captures of the given monsters
+	if monsterIDs != nil {
+		db = db.Where("monster_id IN ?", monsterIDs)
+	}
+
+	err := db.Find(&captures).Error
+	if err != nil {
+		return captures, err
+	}
+
+	return captures, nil
+}
@@ -2,14 +2,22 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
+	"github.com/jackc/pgconn"
 	"github.com/letenk/pokedex/models/domain"
 	"gorm.io/gorm"
 )
 
 type CategoryRepository interface {
 	FindAll(ctx context.Context) ([]domain.Category, error)
+	FindByID(ctx context.Context, ID string) (domain.Category, error)
+	Create(ctx context.Context, category domain.Category) (domain.Category, error)
+	Update(ctx context.Context, category domain.Category) (domain.Category, error)
+	Delete(ctx context.Context, category domain.Category, reassignTo string) (bool, error)
+	CountMonsters(ctx context.Context, ID string) (int64, error)
 }
 
 type categoryRepository struct {
@@ -33,3 +41,107 @@ func (r *categoryRepository) FindAll(ctx context.Context) ([]domain.Category, er
 
 	return categories, nil
 }
+
+func (r *categoryRepository) FindByID(ctx context.Context, ID string) (domain.Category, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	var category domain.Category
+	err := r.db.WithContext(ctx).Where("id = ?", ID).Find(&category).Error
+	if err != nil {
+		return category, err
+	}
+
+	if category.ID == "" {
+		errMessage := fmt.Sprintf("category with id %s not found", ID)
+		return category, errors.New(errMessage)
+	}
+
+	return category, nil
+}
+
+func (r *categoryRepository) Create(ctx context.Context, category domain.Category) (domain.Category, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	err := r.db.WithContext(ctx).Create(&category).Error
+	if err != nil {
+		return category, categoryError(err, category)
+	}
+
+	return category, nil
+}
+
+func (r *categoryRepository) Update(ctx context.Context, category domain.Category) (domain.Category, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	err := r.db.WithContext(ctx).Save(&category).Error
+	if err != nil {
+		return category, categoryError(err, category)
+	}
+
+	return category, nil
+}
+
+func (r *categoryRepository) Delete(ctx context.Context, category domain.Category, reassignTo string) (bool, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Move monsters into other category, monsters in trash are also moved
+		if reassignTo != "" {
+			err := tx.WithContext(ctx).Unscoped().Model(&domain.Monster{}).Where("category_id = ?", category.ID).Update("category_id", reassignTo).Error
+			if err != nil {
+				return categoryError(err, category)
+			}
+		}
+
+		// Remove category
+		err := tx.WithContext(ctx).Where("id = ?", category.ID).Delete(&domain.Category{}).Error
+		if err != nil {
+			return categoryError(err, category)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (r *categoryRepository) CountMonsters(ctx context.Context, ID string) (int64, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	// Monsters in trash still reference the category
+	var count int64
+	err := r.db.WithContext(ctx).Unscoped().Model(&domain.Monster{}).Where("category_id = ?", ID).Count(&count).Error
+	if err != nil {
+		return count, err
+	}
+
+	return count, nil
+}
+
+// categoryError translate error constraint from postgres
+func categoryError(err error, category domain.Category) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505":
+			return fmt.Errorf("category with name %s already exists", category.Name)
+		case "23503":
+			return errors.New("category is still used by monsters, please reassign the monsters to other category")
+		}
+	}
+	return err
+}
@@ -0,0 +1,264 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/letenk/pokedex/models/domain"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxEvolutionDepth is guard of recursive query, chain never longer than this
+	maxEvolutionDepth = 100
+	// Create is retried when concurrent create in the same chain fails the serializable transaction
+	evolutionCreateAttempts = 3
+)
+
+type EvolutionRepository interface {
+	FindByID(ctx context.Context, ID string) (domain.Evolution, error)
+	FindChain(ctx context.Context, monsterID string) ([]domain.Evolution, error)
+	Create(ctx context.Context, evolution domain.Evolution) (domain.Evolution, error)
+	Update(ctx context.Context, evolution domain.Evolution) (domain.Evolution, error)
+	Delete(ctx context.Context, evolution domain.Evolution) (bool, error)
+}
+
+type evolutionRepository struct {
+	db *gorm.DB
+}
+
+func NewEvolutionRepository(db *gorm.DB) *evolutionRepository {
+	return &evolutionRepository{db}
+}
+
+func (r *evolutionRepository) FindByID(ctx context.Context, ID string) (domain.Evolution, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	var evolution domain.Evolution
+	err := r.db.WithContext(ctx).Where("id = ?", ID).Preload("FromMonster").Preload("ToMonster").Find(&evolution).Error
+	if err != nil {
+		return evolution, err
+	}
+
+	if evolution.ID == "" {
+		errMessage := fmt.Sprintf("evolution with id %s not found", ID)
+		return evolution, errors.New(errMessage)
+	}
+
+	return evolution, nil
+}
+
+// FindChain find all evolutions in the same chain with monster, ordered from the first evolution
+func (r *evolutionRepository) FindChain(ctx context.Context, monsterID string) ([]domain.Evolution, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	// Find the first monster of chain
+	var rootID string
+	err := r.db.WithContext(ctx).Raw(`
+		WITH RECURSIVE ancestors AS (
+			SELECT ?::uuid AS monster_id, 0 AS depth
+			UNION ALL
+			SELECT e.from_monster_id, a.depth + 1 FROM evolutions e
+			JOIN ancestors a ON e.to_monster_id = a.monster_id
+			WHERE a.depth < ?
+		)
+		SELECT monster_id FROM ancestors ORDER BY depth DESC LIMIT 1`,
+		monsterID, maxEvolutionDepth,
+	).Scan(&rootID).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Find id of all evolutions from the first monster
+	var chain []struct {
+		ID    string
+		Depth int
+	}
+	err = r.db.WithContext(ctx).Raw(`
+		WITH RECURSIVE chain AS (
+			SELECT e.id, e.to_monster_id, e.created_at, 1 AS depth FROM evolutions e
+			WHERE e.from_monster_id = ?
+			UNION ALL
+			SELECT e.id, e.to_monster_id, e.created_at, c.depth + 1 FROM evolutions e
+			JOIN chain c ON e.from_monster_id = c.to_monster_id
+			WHERE c.depth < ?
+		)
+		SELECT id, depth FROM chain ORDER BY depth, created_at`,
+		rootID, maxEvolutionDepth,
+	).Scan(&chain).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if len(chain) == 0 {
+		return []domain.Evolution{}, nil
+	}
+
+	IDs := make([]string, 0, len(chain))
+	for _, data := range chain {
+		IDs = append(IDs, data.ID)
+	}
+
+	var evolutions []domain.Evolution
+	err = r.db.WithContext(ctx).Where("id IN ?", IDs).Preload("FromMonster").Preload("ToMonster").Find(&evolutions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep order of the chain
+	byID := make(map[string]domain.Evolution, len(evolutions))
+	for _, evolution := range evolutions {
+		byID[evolution.ID] = evolution
+	}
+
+	ordered := make([]domain.Evolution, 0, len(evolutions))
+	for _, ID := range IDs {
+		if evolution, ok := byID[ID]; ok {
+			ordered = append(ordered, evolution)
+		}
+	}
+
+	return ordered, nil
+}
+
+// Create insert evolution, return domain.ErrEvolutionCycle when to monster is already before from monster
+// in the chain. Check and insert run in one serializable transaction, so concurrent creates can't make a cycle.
+func (r *evolutionRepository) Create(ctx context.Context, evolution domain.Evolution) (domain.Evolution, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	var err error
+	for attempt := 0; attempt < evolutionCreateAttempts; attempt++ {
+		err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			err := checkEvolutionCycle(tx, evolution.FromMonsterID, evolution.ToMonsterID)
+			if err != nil {
+				return err
+			}
+
+			return tx.Omit("FromMonster", "ToMonster").Create(&evolution).Error
+		}, &sql.TxOptions{Isolation: sql.LevelSerializable})
+
+		// Concurrent create in the same chain, check again with its evolution
+		if !isSerializationFailure(err) {
+			break
+		}
+	}
+	if err != nil {
+		return evolution, evolutionError(err, evolution)
+	}
+
+	return evolution, nil
+}
+
+func (r *evolutionRepository) Update(ctx context.Context, evolution domain.Evolution) (domain.Evolution, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	err := r.db.WithContext(ctx).Omit("FromMonster", "ToMonster").Save(&evolution).Error
+	if err != nil {
+		return evolution, evolutionError(err, evolution)
+	}
+
+	return evolution, nil
+}
+
+func (r *evolutionRepository) Delete(ctx context.Context, evolution domain.Evolution) (bool, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	// Every monster of chain shows the evolution, deleted row has no updated_at so the monsters are touched
+	chain, err := r.FindChain(ctx, evolution.FromMonsterID)
+	if err != nil {
+		return false, err
+	}
+
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("id = ?", evolution.ID).Delete(&domain.Evolution{}).Error
+		if err != nil {
+			return err
+		}
+
+		return touchMonsters(tx, chainMonsterIDs(chain))
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// chainMonsterIDs return id of every monster in the chain
+func chainMonsterIDs(chain []domain.Evolution) []string {
+	seen := make(map[string]bool, len(chain)+1)
+	IDs := make([]string, 0, len(chain)+1)
+	for _, evolution := range chain {
+		for _, ID := range []string{evolution.FromMonsterID, evolution.ToMonsterID} {
+			if !seen[ID] {
+				seen[ID] = true
+				IDs = append(IDs, ID)
+			}
+		}
+	}
+	return IDs
+}
+
+// checkEvolutionCycle walk back from fromMonsterID until the first monster of chain, monster evolves
+// from at most one monster. Walk is bounded by maxEvolutionDepth and stops on monster already visited.
+func checkEvolutionCycle(tx *gorm.DB, fromMonsterID string, toMonsterID string) error {
+	visited := make(map[string]bool)
+	monsterID := fromMonsterID
+	for depth := 0; depth <= maxEvolutionDepth; depth++ {
+		if monsterID == toMonsterID {
+			return domain.ErrEvolutionCycle
+		}
+		if visited[monsterID] {
+			return nil
+		}
+		visited[monsterID] = true
+
+		var previousIDs []string
+		err := tx.Model(&domain.Evolution{}).Where("to_monster_id = ?", monsterID).Limit(1).Pluck("from_monster_id", &previousIDs).Error
+		if err != nil {
+			return err
+		}
+		if len(previousIDs) == 0 {
+			return nil
+		}
+		monsterID = previousIDs[0]
+	}
+
+	return fmt.Errorf("evolution chain is longer than %d", maxEvolutionDepth)
+}
+
+// isSerializationFailure check error of serializable transaction which can be retried
+func isSerializationFailure(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "40001"
+}
+
+// evolutionError translate error constraint from postgres
+func evolutionError(err error, evolution domain.Evolution) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505":
+			return fmt.Errorf("monster with id %s already evolves from other monster", evolution.ToMonsterID)
+		case "23503":
+			return errors.New("invalid from monster id or to monster id")
+		case "40001":
+			return errors.New("evolution chain is changed by other request, try again")
+		}
+	}
+	return err
+}
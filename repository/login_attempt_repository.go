@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/letenk/pokedex/models/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LoginAttemptRepository interface {
+	FindByKeys(ctx context.Context, keys []string) ([]domain.LoginAttempt, error)
+	Reserve(ctx context.Context, keys []string, window time.Duration, allow func(attempts []domain.LoginAttempt) error) error
+	Release(ctx context.Context, key string) error
+	Delete(ctx context.Context, key string) error
+}
+
+type loginAttemptRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginAttemptRepository(db *gorm.DB) *loginAttemptRepository {
+	return &loginAttemptRepository{db}
+}
+
+func (r *loginAttemptRepository) FindByKeys(ctx context.Context, keys []string) ([]domain.LoginAttempt, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	var attempts []domain.LoginAttempt
+	err := r.db.WithContext(ctx).Where("key IN ?", keys).Find(&attempts).Error
+	if err != nil {
+		return attempts, err
+	}
+
+	return attempts, nil
+}
+
+// Reserve lock attempts of keys, call allow with them and count one failure of every key when allow return nil.
+// The attempt is counted before password is compared, so concurrent guesses can't pass the limit,
+// the caller release or delete the keys when the attempt succeeds.
+func (r *loginAttemptRepository) Reserve(ctx context.Context, keys []string, window time.Duration, allow func(attempts []domain.LoginAttempt) error) error {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	// Same order in every transaction, so concurrent attempts don't deadlock
+	keys = append([]string(nil), keys...)
+	sort.Strings(keys)
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Row must exist to be locked, new row has no failure
+		for _, key := range keys {
+			err := tx.Exec(`
+				INSERT INTO login_attempts (key, failures, last_failed_at) VALUES (?, 0, now())
+				ON CONFLICT (key) DO NOTHING
+			`, key).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		// Concurrent attempt of the same keys wait until this one is counted
+		var attempts []domain.LoginAttempt
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key IN ?", keys).Order("key").Find(&attempts).Error
+		if err != nil {
+			return err
+		}
+
+		err = allow(attempts)
+		if err != nil {
+			return err
+		}
+
+		for _, key := range keys {
+			_, err := registerFailure(tx, key, window)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Release remove one failure counted by Reserve of a succeeded attempt
+func (r *loginAttemptRepository) Release(ctx context.Context, key string) error {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	return r.db.WithContext(ctx).Model(&domain.LoginAttempt{}).
+		Where("key = ? AND failures > 0", key).
+		UpdateColumn("failures", gorm.Expr("failures - 1")).Error
+}
+
+// registerFailure increment failures of key in one statement, so concurrent failure is not lost.
+// Failures start again from 1 when the last failure is older than window.
+func registerFailure(db *gorm.DB, key string, window time.Duration) (domain.LoginAttempt, error) {
+	var attempt domain.LoginAttempt
+	err := db.Raw(`
+		INSERT INTO login_attempts (key, failures, last_failed_at) VALUES (?, 1, now())
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE
+				WHEN login_attempts.last_failed_at < now() - make_interval(secs => ?) THEN 1
+				ELSE login_attempts.failures + 1
+			END,
+			last_failed_at = now()
+		RETURNING key, failures, last_failed_at
+	`, key, window.Seconds()).Scan(&attempt).Error
+	if err != nil {
+		return attempt, err
+	}
+
+	return attempt, nil
+}
+
+func (r *loginAttemptRepository) Delete(ctx context.Context, key string) error {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	return r.db.WithContext(ctx).Where("key = ?", key).Delete(&domain.LoginAttempt{}).Error
+}
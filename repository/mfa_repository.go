@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/letenk/pokedex/models/domain"
+	"gorm.io/gorm"
+)
+
+type MFARepository interface {
+	UseStep(ctx context.Context, userID string, step int64) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userID string, codeHash string) (bool, error)
+	DeleteRecoveryCodes(ctx context.Context, userID string) error
+}
+
+type mfaRepository struct {
+	db *gorm.DB
+}
+
+func NewMFARepository(db *gorm.DB) *mfaRepository {
+	return &mfaRepository{db}
+}
+
+// UseStep save step of TOTP code used by user, false when the step or newer step is already used
+func (r *mfaRepository) UseStep(ctx context.Context, userID string, step int64) (bool, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	result := r.db.WithContext(ctx).Model(&domain.User{}).
+		Where("id = ? AND mfa_last_step < ?", userID, step).
+		UpdateColumn("mfa_last_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected != 0, nil
+}
+
+// ReplaceRecoveryCodes delete old recovery codes of user and save the new codes
+func (r *mfaRepository) ReplaceRecoveryCodes(ctx context.Context, userID string, codeHashes []string) error {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ?", userID).Delete(&domain.MFARecoveryCode{}).Error
+		if err != nil {
+			return err
+		}
+
+		var codes []domain.MFARecoveryCode
+		for _, codeHash := range codeHashes {
+			codes = append(codes, domain.MFARecoveryCode{UserID: userID, CodeHash: codeHash})
+		}
+
+		return tx.Create(&codes).Error
+	})
+}
+
+// UseRecoveryCode mark recovery code as used, false when the code is not found or already used
+func (r *mfaRepository) UseRecoveryCode(ctx context.Context, userID string, codeHash string) (bool, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	result := r.db.WithContext(ctx).Model(&domain.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected != 0, nil
+}
+
+func (r *mfaRepository) DeleteRecoveryCodes(ctx context.Context, userID string) error {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&domain.MFARecoveryCode{}).Error
+}
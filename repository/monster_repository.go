@@ -14,13 +14,24 @@ import (
 )
 
 type MonsterRepository interface {
-	FindAll(ctx context.Context, reqQuery web.MonsterQueryRequest) ([]domain.Monster, error)
+	FindAll(ctx context.Context, reqQuery web.MonsterQueryRequest) ([]domain.Monster, web.Pagination, error)
 	FindByID(ctx context.Context, ID string) (domain.Monster, error)
 	Create(ctx context.Context, monster domain.Monster) (domain.Monster, error)
 	Update(ctx context.Context, monster domain.Monster) (domain.Monster, error)
 	Delete(ctx context.Context, monster domain.Monster) (bool, error)
+	FindTrash(ctx context.Context, reqQuery web.MonsterTrashQueryRequest) ([]domain.Monster, web.Pagination, error)
+	Restore(ctx context.Context, ID string) (domain.Monster, error)
+	FindPurgeable(ctx context.Context, deletedBefore time.Time, limit int) ([]domain.Monster, error)
+	Purge(ctx context.Context, monster domain.Monster, deletedBefore time.Time) (bool, error)
+	Touch(ctx context.Context, IDs []string) error
 }
 
+const (
+	// Default and max of monsters in one page
+	defaultLimit = 20
+	maxLimit     = 100
+)
+
 type monsterRespository struct {
 	db *gorm.DB
 }
@@ -75,16 +86,88 @@ func (r *monsterRespository) Create(ctx context.Context, monster domain.Monster)
 	return monster, nil
 }
 
-func (r *monsterRespository) FindAll(ctx context.Context, reqQuery web.MonsterQueryRequest) ([]domain.Monster, error) {
+func (r *monsterRespository) FindAll(ctx context.Context, reqQuery web.MonsterQueryRequest) ([]domain.Monster, web.Pagination, error) {
 	// Create a context in order to disconnect
 	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
 	// Cancel context after all process ends
 	defer cancel()
 
 	var monsters []domain.Monster
+	var pagination web.Pagination
+
+	// Validate pagination
+	limit := reqQuery.Limit
+	if limit == 0 {
+		limit = defaultLimit
+	}
+	if limit < 1 || limit > maxLimit {
+		return monsters, pagination, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+	}
+	if reqQuery.Page < 0 {
+		return monsters, pagination, errors.New("page must be greater than 0")
+	}
+	if reqQuery.Page != 0 && reqQuery.Cursor != "" {
+		return monsters, pagination, errors.New("page and cursor can not be used together")
+	}
+
+	// Parse sort, e.g. `-attack,name`
+	sorts, err := parseMonsterSort(reqQuery.Sort, reqQuery.Order)
+	if err != nil {
+		return monsters, pagination, err
+	}
+
+	db, err := r.filter(r.db.WithContext(ctx).Model(&domain.Monster{}), reqQuery)
+	if err != nil {
+		return monsters, pagination, err
+	}
+
+	// Count all monsters matched with filter
+	var total int64
+	err = db.Count(&total).Error
+	if err != nil {
+		return monsters, pagination, err
+	}
+
+	if reqQuery.Cursor != "" {
+		cursor, err := decodeMonsterCursor(reqQuery.Cursor, sorts)
+		if err != nil {
+			return monsters, pagination, err
+		}
+		db = db.Where(keysetCondition(sorts, cursor.Values))
+	} else {
+		page := reqQuery.Page
+		if page == 0 {
+			page = 1
+		}
+		db = db.Offset((page - 1) * limit)
+
+		pagination.Page = page
+		pagination.TotalPages = int((total + int64(limit) - 1) / int64(limit))
+	}
+
+	for _, sort := range sorts {
+		db = db.Order(sort.orderBy())
+	}
+
+	// Take one more monster for know there is next page
+	err = db.Limit(limit + 1).Preload("Category").Preload("Types").Find(&monsters).Error
+	if err != nil {
+		return monsters, pagination, err
+	}
+
+	pagination.Total = total
+	pagination.Limit = limit
+
+	if len(monsters) > limit {
+		monsters = monsters[:limit]
+		pagination.NextCursor = encodeMonsterCursor(sorts, monsters[len(monsters)-1])
+	}
 
-	db := r.db.WithContext(ctx).Model(&domain.Monster{})
+	return monsters, pagination, nil
+}
 
+// filter apply query parameter filter into db
+func (r *monsterRespository) filter(db *gorm.DB, reqQuery web.MonsterQueryRequest) (*gorm.DB, error) {
 	if reqQuery.Name != "" {
 		db = db.Where("lower(monsters.name) LIKE lower(?)", "%"+reqQuery.Name+"%")
 	}
@@ -92,41 +175,86 @@ func (r *monsterRespository) FindAll(ctx context.Context, reqQuery web.MonsterQu
 	if reqQuery.Catched != "" {
 		boolCatched, err := strconv.ParseBool(reqQuery.Catched)
 		if err != nil {
-			return monsters, err
+			return db, err
 		}
-		db = db.Where("catched = ?", boolCatched)
-	}
 
-	// For use query parameter order, sort must not empty
-	if reqQuery.Order != "" {
-		if reqQuery.Sort != "" && reqQuery.Order != "" {
-			sortQuery := fmt.Sprintf("%s %s", reqQuery.Sort, reqQuery.Order)
-			db.Order(sortQuery)
+		// Captured is relative to user login, guest never captured any monster
+		if reqQuery.UserID == "" {
+			if boolCatched {
+				db = db.Where("1 = 0")
+			}
+		} else if boolCatched {
+			db = db.Where("EXISTS (SELECT 1 FROM user_monsters um WHERE um.monster_id = monsters.id AND um.user_id = ?)", reqQuery.UserID)
 		} else {
-			return monsters, errors.New("for use order, query parameter sort is required")
+			db = db.Where("NOT EXISTS (SELECT 1 FROM user_monsters um WHERE um.monster_id = monsters.id AND um.user_id = ?)", reqQuery.UserID)
 		}
 	}
 
-	if reqQuery.Sort != "" {
-		db.Order(reqQuery.Sort)
-	}
-
 	if len(reqQuery.Types) != 0 {
-		err := db.Preload("Category").Preload("Types").Joins("inner join monster_types mt on mt.monster_id = monsters.id ").Joins("inner join types t on t.id = mt.type_id ").Where("t.id IN ?", reqQuery.Types).Group("monsters.id").Find(&monsters).Error
-		if err != nil {
-			return monsters, err
+		switch reqQuery.TypesMode {
+		case "", "any":
+			// Monster has any of types
+			db = db.Where("EXISTS (SELECT 1 FROM monster_types mt WHERE mt.monster_id = monsters.id AND mt.type_id IN ?)", reqQuery.Types)
+		case "all":
+			// Monster has every types, count distinct because types can be duplicated in request
+			distinctTypes := map[string]bool{}
+			for _, typeID := range reqQuery.Types {
+				distinctTypes[typeID] = true
+			}
+			db = db.Where("(SELECT COUNT(DISTINCT mt.type_id) FROM monster_types mt WHERE mt.monster_id = monsters.id AND mt.type_id IN ?) = ?", reqQuery.Types, len(distinctTypes))
+		default:
+			return db, errors.New("types_mode must be any or all")
 		}
+	} else if reqQuery.TypesMode != "" && reqQuery.TypesMode != "any" && reqQuery.TypesMode != "all" {
+		return db, errors.New("types_mode must be any or all")
+	}
 
-		return monsters, nil
-	} else {
-		err := db.Preload("Category").Preload("Types").Find(&monsters).Error
-		if err != nil {
-			return monsters, err
+	if len(reqQuery.ExcludeTypes) != 0 {
+		db = db.Where("NOT EXISTS (SELECT 1 FROM monster_types mt WHERE mt.monster_id = monsters.id AND mt.type_id IN ?)", reqQuery.ExcludeTypes)
+	}
+
+	if reqQuery.CategoryID != "" {
+		db = db.Where("monsters.category_id = ?", reqQuery.CategoryID)
+	}
+
+	// Range filter of stats
+	ranges := []struct {
+		field  string
+		column string
+		min    *float64
+		max    *float64
+	}{
+		{"hp", "monsters.hp", reqQuery.HpMin, reqQuery.HpMax},
+		{"attack", "monsters.attack", reqQuery.AttackMin, reqQuery.AttackMax},
+		{"defends", "monsters.defends", reqQuery.DefendsMin, reqQuery.DefendsMax},
+		{"speed", "monsters.speed", reqQuery.SpeedMin, reqQuery.SpeedMax},
+		{"weight", "monsters.weight", reqQuery.WeightMin, reqQuery.WeightMax},
+		{"length", "monsters.length", reqQuery.LengthMin, reqQuery.LengthMax},
+	}
+
+	for _, statRange := range ranges {
+		min, max := statRange.min, statRange.max
+
+		if min != nil && *min < 0 {
+			return db, fmt.Errorf("%s_min must be greater than or equal to 0", statRange.field)
+		}
+		if max != nil && *max < 0 {
+			return db, fmt.Errorf("%s_max must be greater than or equal to 0", statRange.field)
+		}
+		if min != nil && max != nil && *min > *max {
+			return db, fmt.Errorf("%s_min must be less than or equal to %s_max", statRange.field, statRange.field)
 		}
 
-		return monsters, nil
+		if min != nil {
+			db = db.Where(statRange.column+" >= ?", *min)
+		}
+		if max != nil {
+			db = db.Where(statRange.column+" <= ?", *max)
+		}
 	}
 
+	// New session, so db can be used for count and find
+	return db.Session(&gorm.Session{}), nil
 }
 
 func (r *monsterRespository) FindByID(ctx context.Context, ID string) (domain.Monster, error) {
@@ -159,8 +287,24 @@ func (r *monsterRespository) Update(ctx context.Context, monster domain.Monster)
 
 	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 
-		// Update data in table monster
-		err := tx.WithContext(ctx).Save(&monster).Error
+		// Update data in table monster, only when version is not changed since the monster is read
+		result := tx.WithContext(ctx).Model(&domain.Monster{}).
+			Where("id = ? AND version = ?", monster.ID, monster.Version).
+			Updates(map[string]interface{}{
+				"name":        monster.Name,
+				"category_id": monster.CategoryID,
+				"description": monster.Description,
+				"length":      monster.Length,
+				"weight":      monster.Weight,
+				"hp":          monster.Hp,
+				"attack":      monster.Attack,
+				"defends":     monster.Defends,
+				"speed":       monster.Speed,
+				"image_name":  monster.ImageName,
+				"image_url":   monster.ImageURL,
+				"version":     gorm.Expr("version + 1"),
+			})
+		err := result.Error
 		if err != nil {
 			var pgErr *pgconn.PgError
 			if errors.As(err, &pgErr) {
@@ -173,6 +317,9 @@ func (r *monsterRespository) Update(ctx context.Context, monster domain.Monster)
 			}
 			return err
 		}
+		if result.RowsAffected == 0 {
+			return domain.ErrMonsterVersionConflict
+		}
 
 		if len(monster.TypeID) != 0 {
 			tx.WithContext(ctx).Where("monster_id = ?", monster.ID).Delete(&domain.MonsterType{})
@@ -219,6 +366,128 @@ func (r *monsterRespository) Delete(ctx context.Context, monster domain.Monster)
 	// Cancel context after all process ends
 	defer cancel()
 
+	// Move monster into trash, types and captured are kept so monster can be restored
+	err := r.db.WithContext(ctx).Where("id = ?", monster.ID).Delete(&domain.Monster{}).Error
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Touch set updated_at of monsters to now without new version, used when an embedded entity
+// of the monsters (evolution chain, type) is removed and leaves no updated_at of its own
+func (r *monsterRespository) Touch(ctx context.Context, IDs []string) error {
+	// Create a context in order to disconnect
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	// Cancel context after all process ends
+	defer cancel()
+
+	return touchMonsters(r.db.WithContext(ctx), IDs)
+}
+
+// touchMonsters set updated_at of monsters in the transaction tx, IDs may be a slice or a subquery
+func touchMonsters(tx *gorm.DB, IDs interface{}) error {
+	return tx.Model(&domain.Monster{}).Where("id IN (?)", IDs).UpdateColumn("updated_at", time.Now()).Error
+}
+
+func (r *monsterRespository) FindTrash(ctx context.Context, reqQuery web.MonsterTrashQueryRequest) ([]domain.Monster, web.Pagination, error) {
+	// Create a context in order to disconnect
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	// Cancel context after all process ends
+	defer cancel()
+
+	var monsters []domain.Monster
+	var pagination web.Pagination
+
+	// Validate pagination
+	limit := reqQuery.Limit
+	if limit == 0 {
+		limit = defaultLimit
+	}
+	if limit < 1 || limit > maxLimit {
+		return monsters, pagination, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+	}
+	if reqQuery.Page < 0 {
+		return monsters, pagination, errors.New("page must be greater than 0")
+	}
+
+	// New session, so db can be used for count and find
+	db := r.db.WithContext(ctx).Unscoped().Model(&domain.Monster{}).Where("deleted_at IS NOT NULL").Session(&gorm.Session{})
+
+	var total int64
+	err := db.Count(&total).Error
+	if err != nil {
+		return monsters, pagination, err
+	}
+
+	page := reqQuery.Page
+	if page == 0 {
+		page = 1
+	}
+
+	// Last deleted first
+	err = db.Preload("Category").Order("deleted_at desc").Order("id").Offset((page - 1) * limit).Limit(limit).Find(&monsters).Error
+	if err != nil {
+		return monsters, pagination, err
+	}
+
+	pagination.Total = total
+	pagination.Limit = limit
+	pagination.Page = page
+	pagination.TotalPages = int((total + int64(limit) - 1) / int64(limit))
+
+	return monsters, pagination, nil
+}
+
+func (r *monsterRespository) Restore(ctx context.Context, ID string) (domain.Monster, error) {
+	// Create a context in order to disconnect
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	// Cancel context after all process ends
+	defer cancel()
+
+	// Restored monster is a new version, so update with version before delete is rejected
+	result := r.db.WithContext(ctx).Unscoped().Model(&domain.Monster{}).
+		Where("id = ? AND deleted_at IS NOT NULL", ID).
+		Updates(map[string]interface{}{
+			"deleted_at": nil,
+			"version":    gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return domain.Monster{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		errMessage := fmt.Sprintf("monster with id %s not found in trash", ID)
+		return domain.Monster{}, errors.New(errMessage)
+	}
+
+	return r.FindByID(ctx, ID)
+}
+
+func (r *monsterRespository) FindPurgeable(ctx context.Context, deletedBefore time.Time, limit int) ([]domain.Monster, error) {
+	// Create a context in order to disconnect
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	// Cancel context after all process ends
+	defer cancel()
+
+	var monsters []domain.Monster
+	err := r.db.WithContext(ctx).Unscoped().Where("deleted_at < ?", deletedBefore).Order("deleted_at").Limit(limit).Find(&monsters).Error
+	if err != nil {
+		return monsters, err
+	}
+
+	return monsters, nil
+}
+
+// errMonsterNotPurgeable rollback purge of monster which is restored or deleted again after it is found
+var errMonsterNotPurgeable = errors.New("monster is not purgeable")
+
+func (r *monsterRespository) Purge(ctx context.Context, monster domain.Monster, deletedBefore time.Time) (bool, error) {
+	// Create a context in order to disconnect
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	// Cancel context after all process ends
+	defer cancel()
+
 	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 
 		// Remove all type which is monster_id with this id
@@ -227,15 +496,27 @@ func (r *monsterRespository) Delete(ctx context.Context, monster domain.Monster)
 			return err
 		}
 
-		// Remove monster from table monster
-		err = tx.WithContext(ctx).Where("id = ?", monster.ID).Delete(&domain.Monster{}).Error
+		// Remove all captured which is monster_id with this id
+		err = tx.WithContext(ctx).Where("monster_id = ?", monster.ID).Delete(&domain.UserMonster{}).Error
 		if err != nil {
 			return err
 		}
 
+		// Remove monster from table monster, only when it is still in trash since before retention
+		result := tx.WithContext(ctx).Unscoped().Where("id = ? AND deleted_at < ?", monster.ID, deletedBefore).Delete(&domain.Monster{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errMonsterNotPurgeable
+		}
+
 		return nil
 	})
 
+	if err == errMonsterNotPurgeable {
+		return false, nil
+	}
 	if err != nil {
 		return false, err
 	}
@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/letenk/pokedex/models/domain"
+	"gorm.io/gorm/clause"
+)
+
+// monsterSortFields is allowed field for sort monsters, in order for error message
+var monsterSortFields = []string{"id", "name", "hp", "attack", "defends", "speed", "weight", "length", "created_at", "total"}
+
+// monsterSortColumns is column of each allowed field
+var monsterSortColumns = map[string]string{
+	"id":         "monsters.id",
+	"name":       "monsters.name",
+	"hp":         "monsters.hp",
+	"attack":     "monsters.attack",
+	"defends":    "monsters.defends",
+	"speed":      "monsters.speed",
+	"weight":     "monsters.weight",
+	"length":     "monsters.length",
+	"created_at": "monsters.created_at",
+	"total":      "(monsters.hp + monsters.attack + monsters.defends + monsters.speed)",
+}
+
+// monsterSort is one field of sort
+type monsterSort struct {
+	Field string
+	Desc  bool
+}
+
+func (s monsterSort) column() string {
+	return monsterSortColumns[s.Field]
+}
+
+func (s monsterSort) orderBy() string {
+	if s.Desc {
+		return s.column() + " DESC"
+	}
+	return s.column() + " ASC"
+}
+
+// parseMonsterSort parse sort grammar, list of field separated by comma and prefix `-` for descending,
+// e.g. `-attack,name`. Order `asc` or `desc` is default direction for field without prefix.
+func parseMonsterSort(sort string, order string) ([]monsterSort, error) {
+	var desc bool
+	switch strings.ToLower(order) {
+	case "":
+	case "asc":
+	case "desc":
+		desc = true
+	default:
+		return nil, errors.New("order must be asc or desc")
+	}
+
+	// For use query parameter order, sort must not empty
+	if order != "" && sort == "" {
+		return nil, errors.New("for use order, query parameter sort is required")
+	}
+
+	var sorts []monsterSort
+	used := map[string]bool{}
+
+	if sort != "" {
+		for _, field := range strings.Split(sort, ",") {
+			field = strings.TrimSpace(field)
+
+			fieldDesc := desc
+			if strings.HasPrefix(field, "-") {
+				field = strings.TrimPrefix(field, "-")
+				fieldDesc = true
+			}
+
+			if _, ok := monsterSortColumns[field]; !ok {
+				return nil, fmt.Errorf("invalid sort field %q, allowed fields are %s", field, strings.Join(monsterSortFields, ", "))
+			}
+			if used[field] {
+				return nil, fmt.Errorf("duplicate sort field %q", field)
+			}
+			used[field] = true
+
+			sorts = append(sorts, monsterSort{Field: field, Desc: fieldDesc})
+		}
+	} else {
+		// Default sort, oldest monster first
+		sorts = append(sorts, monsterSort{Field: "created_at"})
+		used["created_at"] = true
+	}
+
+	// Id as tie breaker, so the order is stable between pages
+	if !used["id"] {
+		sorts = append(sorts, monsterSort{Field: "id"})
+	}
+
+	return sorts, nil
+}
+
+// monsterSortValue get value of field sort from monster, as string for query parameter
+func monsterSortValue(monster domain.Monster, field string) string {
+	switch field {
+	case "id":
+		return monster.ID
+	case "name":
+		return monster.Name
+	case "hp":
+		return strconv.Itoa(int(monster.Hp))
+	case "attack":
+		return strconv.Itoa(int(monster.Attack))
+	case "defends":
+		return strconv.Itoa(int(monster.Defends))
+	case "speed":
+		return strconv.Itoa(int(monster.Speed))
+	case "weight":
+		return strconv.Itoa(int(monster.Weight))
+	case "length":
+		// Column is float8, format as float64 for same value with the column
+		return strconv.FormatFloat(float64(monster.Length), 'g', -1, 64)
+	case "created_at":
+		return monster.CreatedAt.Format(time.RFC3339Nano)
+	case "total":
+		return strconv.Itoa(int(monster.Hp) + int(monster.Attack) + int(monster.Defends) + int(monster.Speed))
+	}
+	return ""
+}
+
+// keysetCondition create condition for monsters after values of cursor, e.g. for sort `-attack,id`:
+// (attack < ?) OR (attack = ? AND id > ?)
+func keysetCondition(sorts []monsterSort, values []string) clause.Expr {
+	var conditions []string
+	var vars []interface{}
+
+	for i, sort := range sorts {
+		var condition []string
+		for j := 0; j < i; j++ {
+			condition = append(condition, sorts[j].column()+" = ?")
+			vars = append(vars, values[j])
+		}
+
+		operator := ">"
+		if sort.Desc {
+			operator = "<"
+		}
+		condition = append(condition, fmt.Sprintf("%s %s ?", sort.column(), operator))
+		vars = append(vars, values[i])
+
+		conditions = append(conditions, "("+strings.Join(condition, " AND ")+")")
+	}
+
+	return clause.Expr{SQL: "(" + strings.Join(conditions, " OR ") + ")", Vars: vars}
+}
+
+// sortKey is normalized sort, so cursor only valid for the same sort
+func sortKey(sorts []monsterSort) string {
+	var fields []string
+	for _, sort := range sorts {
+		if sort.Desc {
+			fields = append(fields, "-"+sort.Field)
+		} else {
+			fields = append(fields, sort.Field)
+		}
+	}
+	return strings.Join(fields, ",")
+}
+
+// monsterCursor is position of last monster in page, for keyset pagination
+type monsterCursor struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+}
+
+// encodeMonsterCursor encode position of monster into opaque string
+func encodeMonsterCursor(sorts []monsterSort, monster domain.Monster) string {
+	cursor := monsterCursor{Sort: sortKey(sorts)}
+	for _, sort := range sorts {
+		cursor.Values = append(cursor.Values, monsterSortValue(monster, sort.Field))
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeMonsterCursor decode opaque string into cursor, cursor must be created with the same sort
+func decodeMonsterCursor(encoded string, sorts []monsterSort) (monsterCursor, error) {
+	var cursor monsterCursor
+
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return cursor, errors.New("invalid cursor")
+	}
+
+	err = json.Unmarshal(data, &cursor)
+	if err != nil || len(cursor.Values) != len(sorts) {
+		return cursor, errors.New("invalid cursor")
+	}
+
+	if cursor.Sort != sortKey(sorts) {
+		return cursor, errors.New("cursor is not valid for this sort")
+	}
+
+	return cursor, nil
+}
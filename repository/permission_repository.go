@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/letenk/pokedex/models/domain"
+	"gorm.io/gorm"
+)
+
+type PermissionRepository interface {
+	FindByRole(ctx context.Context, role string) ([]domain.RolePermission, error)
+	RoleExists(ctx context.Context, role string) (bool, error)
+}
+
+type permissionRepository struct {
+	db *gorm.DB
+}
+
+func NewPermissionRepository(db *gorm.DB) *permissionRepository {
+	return &permissionRepository{db}
+}
+
+func (r *permissionRepository) FindByRole(ctx context.Context, role string) ([]domain.RolePermission, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	var permissions []domain.RolePermission
+	err := r.db.WithContext(ctx).Where("role = ?", role).Find(&permissions).Error
+	if err != nil {
+		return permissions, err
+	}
+
+	return permissions, nil
+}
+
+func (r *permissionRepository) RoleExists(ctx context.Context, role string) (bool, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	var count int64
+	err := r.db.WithContext(ctx).Model(&domain.RolePermission{}).Where("role = ?", role).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	return count != 0, nil
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/letenk/pokedex/models/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TokenRepository interface {
+	CreateRefreshToken(ctx context.Context, refreshToken domain.RefreshToken) (domain.RefreshToken, error)
+	FindRefreshTokenByHash(ctx context.Context, tokenHash string) (domain.RefreshToken, error)
+	RevokeRefreshToken(ctx context.Context, ID string) (bool, error)
+	RevokeFamily(ctx context.Context, familyID string) error
+	RevokeByUserID(ctx context.Context, userID string) error
+	RevokeAccessToken(ctx context.Context, revokedToken domain.RevokedToken) error
+	IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+type tokenRepository struct {
+	db *gorm.DB
+}
+
+func NewTokenRepository(db *gorm.DB) *tokenRepository {
+	return &tokenRepository{db}
+}
+
+func (r *tokenRepository) CreateRefreshToken(ctx context.Context, refreshToken domain.RefreshToken) (domain.RefreshToken, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	err := r.db.WithContext(ctx).Create(&refreshToken).Error
+	if err != nil {
+		return refreshToken, err
+	}
+
+	return refreshToken, nil
+}
+
+func (r *tokenRepository) FindRefreshTokenByHash(ctx context.Context, tokenHash string) (domain.RefreshToken, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	var refreshToken domain.RefreshToken
+	err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).Find(&refreshToken).Error
+	if err != nil {
+		return refreshToken, err
+	}
+
+	return refreshToken, nil
+}
+
+// RevokeRefreshToken revoke refresh token, false when it is already revoked
+func (r *tokenRepository) RevokeRefreshToken(ctx context.Context, ID string) (bool, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	result := r.db.WithContext(ctx).Model(&domain.RefreshToken{}).
+		Where("id = ? AND revoked_at IS NULL", ID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected != 0, nil
+}
+
+func (r *tokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	err := r.db.WithContext(ctx).Model(&domain.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *tokenRepository) RevokeByUserID(ctx context.Context, userID string) error {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	err := r.db.WithContext(ctx).Model(&domain.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *tokenRepository) RevokeAccessToken(ctx context.Context, revokedToken domain.RevokedToken) error {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Token which is already expired is rejected anyway, no need to keep it
+		err := tx.WithContext(ctx).Where("expires_at < ?", time.Now()).Delete(&domain.RevokedToken{}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&revokedToken).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *tokenRepository) IsAccessTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	var count int64
+	err := r.db.WithContext(ctx).Model(&domain.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+
+	return count != 0, nil
+}
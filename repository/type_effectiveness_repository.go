@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/letenk/pokedex/models/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TypeEffectivenessRepository interface {
+	FindByType(ctx context.Context, typeID string) ([]domain.TypeEffectiveness, error)
+	FindByDefenders(ctx context.Context, defenderTypeIDs []string) ([]domain.TypeEffectiveness, error)
+	Save(ctx context.Context, effectiveness domain.TypeEffectiveness) (domain.TypeEffectiveness, error)
+	Delete(ctx context.Context, attackerTypeID string, defenderTypeID string) (bool, error)
+}
+
+type typeEffectivenessRepository struct {
+	db *gorm.DB
+}
+
+func NewTypeEffectivenessRepository(db *gorm.DB) *typeEffectivenessRepository {
+	return &typeEffectivenessRepository{db}
+}
+
+func (r *typeEffectivenessRepository) FindByType(ctx context.Context, typeID string) ([]domain.TypeEffectiveness, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	// Type as attacker or as defender
+	var effectiveness []domain.TypeEffectiveness
+	err := r.db.WithContext(ctx).
+		Where("attacker_type_id = ? OR defender_type_id = ?", typeID, typeID).
+		Preload("AttackerType").
+		Preload("DefenderType").
+		Find(&effectiveness).Error
+	if err != nil {
+		return effectiveness, err
+	}
+
+	return effectiveness, nil
+}
+
+func (r *typeEffectivenessRepository) FindByDefenders(ctx context.Context, defenderTypeIDs []string) ([]domain.TypeEffectiveness, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	var effectiveness []domain.TypeEffectiveness
+	err := r.db.WithContext(ctx).
+		Where("defender_type_id IN ?", defenderTypeIDs).
+		Find(&effectiveness).Error
+	if err != nil {
+		return effectiveness, err
+	}
+
+	return effectiveness, nil
+}
+
+func (r *typeEffectivenessRepository) Save(ctx context.Context, effectiveness domain.TypeEffectiveness) (domain.TypeEffectiveness, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	// Insert, or update multiplier when pair of attacker and defender already exists
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "attacker_type_id"}, {Name: "defender_type_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"multiplier", "updated_at"}),
+	}).Omit("AttackerType", "DefenderType").Create(&effectiveness).Error
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			if pgErr.Code == "23503" {
+				return effectiveness, errors.New("invalid attacker type id or defender type id")
+			}
+		}
+		return effectiveness, err
+	}
+
+	return effectiveness, nil
+}
+
+func (r *typeEffectivenessRepository) Delete(ctx context.Context, attackerTypeID string, defenderTypeID string) (bool, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	result := r.db.WithContext(ctx).
+		Where("attacker_type_id = ? AND defender_type_id = ?", attackerTypeID, defenderTypeID).
+		Delete(&domain.TypeEffectiveness{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return false, errors.New("type effectiveness not found")
+	}
+
+	return true, nil
+}
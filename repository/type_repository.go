@@ -2,14 +2,22 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
+	"github.com/jackc/pgconn"
 	"github.com/letenk/pokedex/models/domain"
 	"gorm.io/gorm"
 )
 
 type TypeRepository interface {
 	FindAll(ctx context.Context) ([]domain.Type, error)
+	FindByID(ctx context.Context, ID string) (domain.Type, error)
+	Create(ctx context.Context, types domain.Type) (domain.Type, error)
+	Update(ctx context.Context, types domain.Type) (domain.Type, error)
+	Delete(ctx context.Context, types domain.Type, cascade bool) (bool, error)
+	CountMonsters(ctx context.Context, ID string) (int64, error)
 }
 
 type typeRespository struct {
@@ -33,3 +41,112 @@ func (r *typeRespository) FindAll(ctx context.Context) ([]domain.Type, error) {
 
 	return types, nil
 }
+
+func (r *typeRespository) FindByID(ctx context.Context, ID string) (domain.Type, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	var types domain.Type
+	err := r.db.WithContext(ctx).Where("id = ?", ID).Find(&types).Error
+	if err != nil {
+		return types, err
+	}
+
+	if types.ID == "" {
+		errMessage := fmt.Sprintf("type with id %s not found", ID)
+		return types, errors.New(errMessage)
+	}
+
+	return types, nil
+}
+
+func (r *typeRespository) Create(ctx context.Context, types domain.Type) (domain.Type, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	err := r.db.WithContext(ctx).Create(&types).Error
+	if err != nil {
+		return types, typeError(err, types)
+	}
+
+	return types, nil
+}
+
+func (r *typeRespository) Update(ctx context.Context, types domain.Type) (domain.Type, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	err := r.db.WithContext(ctx).Save(&types).Error
+	if err != nil {
+		return types, typeError(err, types)
+	}
+
+	return types, nil
+}
+
+func (r *typeRespository) Delete(ctx context.Context, types domain.Type, cascade bool) (bool, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Remove type from monsters, the monsters are touched since the removed relation has no updated_at
+		if cascade {
+			monsterIDs := tx.WithContext(ctx).Model(&domain.MonsterType{}).Select("monster_id").Where("type_id = ?", types.ID)
+			err := touchMonsters(tx.WithContext(ctx), monsterIDs)
+			if err != nil {
+				return err
+			}
+
+			err = tx.WithContext(ctx).Where("type_id = ?", types.ID).Delete(&domain.MonsterType{}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		// Remove type
+		err := tx.WithContext(ctx).Where("id = ?", types.ID).Delete(&domain.Type{}).Error
+		if err != nil {
+			return typeError(err, types)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (r *typeRespository) CountMonsters(ctx context.Context, ID string) (int64, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	var count int64
+	err := r.db.WithContext(ctx).Model(&domain.MonsterType{}).Where("type_id = ?", ID).Count(&count).Error
+	if err != nil {
+		return count, err
+	}
+
+	return count, nil
+}
+
+// typeError translate error constraint from postgres
+func typeError(err error, types domain.Type) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "23505":
+			return fmt.Errorf("type with name %s already exists", types.Name)
+		case "23503":
+			return errors.New("type is still used by monsters, please delete with cascade for remove the type from monsters")
+		}
+	}
+	return err
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/letenk/pokedex/models/domain"
+	"gorm.io/gorm"
+)
+
+type UserIdentityRepository interface {
+	FindBySubject(ctx context.Context, issuer string, subject string) (domain.UserIdentity, error)
+	Provision(ctx context.Context, user domain.User, identity domain.UserIdentity) (domain.User, error)
+}
+
+type userIdentityRepository struct {
+	db *gorm.DB
+}
+
+func NewUserIdentityRepository(db *gorm.DB) *userIdentityRepository {
+	return &userIdentityRepository{db}
+}
+
+func (r *userIdentityRepository) FindBySubject(ctx context.Context, issuer string, subject string) (domain.UserIdentity, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	var identity domain.UserIdentity
+	err := r.db.WithContext(ctx).Where("issuer = ? AND subject = ?", issuer, subject).Find(&identity).Error
+	if err != nil {
+		return identity, err
+	}
+
+	return identity, nil
+}
+
+// Provision create user and the identity in one transaction, so user is never created without identity
+func (r *userIdentityRepository) Provision(ctx context.Context, user domain.User, identity domain.UserIdentity) (domain.User, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(&user).Error
+		if err != nil {
+			return userError(err, user)
+		}
+
+		identity.UserID = user.ID
+		return tx.Create(&identity).Error
+	})
+	if err != nil {
+		return user, err
+	}
+
+	return user, nil
+}
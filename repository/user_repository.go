@@ -2,15 +2,25 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
+	"github.com/jackc/pgconn"
 	"github.com/letenk/pokedex/models/domain"
+	"github.com/letenk/pokedex/models/web"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserRepository interface {
+	FindAll(ctx context.Context, reqQuery web.UserQueryRequest) ([]domain.User, web.Pagination, error)
 	FindByUsername(ctx context.Context, username string) (domain.User, error)
 	FindByID(ctx context.Context, id string) (domain.User, error)
+	Create(ctx context.Context, user domain.User) (domain.User, error)
+	Update(ctx context.Context, user domain.User) (domain.User, error)
+	UpdateKeepAdmin(ctx context.Context, user domain.User) (domain.User, error)
 }
 
 type userRepository struct {
@@ -21,6 +31,73 @@ func NewUserRepository(db *gorm.DB) *userRepository {
 	return &userRepository{db}
 }
 
+func (r *userRepository) FindAll(ctx context.Context, reqQuery web.UserQueryRequest) ([]domain.User, web.Pagination, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	var users []domain.User
+	var pagination web.Pagination
+
+	// Validate pagination
+	limit := reqQuery.Limit
+	if limit == 0 {
+		limit = defaultLimit
+	}
+	if limit < 1 || limit > maxLimit {
+		return users, pagination, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+	}
+	if reqQuery.Page < 0 {
+		return users, pagination, errors.New("page must be greater than 0")
+	}
+
+	db := r.db.WithContext(ctx).Model(&domain.User{})
+
+	// Search by username or fullname
+	if reqQuery.Search != "" {
+		search := "%" + strings.ToLower(reqQuery.Search) + "%"
+		db = db.Where("lower(username) LIKE ? OR lower(fullname) LIKE ?", search, search)
+	}
+
+	if reqQuery.Role != "" {
+		db = db.Where("role = ?", reqQuery.Role)
+	}
+
+	if reqQuery.Active != nil {
+		if *reqQuery.Active {
+			db = db.Where("deactivated_at IS NULL")
+		} else {
+			db = db.Where("deactivated_at IS NOT NULL")
+		}
+	}
+
+	// New session, so db can be used for count and find
+	db = db.Session(&gorm.Session{})
+
+	var total int64
+	err := db.Count(&total).Error
+	if err != nil {
+		return users, pagination, err
+	}
+
+	page := reqQuery.Page
+	if page == 0 {
+		page = 1
+	}
+
+	err = db.Order("username").Order("id").Offset((page - 1) * limit).Limit(limit).Find(&users).Error
+	if err != nil {
+		return users, pagination, err
+	}
+
+	pagination.Total = total
+	pagination.Limit = limit
+	pagination.Page = page
+	pagination.TotalPages = int((total + int64(limit) - 1) / int64(limit))
+
+	return users, pagination, nil
+}
+
 func (r *userRepository) FindByUsername(ctx context.Context, username string) (domain.User, error) {
 	// Create a context in order to disconnect after 15 seconds
 	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
@@ -50,3 +127,88 @@ func (r *userRepository) FindByID(ctx context.Context, id string) (domain.User,
 
 	return user, nil
 }
+
+func (r *userRepository) Create(ctx context.Context, user domain.User) (domain.User, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	err := r.db.WithContext(ctx).Create(&user).Error
+	if err != nil {
+		return user, userError(err, user)
+	}
+
+	return user, nil
+}
+
+func (r *userRepository) Update(ctx context.Context, user domain.User) (domain.User, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	err := r.db.WithContext(ctx).Save(&user).Error
+	if err != nil {
+		return user, userError(err, user)
+	}
+
+	return user, nil
+}
+
+// UpdateKeepAdmin update user like Update, but return domain.ErrLastAdmin when no other active user
+// can manage users and the update removes the permission from the user. Active admins are locked,
+// so concurrent updates of two admins can't remove both.
+func (r *userRepository) UpdateKeepAdmin(ctx context.Context, user domain.User) (domain.User, error) {
+	// Create a context in order to disconnect after 15 seconds
+	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		adminRoles := tx.Model(&domain.RolePermission{}).Select("role").Where("permission = ?", domain.PermissionUserManage)
+
+		var adminIDs []string
+		err := tx.Model(&domain.User{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("deactivated_at IS NULL AND role IN (?)", adminRoles).
+			Pluck("id", &adminIDs).Error
+		if err != nil {
+			return err
+		}
+
+		// Only the last admin can't lose the permission
+		if len(adminIDs) == 1 && adminIDs[0] == user.ID {
+			var isAdmin int64
+			err = tx.Model(&domain.RolePermission{}).
+				Where("role = ? AND permission = ?", user.Role, domain.PermissionUserManage).
+				Count(&isAdmin).Error
+			if err != nil {
+				return err
+			}
+
+			if user.DeactivatedAt != nil || isAdmin == 0 {
+				return domain.ErrLastAdmin
+			}
+		}
+
+		err = tx.Save(&user).Error
+		if err != nil {
+			return userError(err, user)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return user, err
+	}
+
+	return user, nil
+}
+
+// userError translate error constraint from postgres
+func userError(err error, user domain.User) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		if pgErr.Code == "23505" {
+			return fmt.Errorf("username %s already exists", user.Username)
+		}
+	}
+	return err
+}
@@ -1,47 +1,116 @@
 package router
 
 import (
+	"context"
+	"log"
 	"net/http"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/letenk/pokedex/handlers"
 	"github.com/letenk/pokedex/middleware"
+	"github.com/letenk/pokedex/models/domain"
 	"github.com/letenk/pokedex/repository"
 	"github.com/letenk/pokedex/usecase"
+	"github.com/letenk/pokedex/util"
 	"gorm.io/gorm"
 )
 
-func SetupRouter(db *gorm.DB) *gin.Engine {
+// SetupRouter setup routes of the api, without background jobs
+func SetupRouter(db *gorm.DB, config util.Config, cache usecase.Cache) *gin.Engine {
+	router, _ := SetupRouterWithJobs(db, config, cache)
+	return router
+}
+
+// SetupRouterWithJobs setup routes and return function which starts background jobs with the same usecases,
+// the jobs run until ctx is done
+func SetupRouterWithJobs(db *gorm.DB, config util.Config, cache usecase.Cache) (*gin.Engine, func(ctx context.Context)) {
 	router := gin.Default()
+	// Client ip is taken from X-Forwarded-For only when request comes from trusted proxy
+	err := router.SetTrustedProxies(config.TRUSTED_PROXIES)
+	if err != nil {
+		log.Fatal("cannot set trusted proxies:", err)
+	}
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"https://*", "http://*"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE"},
-		AllowHeaders:     []string{"Accept", "Authorization", "Content-Type"},
-		ExposeHeaders:    []string{"Link"},
+		AllowHeaders:     []string{"Accept", "Authorization", "Content-Type", "X-API-Key", "X-Request-ID", "If-Match", "If-None-Match", "If-Modified-Since"},
+		ExposeHeaders:    []string{"Link", "Retry-After", "X-Request-ID", "ETag", "Last-Modified"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
+	router.Use(middleware.RequestIDMiddleware())
+
+	// Keys for sign and verify access token
+	keySet, err := usecase.NewKeySet(config)
+	if err != nil {
+		log.Fatal("cannot load jwt keys:", err)
+	}
+
+	// Use layers audit, every write is recorded
+	repositoryAudit := repository.NewAuditRepository(db)
+	usecaseAudit := usecase.NewUsecaseAudit(repositoryAudit)
+	handlerAudit := handlers.NewHandlerAudit(usecaseAudit)
 
 	// Use layers users
 	repositoryUser := repository.NewUserRepository(db)
-	usecaseUser := usecase.NewUsecaseUser(repositoryUser)
+	repositoryToken := repository.NewTokenRepository(db)
+	repositoryPermission := repository.NewPermissionRepository(db)
+	repositoryLoginAttempt := repository.NewLoginAttemptRepository(db)
+	repositoryMFA := repository.NewMFARepository(db)
+	usecaseUser := usecase.NewUsecaseUser(repositoryUser, repositoryToken, repositoryPermission, repositoryLoginAttempt, repositoryMFA, usecaseAudit, keySet, config)
 	handlerUser := handlers.NewHandlerUser(usecaseUser)
 
+	// Use layers permission
+	usecasePermission := usecase.NewUsecasePermission(repositoryPermission)
+
+	// Use layers api key
+	repositoryAPIKey := repository.NewAPIKeyRepository(db)
+	usecaseAPIKey := usecase.NewUsecaseAPIKey(repositoryAPIKey, repositoryUser, repositoryPermission, usecaseAudit)
+	handlerAPIKey := handlers.NewHandlerAPIKey(usecaseAPIKey)
+
+	// Authenticate with token or api key
+	authMiddleware := middleware.AuthMiddleware(usecaseUser, usecaseAPIKey)
+	optionalAuthMiddleware := middleware.OptionalAuthMiddleware(usecaseUser, usecaseAPIKey)
+
+	// Use layers oidc
+	repositoryUserIdentity := repository.NewUserIdentityRepository(db)
+	providerOIDC := usecase.NewOIDCProvider(config)
+	usecaseOIDC := usecase.NewUsecaseOIDC(providerOIDC, repositoryUserIdentity, repositoryUser, usecaseUser, usecaseAudit, keySet, config)
+	handlerOIDC := handlers.NewHandlerOIDC(usecaseOIDC)
+
+	// Image store for monster images
+	imageStore, err := usecase.NewImageStore(config)
+	if err != nil {
+		log.Fatal("cannot create image store:", err)
+	}
+
+	// Use layers montser
+	repositoryMonster := repository.NewMonsterRespository(db)
+	repositoryCapture := repository.NewCaptureRepository(db)
+	repositoryEvolution := repository.NewEvolutionRepository(db)
+	// Monsters are read through cache, the cache is removed on every write of monster
+	usecaseMonster := usecase.NewCachedMonsterUsecase(usecase.NewUsecaseMonster(repositoryMonster, repositoryCapture, repositoryEvolution, imageStore, usecaseAudit), cache)
+	handlerMonster := handlers.NewHandlerMonster(usecaseMonster)
+
 	// Use layers category
 	repositoryCategory := repository.NewCategoryRepository(db)
-	usecaseCategory := usecase.NewUsecaseCategory(repositoryCategory)
-	handlerCategory := handlers.NewHandlerCategory(usecaseCategory)
+	usecaseCategory := usecase.NewUsecaseCategory(repositoryCategory, usecaseAudit)
+	handlerCategory := handlers.NewHandlerCategory(usecaseCategory, cache, usecaseMonster)
 
 	// Use layers type
 	repositoryType := repository.NewTypeRespository(db)
-	usecaseType := usecase.NewUsecaseType(repositoryType)
-	handlerType := handlers.NewHandlerType(usecaseType)
+	usecaseType := usecase.NewUsecaseType(repositoryType, usecaseAudit)
+	handlerType := handlers.NewHandlerType(usecaseType, cache, usecaseMonster)
 
-	// Use layers montser
-	repositoryMonster := repository.NewMonsterRespository(db)
-	usecaseMonster := usecase.NewUsecaseMonster(repositoryMonster)
-	handlerMonster := handlers.NewHandlerMonster(usecaseMonster)
+	// Use layers type effectiveness
+	repositoryTypeEffectiveness := repository.NewTypeEffectivenessRepository(db)
+	usecaseTypeEffectiveness := usecase.NewUsecaseTypeEffectiveness(repositoryTypeEffectiveness, repositoryType, repositoryMonster, usecaseAudit)
+	handlerTypeEffectiveness := handlers.NewHandlerTypeEffectiveness(usecaseTypeEffectiveness)
+
+	// Use layers evolution
+	usecaseEvolution := usecase.NewUsecaseEvolution(repositoryEvolution, repositoryMonster, usecaseAudit)
+	handlerEvolution := handlers.NewHandlerEvolution(usecaseEvolution, usecaseMonster)
 
 	// Route home
 	router.GET("/", func(c *gin.Context) {
@@ -49,30 +118,108 @@ func SetupRouter(db *gorm.DB) *gin.Engine {
 		c.JSON(http.StatusOK, resp)
 	})
 
+	// Public keys for verify access token by other services
+	router.GET("/.well-known/jwks.json", handlerUser.JWKS)
+
+	// Serve monster images when stored on local disk
+	if config.IMAGE_STORAGE == "local" {
+		router.Static("/static/images", config.IMAGE_LOCAL_DIR)
+	}
+
 	// Group api version 1
 	v1 := router.Group("/api/v1")
 
 	// Login
 	v1.POST("/login", handlerUser.Login)
+	v1.POST("/register", handlerUser.Register)
+	v1.POST("/login/mfa", handlerUser.LoginMFA)
+	v1.POST("/login/mfa/enroll", handlerUser.EnrollMFAWithToken)
+	v1.POST("/token/refresh", handlerUser.RefreshToken)
+	// Login with OpenID Connect provider
+	if config.OIDC_ISSUER != "" {
+		v1.GET("/oidc/login", handlerOIDC.Login)
+		v1.GET("/oidc/callback", handlerOIDC.Callback)
+	}
+	v1.POST("/logout", authMiddleware, middleware.RequireSession(), handlerUser.Logout)
+	// Profile of user login, api key can not manage the account
+	me := v1.Group("/me", authMiddleware, middleware.RequireSession())
+	me.GET("", handlerUser.Me)
+	me.PATCH("", handlerUser.UpdateProfile)
+	me.PATCH("/password", handlerUser.ChangePassword)
+	me.POST("/mfa", handlerUser.EnrollMFA)
+	me.POST("/mfa/verify", handlerUser.ConfirmMFA)
+	me.DELETE("/mfa", handlerUser.DisableMFA)
+	// Users management
+	users := v1.Group("/users", authMiddleware, middleware.RequirePermission(usecasePermission, domain.PermissionUserManage))
+	users.GET("", handlerUser.FindAll)
+	users.GET("/:id", handlerUser.FindByID)
+	users.POST("", handlerUser.Create)
+	users.PATCH("/:id/role", handlerUser.ChangeRole)
+	users.PATCH("/:id/password", handlerUser.ResetPassword)
+	users.POST("/:id/deactivate", handlerUser.Deactivate)
+	users.POST("/:id/activate", handlerUser.Activate)
+	users.POST("/:id/unlock", handlerUser.Unlock)
+	users.GET("/:id/api-keys", handlerAPIKey.FindAllByUser)
+	users.DELETE("/:id/api-keys/:key_id", handlerAPIKey.RevokeByUser)
+	// Audit log of write operations
+	v1.GET("/audit", authMiddleware, middleware.RequirePermission(usecasePermission, domain.PermissionAuditRead), handlerAudit.FindAll)
+	// Api keys of user login, api key can not create or revoke other api key
+	apiKeys := v1.Group("/api-keys", authMiddleware, middleware.RequireSession())
+	apiKeys.GET("", handlerAPIKey.FindAll)
+	apiKeys.POST("", handlerAPIKey.Create)
+	apiKeys.DELETE("/:id", handlerAPIKey.Revoke)
 	// Categories
-	v1.GET("/category", middleware.AuthMiddleware(usecaseUser), handlerCategory.FindAll)
+	category := v1.Group("/category", authMiddleware)
+	category.GET("", middleware.RequirePermission(usecasePermission, domain.PermissionCategoryRead), handlerCategory.FindAll)
+	category.GET("/:id", middleware.RequirePermission(usecasePermission, domain.PermissionCategoryRead), handlerCategory.FindByID)
+	category.POST("", middleware.RequirePermission(usecasePermission, domain.PermissionCategoryWrite), handlerCategory.Create)
+	category.PATCH("/:id", middleware.RequirePermission(usecasePermission, domain.PermissionCategoryWrite), handlerCategory.Update)
+	category.DELETE("/:id", middleware.RequirePermission(usecasePermission, domain.PermissionCategoryWrite), handlerCategory.Delete)
 	// Types
-	v1.GET("/type", middleware.AuthMiddleware(usecaseUser), handlerType.FindAll)
+	types := v1.Group("/type", authMiddleware)
+	types.GET("", middleware.RequirePermission(usecasePermission, domain.PermissionTypeRead), handlerType.FindAll)
+	types.GET("/:id", middleware.RequirePermission(usecasePermission, domain.PermissionTypeRead), handlerType.FindByID)
+	types.POST("", middleware.RequirePermission(usecasePermission, domain.PermissionTypeWrite), handlerType.Create)
+	types.PATCH("/:id", middleware.RequirePermission(usecasePermission, domain.PermissionTypeWrite), handlerType.Update)
+	types.DELETE("/:id", middleware.RequirePermission(usecasePermission, domain.PermissionTypeWrite), handlerType.Delete)
+	// Type effectiveness, type on path `id` is the attacker
+	types.GET("/:id/matchups", handlerTypeEffectiveness.Matchups)
+	types.PUT("/:id/matchups/:defender_id", middleware.RequirePermission(usecasePermission, domain.PermissionTypeWrite), handlerTypeEffectiveness.Save)
+	types.DELETE("/:id/matchups/:defender_id", middleware.RequirePermission(usecasePermission, domain.PermissionTypeWrite), handlerTypeEffectiveness.Delete)
+
+	// Evolutions
+	evolution := v1.Group("/evolution", authMiddleware)
+	evolution.POST("", middleware.RequirePermission(usecasePermission, domain.PermissionEvolutionWrite), handlerEvolution.Create)
+	evolution.PATCH("/:id", middleware.RequirePermission(usecasePermission, domain.PermissionEvolutionWrite), handlerEvolution.Update)
+	evolution.DELETE("/:id", middleware.RequirePermission(usecasePermission, domain.PermissionEvolutionWrite), handlerEvolution.Delete)
 
 	// Group endpoint monster
 	monster := v1.Group("/monster")
 	// Find all monster
-	monster.GET("", handlerMonster.FindAll)
+	monster.GET("", optionalAuthMiddleware, handlerMonster.FindAll)
+	// Monsters in trash
+	monster.GET("/trash", authMiddleware, middleware.RequirePermission(usecasePermission, domain.PermissionMonsterTrash), handlerMonster.FindTrash)
 	// Find by id monster
-	monster.GET("/:id", handlerMonster.FindByID)
+	monster.GET("/:id", optionalAuthMiddleware, handlerMonster.FindByID)
+	// Weaknesses of monster from the types
+	monster.GET("/:id/weaknesses", handlerTypeEffectiveness.Weaknesses)
 	// Create monster
-	monster.POST("", middleware.AuthMiddleware(usecaseUser), handlerMonster.Create)
-	// Update monster
-	monster.PATCH("/:id", middleware.AuthMiddleware(usecaseUser), handlerMonster.Update)
+	monster.POST("", authMiddleware, middleware.RequirePermission(usecasePermission, domain.PermissionMonsterWrite), handlerMonster.Create)
 	// Update monster
-	monster.PATCH("/:id/captured", middleware.AuthMiddleware(usecaseUser), handlerMonster.UpdateMarkMonsterCaptured)
-	// Update monster
-	monster.DELETE("/:id", middleware.AuthMiddleware(usecaseUser), handlerMonster.Delete)
+	monster.PATCH("/:id", authMiddleware, middleware.RequirePermission(usecasePermission, domain.PermissionMonsterWrite), handlerMonster.Update)
+	// Mark monster captured by user login
+	monster.PATCH("/:id/captured", authMiddleware, middleware.RequirePermission(usecasePermission, domain.PermissionMonsterCapture), handlerMonster.UpdateMarkMonsterCaptured)
+	// Delete monster, monster is moved into trash
+	monster.DELETE("/:id", authMiddleware, middleware.RequirePermission(usecasePermission, domain.PermissionMonsterWrite), handlerMonster.Delete)
+	// Restore monster from trash
+	monster.POST("/:id/restore", authMiddleware, middleware.RequirePermission(usecasePermission, domain.PermissionMonsterTrash), handlerMonster.Restore)
 
-	return router
+	startJobs := func(ctx context.Context) {
+		// Purge monsters in trash after retention period, only on instance which enables it
+		if config.MONSTER_PURGE_ENABLED {
+			go usecase.StartMonsterTrashPurge(ctx, usecaseMonster, config)
+		}
+	}
+
+	return router, startJobs
 }
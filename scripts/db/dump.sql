@@ -6,6 +6,10 @@ CREATE TABLE IF NOT EXISTS users(
   "fullname" varchar NOT NULL,
   "password" varchar NOT NULL,
   "role" varchar NOT NULL,
+  "deactivated_at" timestamptz,
+  "mfa_secret" varchar NOT NULL DEFAULT '',
+  "mfa_enabled_at" timestamptz,
+  "mfa_last_step" bigint NOT NULL DEFAULT 0,
   "created_at" timestamptz NOT NULL DEFAULT (now()),
   "updated_at" timestamptz NOT NULL DEFAULT (now())
 );
@@ -35,7 +39,6 @@ CREATE TABLE IF NOT EXISTS monsters (
   "attack" int NOT NULL,
   "defends" int NOT NULL,
   "speed" int NOT NULL,
-  "catched" boolean NOT NULL DEFAULT false,
   "image_name" varchar NOT NULL,
   "image_url" varchar NOT NULL,
   "created_at" timestamptz NOT NULL DEFAULT (now()),
@@ -47,24 +50,217 @@ CREATE TABLE IF NOT EXISTS  monster_types (
   "type_id" uuid NOT NULL
 );
 
+CREATE TABLE IF NOT EXISTS user_monsters (
+  "user_id" uuid NOT NULL,
+  "monster_id" uuid NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("user_id", "monster_id")
+);
+
+CREATE TABLE IF NOT EXISTS type_effectiveness (
+  "attacker_type_id" uuid NOT NULL,
+  "defender_type_id" uuid NOT NULL,
+  "multiplier" float8 NOT NULL CHECK ("multiplier" >= 0),
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  PRIMARY KEY ("attacker_type_id", "defender_type_id")
+);
+
+CREATE TABLE IF NOT EXISTS evolutions (
+  "id" uuid PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "from_monster_id" uuid NOT NULL,
+  "to_monster_id" uuid NOT NULL,
+  "trigger" varchar NOT NULL CHECK ("trigger" IN ('level', 'item', 'condition')),
+  "level" int,
+  "item" varchar,
+  "condition" varchar,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  CHECK ("from_monster_id" <> "to_monster_id")
+);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+  "id" uuid PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "user_id" uuid NOT NULL,
+  "family_id" uuid NOT NULL DEFAULT (uuid_generate_v4()),
+  "token_hash" varchar NOT NULL UNIQUE,
+  "expires_at" timestamptz NOT NULL,
+  "revoked_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+-- Denylist of access token by jti
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+  "jti" varchar PRIMARY KEY,
+  "expires_at" timestamptz NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+-- Account of external identity provider linked to user
+CREATE TABLE IF NOT EXISTS user_identities (
+  "id" uuid PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "user_id" uuid NOT NULL,
+  "issuer" varchar NOT NULL,
+  "subject" varchar NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz NOT NULL DEFAULT (now()),
+  UNIQUE ("issuer", "subject")
+);
+
+-- One time codes for login when authenticator is lost
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+  "id" uuid PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "user_id" uuid NOT NULL,
+  "code_hash" varchar NOT NULL,
+  "used_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+-- Failed login per username or ip, for brute-force protection
+CREATE TABLE IF NOT EXISTS login_attempts (
+  "key" varchar PRIMARY KEY,
+  "failures" int NOT NULL DEFAULT 0,
+  "last_failed_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+-- Api keys for service access, only hash of the key is saved
+CREATE TABLE IF NOT EXISTS api_keys (
+  "id" uuid PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "user_id" uuid NOT NULL,
+  "name" varchar NOT NULL,
+  "prefix" varchar NOT NULL,
+  "key_hash" varchar NOT NULL UNIQUE,
+  "scopes" varchar NOT NULL,
+  "expires_at" timestamptz NOT NULL,
+  "last_used_at" timestamptz,
+  "revoked_at" timestamptz,
+  "created_at" timestamptz NOT NULL DEFAULT (now()),
+  "updated_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+-- Audit of write operations, actor is not a foreign key so the entry is kept as it was
+CREATE TABLE IF NOT EXISTS audit_logs (
+  "id" uuid PRIMARY KEY DEFAULT (uuid_generate_v4()),
+  "actor_id" varchar NOT NULL DEFAULT '',
+  "actor_username" varchar NOT NULL DEFAULT '',
+  "action" varchar NOT NULL,
+  "entity" varchar NOT NULL,
+  "entity_id" varchar NOT NULL DEFAULT '',
+  "changes" jsonb,
+  "request_id" varchar NOT NULL DEFAULT '',
+  "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+-- Permissions granted to each role, new role only need new rows
+CREATE TABLE IF NOT EXISTS role_permissions (
+  "role" varchar NOT NULL,
+  "permission" varchar NOT NULL,
+  PRIMARY KEY ("role", "permission")
+);
+
+-- Captured is tracked per user in table user_monsters
+ALTER TABLE "monsters" DROP COLUMN IF EXISTS "catched";
+
+-- Version of monster for optimistic concurrency, incremented on every update
+ALTER TABLE "monsters" ADD COLUMN IF NOT EXISTS "version" int NOT NULL DEFAULT 1;
+
+-- Deleted monster is kept in trash until purged
+ALTER TABLE "monsters" ADD COLUMN IF NOT EXISTS "deleted_at" timestamptz;
+
+-- Deactivated user can not login
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "deactivated_at" timestamptz;
+
+-- Two-factor authentication with TOTP
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "mfa_secret" varchar NOT NULL DEFAULT '';
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "mfa_enabled_at" timestamptz;
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "mfa_last_step" bigint NOT NULL DEFAULT 0;
+
+CREATE UNIQUE INDEX IF NOT EXISTS "users_username_key" ON "users" (lower("username"));
+
+CREATE INDEX IF NOT EXISTS "refresh_tokens_family_id_idx" ON "refresh_tokens" ("family_id");
+
+CREATE INDEX IF NOT EXISTS "api_keys_user_id_idx" ON "api_keys" ("user_id");
+
+CREATE INDEX IF NOT EXISTS "mfa_recovery_codes_user_id_idx" ON "mfa_recovery_codes" ("user_id");
+
+CREATE INDEX IF NOT EXISTS "audit_logs_created_at_idx" ON "audit_logs" ("created_at");
+
+CREATE INDEX IF NOT EXISTS "audit_logs_actor_id_idx" ON "audit_logs" ("actor_id", "created_at");
+
+CREATE INDEX IF NOT EXISTS "audit_logs_entity_idx" ON "audit_logs" ("entity", "entity_id", "created_at");
+
+CREATE UNIQUE INDEX IF NOT EXISTS "categories_name_key" ON "categories" (lower("name"));
+
+CREATE UNIQUE INDEX IF NOT EXISTS "types_name_key" ON "types" (lower("name"));
+
+-- Monster evolves from at most one monster, so chain is a tree
+CREATE UNIQUE INDEX IF NOT EXISTS "evolutions_to_monster_id_key" ON "evolutions" ("to_monster_id");
+
+CREATE INDEX IF NOT EXISTS "evolutions_from_monster_id_idx" ON "evolutions" ("from_monster_id");
+
+CREATE INDEX IF NOT EXISTS "monsters_deleted_at_idx" ON "monsters" ("deleted_at") WHERE "deleted_at" IS NOT NULL;
+
 ALTER TABLE "monsters" ADD FOREIGN KEY ("category_id") REFERENCES "categories" ("id");
 
 ALTER TABLE "monster_types" ADD FOREIGN KEY ("monster_id") REFERENCES "monsters" ("id");
 
 ALTER TABLE "monster_types" ADD FOREIGN KEY ("type_id") REFERENCES "types" ("id");
 
+ALTER TABLE "type_effectiveness" ADD FOREIGN KEY ("attacker_type_id") REFERENCES "types" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "type_effectiveness" ADD FOREIGN KEY ("defender_type_id") REFERENCES "types" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "evolutions" ADD FOREIGN KEY ("from_monster_id") REFERENCES "monsters" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "evolutions" ADD FOREIGN KEY ("to_monster_id") REFERENCES "monsters" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "refresh_tokens" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "api_keys" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "user_identities" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "mfa_recovery_codes" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "user_monsters" ADD FOREIGN KEY ("user_id") REFERENCES "users" ("id");
+
+ALTER TABLE "user_monsters" ADD FOREIGN KEY ("monster_id") REFERENCES "monsters" ("id");
+
 -- Delete data
+DELETE FROM user_monsters;
+DELETE FROM refresh_tokens;
+DELETE FROM api_keys;
+DELETE FROM login_attempts;
+DELETE FROM mfa_recovery_codes;
+DELETE FROM user_identities;
+DELETE FROM revoked_tokens;
+DELETE FROM audit_logs;
 DELETE FROM users;
+DELETE FROM role_permissions;
 DELETE FROM categories;
+DELETE FROM type_effectiveness;
 DELETE FROM types;
 
 -- Seed data
 INSERT INTO users (username, fullname, password, role) VALUES('admin', 'ADMIN', '$2a$04$euYwgSigV4MDtKR0pvnBXumov0IsFsfumR0fsjgwGcEqXNOpmp0Ju', 'admin'), ('user', 'USER', '$2a$04$yYhf5Y3wsZoYmlGWc.uX8OCfgA2oJgGl5GX73n5rvRlUpZQtOuOFG', 'user');
 
+INSERT INTO role_permissions (role, permission) VALUES
+  ('admin', 'category:read'), ('admin', 'category:write'),
+  ('admin', 'type:read'), ('admin', 'type:write'),
+  ('admin', 'monster:write'), ('admin', 'monster:capture'), ('admin', 'monster:trash'),
+  ('admin', 'evolution:write'), ('admin', 'user:manage'),
+  ('admin', 'audit:read'),
+  ('user', 'monster:capture');
+
 INSERT INTO categories (name) VALUES('Leaf Monster'), ('Diving Monster'), ('Lizard Monster');
 
 INSERT INTO types (name) VALUES('GRASS'), ('PSYCHIC'), ('FLYING'), ('FIRE'), ('WATER'), ('ELECTRIC'), ('BUG');
 
+INSERT INTO type_effectiveness (attacker_type_id, defender_type_id, multiplier)
+SELECT a.id, d.id, v.multiplier FROM (VALUES ('FIRE', 'GRASS', 2.0), ('FIRE', 'BUG', 2.0), ('FIRE', 'FIRE', 0.5), ('FIRE', 'WATER', 0.5), ('WATER', 'FIRE', 2.0), ('WATER', 'WATER', 0.5), ('WATER', 'GRASS', 0.5), ('GRASS', 'WATER', 2.0), ('GRASS', 'FIRE', 0.5), ('GRASS', 'GRASS', 0.5), ('GRASS', 'FLYING', 0.5), ('GRASS', 'BUG', 0.5), ('ELECTRIC', 'WATER', 2.0), ('ELECTRIC', 'FLYING', 2.0), ('ELECTRIC', 'GRASS', 0.5), ('ELECTRIC', 'ELECTRIC', 0.5), ('FLYING', 'GRASS', 2.0), ('FLYING', 'BUG', 2.0), ('FLYING', 'ELECTRIC', 0.5), ('BUG', 'GRASS', 2.0), ('BUG', 'PSYCHIC', 2.0), ('BUG', 'FIRE', 0.5), ('BUG', 'FLYING', 0.5), ('PSYCHIC', 'PSYCHIC', 0.5)) AS v(attacker, defender, multiplier)
+JOIN types a ON a.name = v.attacker
+JOIN types d ON d.name = v.defender;
+
 -- Select
 SELECT * FROM users;
 SELECT * FROM categories;
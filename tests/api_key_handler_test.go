@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/letenk/pokedex/models/web"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyHandler(t *testing.T) {
+	t.Parallel()
+
+	// Helper for send request, header is `Authorization` or `X-API-Key`
+	send := func(method, target, header, value, dataBody string) (*http.Response, map[string]interface{}) {
+		request := httptest.NewRequest(method, target, strings.NewReader(dataBody))
+		request.Header.Add("Content-Type", "application/json")
+		if value != "" {
+			request.Header.Add(header, value)
+		}
+		recorder := httptest.NewRecorder()
+		RouteTest.ServeHTTP(recorder, request)
+
+		response := recorder.Result()
+		body, _ := io.ReadAll(response.Body)
+		var responseBody map[string]interface{}
+		json.Unmarshal(body, &responseBody)
+		return response, responseBody
+	}
+
+	token := GetToken(web.UserLoginRequest{Username: "admin", Password: "password"})
+	bearer := fmt.Sprintf("Bearer %s", token)
+
+	// Create api key only for read category
+	response, responseBody := send(http.MethodPost, "http://localhost:3000/api/v1/api-keys", "Authorization", bearer, `{"name": "ingestion", "scopes": ["category:read"], "expires_in_days": 30}`)
+	require.Equal(t, 201, response.StatusCode)
+	data := responseBody["data"].(map[string]interface{})
+	key := data["key"].(string)
+	require.NotEmpty(t, key)
+	require.Nil(t, data["key_hash"])
+	apiKeyID := data["id"].(string)
+
+	// Key is not shown on list
+	response, responseBody = send(http.MethodGet, "http://localhost:3000/api/v1/api-keys", "Authorization", bearer, "")
+	require.Equal(t, 200, response.StatusCode)
+	for _, item := range responseBody["data"].([]interface{}) {
+		require.Nil(t, item.(map[string]interface{})["key"])
+	}
+
+	// Access with api key on the scope
+	response, _ = send(http.MethodGet, "http://localhost:3000/api/v1/category", "X-API-Key", key, "")
+	require.Equal(t, 200, response.StatusCode)
+
+	// Failed access out of scope, even though role of user has the permission
+	response, _ = send(http.MethodGet, "http://localhost:3000/api/v1/type", "X-API-Key", key, "")
+	require.Equal(t, 403, response.StatusCode)
+
+	// Failed create api key with api key
+	response, _ = send(http.MethodPost, "http://localhost:3000/api/v1/api-keys", "X-API-Key", key, `{"name": "other", "scopes": ["category:read"]}`)
+	require.Equal(t, 403, response.StatusCode)
+
+	// Failed manage account, api keys and session of the owner with api key
+	forbidden := []struct {
+		method string
+		target string
+		body   string
+	}{
+		{http.MethodGet, "http://localhost:3000/api/v1/me", ""},
+		{http.MethodPatch, "http://localhost:3000/api/v1/me", `{"fullname": "changed"}`},
+		{http.MethodPatch, "http://localhost:3000/api/v1/me/password", `{"old_password": "password", "new_password": "password123"}`},
+		{http.MethodPost, "http://localhost:3000/api/v1/me/mfa", `{"password": "password"}`},
+		{http.MethodPost, "http://localhost:3000/api/v1/me/mfa/verify", `{"code": "123456"}`},
+		{http.MethodDelete, "http://localhost:3000/api/v1/me/mfa", `{"password": "password"}`},
+		{http.MethodGet, "http://localhost:3000/api/v1/api-keys", ""},
+		{http.MethodDelete, fmt.Sprintf("http://localhost:3000/api/v1/api-keys/%s", apiKeyID), ""},
+		{http.MethodPost, "http://localhost:3000/api/v1/logout", ""},
+	}
+	for _, req := range forbidden {
+		response, _ = send(req.method, req.target, "X-API-Key", key, req.body)
+		require.Equal(t, 403, response.StatusCode, "%s %s", req.method, req.target)
+	}
+
+	// Api key is not revoked by itself
+	response, _ = send(http.MethodGet, "http://localhost:3000/api/v1/category", "X-API-Key", key, "")
+	require.Equal(t, 200, response.StatusCode)
+
+	// Failed wrong key
+	response, _ = send(http.MethodGet, "http://localhost:3000/api/v1/category", "X-API-Key", "pdx_wrong", "")
+	require.Equal(t, 401, response.StatusCode)
+
+	// Revoke
+	response, responseBody = send(http.MethodDelete, fmt.Sprintf("http://localhost:3000/api/v1/api-keys/%s", apiKeyID), "Authorization", bearer, "")
+	require.Equal(t, 200, response.StatusCode)
+	require.NotNil(t, responseBody["data"].(map[string]interface{})["revoked_at"])
+
+	response, _ = send(http.MethodGet, "http://localhost:3000/api/v1/category", "X-API-Key", key, "")
+	require.Equal(t, 401, response.StatusCode)
+}
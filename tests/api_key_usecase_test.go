@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/letenk/pokedex/models/domain"
+	"github.com/letenk/pokedex/models/web"
+	"github.com/letenk/pokedex/repository"
+	"github.com/letenk/pokedex/usecase"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyUsecase(t *testing.T) {
+	t.Parallel()
+	repositoryUser := repository.NewUserRepository(ConnTest)
+	repositoryPermission := repository.NewPermissionRepository(ConnTest)
+	repository := repository.NewAPIKeyRepository(ConnTest)
+	usecase := usecase.NewUsecaseAPIKey(repository, repositoryUser, repositoryPermission, AuditTest)
+	ctx := context.Background()
+
+	user := RandomCreateUser(t, "user")
+
+	// Failed scope is not owned by role of user
+	_, _, err := usecase.Create(ctx, user, web.APIKeyCreateRequest{
+		Name:   "ingestion",
+		Scopes: []string{domain.PermissionMonsterWrite},
+	})
+	require.Error(t, err)
+	require.Equal(t, "scope monster:write is not permitted", err.Error())
+
+	// Create
+	apiKey, key, err := usecase.Create(ctx, user, web.APIKeyCreateRequest{
+		Name:   "ingestion",
+		Scopes: []string{domain.PermissionMonsterCapture},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, apiKey.ID)
+	require.True(t, strings.HasPrefix(key, apiKey.Prefix))
+	require.NotEqual(t, key, apiKey.KeyHash)
+	require.True(t, apiKey.HasScope(domain.PermissionMonsterCapture))
+	require.Nil(t, apiKey.LastUsedAt)
+
+	// Authenticate
+	authUser, authAPIKey, err := usecase.Authenticate(ctx, key)
+	require.NoError(t, err)
+	require.Equal(t, user.ID, authUser.ID)
+	require.Equal(t, apiKey.ID, authAPIKey.ID)
+	require.NotNil(t, authAPIKey.LastUsedAt)
+
+	// Last used is tracked
+	apiKeys, err := usecase.FindAll(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, apiKeys, 1)
+	require.NotNil(t, apiKeys[0].LastUsedAt)
+
+	// Failed wrong key
+	_, _, err = usecase.Authenticate(ctx, key+"wrong")
+	require.Error(t, err)
+	require.Equal(t, "invalid api key", err.Error())
+
+	// Failed revoke key of other user
+	otherUser := RandomCreateUser(t, "user")
+	_, err = usecase.Revoke(ctx, otherUser.ID, apiKey.ID)
+	require.Error(t, err)
+
+	// Revoke
+	apiKey, err = usecase.Revoke(ctx, user.ID, apiKey.ID)
+	require.NoError(t, err)
+	require.NotNil(t, apiKey.RevokedAt)
+
+	_, _, err = usecase.Authenticate(ctx, key)
+	require.Error(t, err)
+	require.Equal(t, "api key has been revoked", err.Error())
+}
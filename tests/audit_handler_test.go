@@ -0,0 +1,184 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/letenk/pokedex/models/web"
+	"github.com/letenk/pokedex/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditHandler(t *testing.T) {
+	t.Parallel()
+	start := time.Now().Add(-time.Second)
+	admin := RandomCreateUser(t, "admin")
+	token := GetToken(web.UserLoginRequest{Username: admin.Username, Password: "password"})
+
+	// Helper for send request as admin with request id
+	send := func(method string, target string, body string, requestID string) (int, map[string]interface{}) {
+		request := httptest.NewRequest(method, "http://localhost:3000"+target, strings.NewReader(body))
+		request.Header.Add("Content-Type", "application/json")
+		request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+		if requestID != "" {
+			request.Header.Add("X-Request-ID", requestID)
+		}
+		recorder := httptest.NewRecorder()
+		RouteTest.ServeHTTP(recorder, request)
+
+		response := recorder.Result()
+		if requestID != "" {
+			require.Equal(t, requestID, response.Header.Get("X-Request-ID"))
+		}
+		responseBody, _ := io.ReadAll(response.Body)
+		var data map[string]interface{}
+		json.Unmarshal(responseBody, &data)
+		return response.StatusCode, data
+	}
+
+	// Create and rename category
+	name := util.RandomString(10)
+	createRequestID := util.RandomString(16)
+	code, body := send(http.MethodPost, "/api/v1/category", fmt.Sprintf(`{"name": "%s"}`, name), createRequestID)
+	require.Equal(t, 201, code)
+	categoryID := body["data"].(map[string]interface{})["id"].(string)
+
+	newName := util.RandomString(10)
+	updateRequestID := util.RandomString(16)
+	code, _ = send(http.MethodPatch, "/api/v1/category/"+categoryID, fmt.Sprintf(`{"name": "%s"}`, newName), updateRequestID)
+	require.Equal(t, 200, code)
+
+	t.Run("filter_by_entity", func(t *testing.T) {
+		code, body := send(http.MethodGet, "/api/v1/audit?entity=category&entity_id="+categoryID, "", "")
+		require.Equal(t, 200, code)
+
+		logs := body["data"].([]interface{})
+		require.Equal(t, 2, len(logs))
+
+		// Newest first
+		update := logs[0].(map[string]interface{})
+		require.Equal(t, "update", update["action"])
+		require.Equal(t, admin.ID, update["actor_id"])
+		require.Equal(t, admin.Username, update["actor_username"])
+		require.Equal(t, updateRequestID, update["request_id"])
+		changes := update["changes"].(map[string]interface{})
+		require.Equal(t, 1, len(changes))
+		require.Equal(t, map[string]interface{}{"before": name, "after": newName}, changes["Name"])
+
+		create := logs[1].(map[string]interface{})
+		require.Equal(t, "create", create["action"])
+		require.Equal(t, createRequestID, create["request_id"])
+		changes = create["changes"].(map[string]interface{})
+		require.Equal(t, map[string]interface{}{"after": name}, changes["Name"])
+		require.Equal(t, map[string]interface{}{"after": categoryID}, changes["ID"])
+	})
+
+	t.Run("filter_by_actor_and_time_range", func(t *testing.T) {
+		query := url.Values{}
+		query.Set("actor_id", admin.ID)
+		query.Set("from", start.Format(time.RFC3339))
+		query.Set("to", time.Now().Add(time.Minute).Format(time.RFC3339))
+		code, body := send(http.MethodGet, "/api/v1/audit?"+query.Encode(), "", "")
+		require.Equal(t, 200, code)
+
+		// Login, create and update of the admin
+		actions := []string{}
+		for _, data := range body["data"].([]interface{}) {
+			auditLog := data.(map[string]interface{})
+			require.Equal(t, admin.ID, auditLog["actor_id"])
+			actions = append(actions, auditLog["action"].(string))
+		}
+		require.Equal(t, []string{"update", "create", "login"}, actions)
+
+		// Range before the writes
+		query.Set("from", start.Add(-time.Hour).Format(time.RFC3339))
+		query.Set("to", start.Format(time.RFC3339))
+		code, body = send(http.MethodGet, "/api/v1/audit?"+query.Encode(), "", "")
+		require.Equal(t, 200, code)
+		require.Equal(t, 0, len(body["data"].([]interface{})))
+	})
+
+	t.Run("failed_invalid_time_range", func(t *testing.T) {
+		query := url.Values{}
+		query.Set("from", time.Now().Format(time.RFC3339))
+		query.Set("to", start.Format(time.RFC3339))
+		code, _ := send(http.MethodGet, "/api/v1/audit?"+query.Encode(), "", "")
+		require.Equal(t, 400, code)
+	})
+
+	t.Run("failed_forbidden_with_role_user", func(t *testing.T) {
+		userToken := GetToken(web.UserLoginRequest{Username: "user", Password: "password"})
+		request := httptest.NewRequest(http.MethodGet, "http://localhost:3000/api/v1/audit", nil)
+		request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", userToken))
+		recorder := httptest.NewRecorder()
+		RouteTest.ServeHTTP(recorder, request)
+		require.Equal(t, 403, recorder.Result().StatusCode)
+	})
+}
+
+func TestAuditLoginFailedHandler(t *testing.T) {
+	t.Parallel()
+	user := RandomCreateUser(t, "user")
+
+	// Failed login is recorded with the user as actor
+	dataBody := fmt.Sprintf(`{"username": "%s", "password": "wrong password"}`, user.Username)
+	request := httptest.NewRequest(http.MethodPost, "http://localhost:3000/api/v1/login", strings.NewReader(dataBody))
+	request.Header.Add("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	RouteTest.ServeHTTP(recorder, request)
+	require.Equal(t, 400, recorder.Result().StatusCode)
+
+	adminToken := GetToken(web.UserLoginRequest{Username: "admin", Password: "password"})
+	request = httptest.NewRequest(http.MethodGet, "http://localhost:3000/api/v1/audit?action=login_failed&actor_id="+user.ID, nil)
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", adminToken))
+	recorder = httptest.NewRecorder()
+	RouteTest.ServeHTTP(recorder, request)
+
+	response := recorder.Result()
+	require.Equal(t, 200, response.StatusCode)
+	// Request id is generated when not sent
+	require.NotEmpty(t, response.Header.Get("X-Request-ID"))
+
+	responseBody, _ := io.ReadAll(response.Body)
+	var body map[string]interface{}
+	json.Unmarshal(responseBody, &body)
+
+	logs := body["data"].([]interface{})
+	require.Equal(t, 1, len(logs))
+	auditLog := logs[0].(map[string]interface{})
+	require.Equal(t, "user", auditLog["entity"])
+	require.Equal(t, user.ID, auditLog["entity_id"])
+	require.Equal(t, user.Username, auditLog["actor_username"])
+	require.NotEmpty(t, auditLog["request_id"])
+
+	// Unknown username is saved as change, actor is empty
+	from := time.Now().Add(-time.Second)
+	username := util.RandomString(12)
+	dataBody = fmt.Sprintf(`{"username": "%s", "password": "wrong password"}`, username)
+	request = httptest.NewRequest(http.MethodPost, "http://localhost:3000/api/v1/login", strings.NewReader(dataBody))
+	request.Header.Add("Content-Type", "application/json")
+	recorder = httptest.NewRecorder()
+	RouteTest.ServeHTTP(recorder, request)
+	require.Equal(t, 400, recorder.Result().StatusCode)
+
+	auditLogs, _, err := AuditTest.FindAll(context.Background(), web.AuditQueryRequest{Action: "login_failed", From: &from, Limit: 100})
+	require.NoError(t, err)
+	var found bool
+	for _, auditLog := range auditLogs {
+		if auditLog.Changes["Username"].After == username {
+			found = true
+			require.Empty(t, auditLog.ActorID)
+			require.Empty(t, auditLog.ActorUsername)
+			require.Empty(t, auditLog.EntityID)
+		}
+	}
+	require.True(t, found)
+}
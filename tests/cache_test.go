@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/letenk/pokedex/models/web"
+	"github.com/letenk/pokedex/router"
+	"github.com/letenk/pokedex/usecase"
+	"github.com/letenk/pokedex/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache(t *testing.T) {
+	t.Parallel()
+	server := miniredis.RunT(t)
+	server.RequireAuth("secret")
+
+	// Time of memory is real, time of redis server is moved forward by test
+	caches := map[string]struct {
+		cache   usecase.Cache
+		forward func(d time.Duration)
+	}{
+		"memory": {usecase.NewMemoryCache(0), time.Sleep},
+		"redis":  {usecase.NewRedisCache(server.Addr(), "secret", 1, "pokedex:"), server.FastForward},
+	}
+
+	for name, tc := range caches {
+		cache, forward := tc.cache, tc.forward
+
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+
+			// Miss
+			_, err := cache.Get(ctx, "monster_id_1")
+			require.ErrorIs(t, err, usecase.ErrCacheMiss)
+
+			// Set and get
+			err = cache.Set(ctx, "monster_id_1", []byte(`{"name":"pikachu"}`), time.Hour)
+			require.NoError(t, err)
+			err = cache.Set(ctx, "monster_id_2", []byte("binary\r\n\x00value"), time.Hour)
+			require.NoError(t, err)
+			err = cache.Set(ctx, "types", []byte("[]"), time.Hour)
+			require.NoError(t, err)
+
+			value, err := cache.Get(ctx, "monster_id_1")
+			require.NoError(t, err)
+			require.Equal(t, `{"name":"pikachu"}`, string(value))
+			value, err = cache.Get(ctx, "monster_id_2")
+			require.NoError(t, err)
+			require.Equal(t, "binary\r\n\x00value", string(value))
+
+			// Expired
+			err = cache.Set(ctx, "short", []byte("value"), 50*time.Millisecond)
+			require.NoError(t, err)
+			forward(100 * time.Millisecond)
+			_, err = cache.Get(ctx, "short")
+			require.ErrorIs(t, err, usecase.ErrCacheMiss)
+
+			// Delete by prefix only remove the keys started with prefix
+			err = cache.DeletePrefix(ctx, "monster_id_")
+			require.NoError(t, err)
+			_, err = cache.Get(ctx, "monster_id_1")
+			require.ErrorIs(t, err, usecase.ErrCacheMiss)
+			_, err = cache.Get(ctx, "monster_id_2")
+			require.ErrorIs(t, err, usecase.ErrCacheMiss)
+			_, err = cache.Get(ctx, "types")
+			require.NoError(t, err)
+
+			// Delete, key not exist is not error
+			err = cache.Delete(ctx, "types", "not_exist")
+			require.NoError(t, err)
+			_, err = cache.Get(ctx, "types")
+			require.ErrorIs(t, err, usecase.ErrCacheMiss)
+		})
+	}
+
+	t.Run("redis_keys_with_prefix", func(t *testing.T) {
+		err := caches["redis"].cache.Set(context.Background(), "categories", []byte("[]"), time.Hour)
+		require.NoError(t, err)
+		require.True(t, server.DB(1).Exists("pokedex:categories"))
+	})
+
+	t.Run("redis_failed_wrong_password", func(t *testing.T) {
+		cache := usecase.NewRedisCache(server.Addr(), "wrong", 0, "pokedex:")
+		_, err := cache.Get(context.Background(), "types")
+		require.Error(t, err)
+		require.NotErrorIs(t, err, usecase.ErrCacheMiss)
+	})
+}
+
+// Update on one instance must remove cache which is read by other instance
+func TestSharedCacheInvalidation(t *testing.T) {
+	monster, _ := RandomCreateMonster(t)
+	server := miniredis.RunT(t)
+
+	// Two instances of api with the same redis
+	config := ConfigTest
+	config.CACHE_DRIVER = "redis"
+	config.REDIS_ADDR = server.Addr()
+	config.REDIS_KEY_PREFIX = "pokedex:" + util.RandomString(6) + ":"
+
+	newRoute := func() http.Handler {
+		cache, err := usecase.NewCache(config)
+		require.NoError(t, err)
+		return router.SetupRouter(ConnTest, config, cache)
+	}
+	routeA := newRoute()
+	routeB := newRoute()
+
+	// Detail of monster as guest, response is cached
+	findName := func(route http.Handler) string {
+		request := httptest.NewRequest(http.MethodGet, "http://localhost:3000/api/v1/monster/"+monster.ID, nil)
+		recorder := httptest.NewRecorder()
+		route.ServeHTTP(recorder, request)
+
+		response := recorder.Result()
+		require.Equal(t, 200, response.StatusCode)
+		body, _ := io.ReadAll(response.Body)
+		var responseBody map[string]interface{}
+		json.Unmarshal(body, &responseBody)
+		return responseBody["data"].(map[string]interface{})["name"].(string)
+	}
+
+	require.Equal(t, monster.Name, findName(routeA))
+	require.True(t, server.Exists(config.REDIS_KEY_PREFIX+"monster_id_"+monster.ID))
+
+	// Update name on other instance
+	newName := util.RandomString(10)
+	bodyRequest := new(bytes.Buffer)
+	writer := multipart.NewWriter(bodyRequest)
+	writer.WriteField("name", newName)
+	writer.WriteField("version", strconv.Itoa(int(monster.Version)))
+	writer.Close()
+
+	token := GetToken(web.UserLoginRequest{Username: "admin", Password: "password"})
+	request := httptest.NewRequest(http.MethodPatch, "http://localhost:3000/api/v1/monster/"+monster.ID, bodyRequest)
+	request.Header.Add("Content-Type", writer.FormDataContentType())
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	recorder := httptest.NewRecorder()
+	routeB.ServeHTTP(recorder, request)
+	require.Equal(t, 200, recorder.Result().StatusCode)
+
+	// Cache is removed before response, so the first instance read new name
+	require.False(t, server.Exists(config.REDIS_KEY_PREFIX+"monster_id_"+monster.ID))
+	require.Equal(t, newName, findName(routeA))
+}
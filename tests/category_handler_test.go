@@ -6,9 +6,11 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/letenk/pokedex/models/web"
+	"github.com/letenk/pokedex/util"
 	"github.com/stretchr/testify/require"
 )
 
@@ -107,3 +109,77 @@ func TestFindAllCategoryHandlerTest(t *testing.T) {
 		})
 	}
 }
+
+// Not parallel, so created categories are never picked by random monster in other tests
+func TestCategoryHandlerCRUD(t *testing.T) {
+	tokenAdmin := GetToken(web.UserLoginRequest{Username: "admin", Password: "password"})
+	tokenUser := GetToken(web.UserLoginRequest{Username: "user", Password: "password"})
+
+	// Helper for send request
+	send := func(method, target, token, dataBody string) (*http.Response, map[string]interface{}) {
+		request := httptest.NewRequest(method, target, strings.NewReader(dataBody))
+		request.Header.Add("Content-Type", "application/json")
+		request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+		recorder := httptest.NewRecorder()
+		RouteTest.ServeHTTP(recorder, request)
+
+		response := recorder.Result()
+		body, _ := io.ReadAll(response.Body)
+		var responseBody map[string]interface{}
+		json.Unmarshal(body, &responseBody)
+		return response, responseBody
+	}
+
+	name := util.RandomString(10)
+
+	// Forbidden with role user
+	response, responseBody := send(http.MethodPost, "http://localhost:3000/api/v1/category", tokenUser, fmt.Sprintf(`{"name": "%s"}`, name))
+	require.Equal(t, 403, response.StatusCode)
+	require.Equal(t, "forbidden", responseBody["message"])
+
+	// Failed without name
+	response, responseBody = send(http.MethodPost, "http://localhost:3000/api/v1/category", tokenAdmin, `{}`)
+	require.Equal(t, 400, response.StatusCode)
+	require.Equal(t, "create category failed", responseBody["message"])
+
+	// Success create
+	response, responseBody = send(http.MethodPost, "http://localhost:3000/api/v1/category", tokenAdmin, fmt.Sprintf(`{"name": "%s"}`, name))
+	require.Equal(t, 201, response.StatusCode)
+	require.Equal(t, "Category has been created", responseBody["message"])
+	category := responseBody["data"].(map[string]interface{})
+	require.Equal(t, name, category["name"])
+	categoryURL := fmt.Sprintf("http://localhost:3000/api/v1/category/%s", category["id"])
+
+	// Failed duplicate name
+	response, responseBody = send(http.MethodPost, "http://localhost:3000/api/v1/category", tokenAdmin, fmt.Sprintf(`{"name": "%s"}`, name))
+	require.Equal(t, 400, response.StatusCode)
+	require.Equal(t, fmt.Sprintf("category with name %s already exists", name), responseBody["data"].(map[string]interface{})["errors"])
+
+	// Rename
+	newName := util.RandomString(10)
+	response, responseBody = send(http.MethodPatch, categoryURL, tokenAdmin, fmt.Sprintf(`{"name": "%s"}`, newName))
+	require.Equal(t, 200, response.StatusCode)
+	require.Equal(t, newName, responseBody["data"].(map[string]interface{})["name"])
+
+	// Get by id
+	response, responseBody = send(http.MethodGet, categoryURL, tokenAdmin, "")
+	require.Equal(t, 200, response.StatusCode)
+	require.Equal(t, "detail of category", responseBody["message"])
+	require.Equal(t, newName, responseBody["data"].(map[string]interface{})["name"])
+
+	// Failed invalid id
+	response, _ = send(http.MethodGet, "http://localhost:3000/api/v1/category/abc", tokenAdmin, "")
+	require.Equal(t, 400, response.StatusCode)
+
+	// Failed invalid reassign
+	response, _ = send(http.MethodDelete, categoryURL+"?reassign_to=abc", tokenAdmin, "")
+	require.Equal(t, 400, response.StatusCode)
+
+	// Delete
+	response, responseBody = send(http.MethodDelete, categoryURL, tokenAdmin, "")
+	require.Equal(t, 200, response.StatusCode)
+	require.Equal(t, "category deleted", responseBody["message"])
+
+	response, _ = send(http.MethodGet, categoryURL, tokenAdmin, "")
+	require.Equal(t, 400, response.StatusCode)
+}
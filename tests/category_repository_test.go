@@ -2,9 +2,13 @@ package tests
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/letenk/pokedex/models/domain"
 	"github.com/letenk/pokedex/repository"
+	"github.com/letenk/pokedex/util"
 	"github.com/stretchr/testify/require"
 )
 
@@ -25,3 +29,45 @@ func TestFindAllCategories(t *testing.T) {
 		require.NotEmpty(t, data.UpdatedAt)
 	}
 }
+
+// Not parallel, so created categories are never picked by random monster in other tests
+func TestCategoryRepositoryCRUD(t *testing.T) {
+	repository := repository.NewCategoryRepository(ConnTest)
+	ctx := context.Background()
+
+	// Create
+	name := util.RandomString(10)
+	category, err := repository.Create(ctx, domain.Category{Name: name})
+	require.NoError(t, err)
+	require.NotEmpty(t, category.ID)
+	require.Equal(t, name, category.Name)
+
+	// Name is unique without case sensitive
+	_, err = repository.Create(ctx, domain.Category{Name: strings.ToUpper(name)})
+	require.Error(t, err)
+	require.Equal(t, fmt.Sprintf("category with name %s already exists", strings.ToUpper(name)), err.Error())
+
+	// Rename
+	category.Name = util.RandomString(10)
+	categoryUpdated, err := repository.Update(ctx, category)
+	require.NoError(t, err)
+
+	// Find by id
+	categoryFound, err := repository.FindByID(ctx, category.ID)
+	require.NoError(t, err)
+	require.Equal(t, categoryUpdated.Name, categoryFound.Name)
+
+	// Count monsters
+	count, err := repository.CountMonsters(ctx, category.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), count)
+
+	// Delete
+	ok, err := repository.Delete(ctx, category, "")
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	_, err = repository.FindByID(ctx, category.ID)
+	require.Error(t, err)
+	require.Equal(t, fmt.Sprintf("category with id %s not found", category.ID), err.Error())
+}
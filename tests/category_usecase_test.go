@@ -3,16 +3,20 @@ package tests
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/letenk/pokedex/models/domain"
+	"github.com/letenk/pokedex/models/web"
 	"github.com/letenk/pokedex/repository"
 	"github.com/letenk/pokedex/usecase"
+	"github.com/letenk/pokedex/util"
 	"github.com/stretchr/testify/require"
 )
 
 func TestFindAllCategoriesUsecase(t *testing.T) {
 	t.Parallel()
 	repository := repository.NewCategoryRepository(ConnTest)
-	usecase := usecase.NewUsecaseCategory(repository)
+	usecase := usecase.NewUsecaseCategory(repository, AuditTest)
 
 	// Find all
 	todos, err := usecase.FindAll(context.Background())
@@ -27,3 +31,69 @@ func TestFindAllCategoriesUsecase(t *testing.T) {
 		require.NotEmpty(t, data.UpdatedAt)
 	}
 }
+
+// Not parallel, so created categories are never picked by random monster in other tests
+func TestDeleteCategoryUsecase(t *testing.T) {
+	repositoryCategory := repository.NewCategoryRepository(ConnTest)
+	repositoryMonster := repository.NewMonsterRespository(ConnTest)
+	usecase := usecase.NewUsecaseCategory(repositoryCategory, AuditTest)
+	ctx := context.Background()
+
+	// Create category
+	_, err := usecase.Create(ctx, web.CategoryRequest{Name: "   "})
+	require.Error(t, err)
+	require.Equal(t, "name can not be empty", err.Error())
+
+	category, err := usecase.Create(ctx, web.CategoryRequest{Name: util.RandomString(10)})
+	require.NoError(t, err)
+	target, err := usecase.Create(ctx, web.CategoryRequest{Name: util.RandomString(10)})
+	require.NoError(t, err)
+
+	// Create monster inside category
+	_, randType := RandomCategoryAndType()
+	monster, err := repositoryMonster.Create(ctx, domain.Monster{
+		Name:        util.RandomString(10),
+		CategoryID:  category.ID,
+		Description: util.RandomString(20),
+		Length:      54.3,
+		Weight:      uint16(util.RandomInt(50, 500)),
+		Hp:          uint16(util.RandomInt(50, 500)),
+		Attack:      uint16(util.RandomInt(50, 500)),
+		Defends:     uint16(util.RandomInt(50, 500)),
+		Speed:       uint16(util.RandomInt(50, 500)),
+		ImageName:   util.RandomString(10),
+		ImageURL:    util.RandomString(10),
+		TypeID:      []string{randType},
+	})
+	require.NoError(t, err)
+
+	// Refused, category is still used
+	ok, err := usecase.Delete(ctx, category.ID, "")
+	require.Error(t, err)
+	require.False(t, ok)
+	require.Equal(t, "category is still used by 1 monsters, please reassign the monsters to other category", err.Error())
+
+	// Refused, reassign to the deleted category
+	ok, err = usecase.Delete(ctx, category.ID, category.ID)
+	require.Error(t, err)
+	require.False(t, ok)
+	require.Equal(t, "can not reassign monsters to the deleted category", err.Error())
+
+	// Success with reassign
+	ok, err = usecase.Delete(ctx, category.ID, target.ID)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	monsterFound, err := repositoryMonster.FindByID(ctx, monster.ID)
+	require.NoError(t, err)
+	require.Equal(t, target.ID, monsterFound.CategoryID)
+
+	// Clean up, monster in trash still uses the category
+	_, err = repositoryMonster.Delete(ctx, monsterFound)
+	require.NoError(t, err)
+	_, err = repositoryMonster.Purge(ctx, monsterFound, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	ok, err = usecase.Delete(ctx, target.ID, "")
+	require.NoError(t, err)
+	require.True(t, ok)
+}
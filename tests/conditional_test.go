@@ -0,0 +1,207 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/letenk/pokedex/models/web"
+	"github.com/letenk/pokedex/util"
+	"github.com/stretchr/testify/require"
+)
+
+// getMonsterConditional send GET with header of condition, token is empty as guest
+func getMonsterConditional(t *testing.T, url string, token string, headers map[string]string) (*http.Response, []byte) {
+	request := httptest.NewRequest(http.MethodGet, url, nil)
+	if token != "" {
+		request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	}
+	for key, value := range headers {
+		request.Header.Set(key, value)
+	}
+
+	recorder := httptest.NewRecorder()
+	RouteTest.ServeHTTP(recorder, request)
+
+	response := recorder.Result()
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	return response, body
+}
+
+func TestConditionalFindByIDMonsterHandler(t *testing.T) {
+	newMonster, _ := RandomCreateMonster(t)
+	url := "http://localhost:3000/api/v1/monster/" + newMonster.ID
+
+	// First request return full body with validators
+	response, body := getMonsterConditional(t, url, "", nil)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.NotEmpty(t, body)
+
+	etag := response.Header.Get("ETag")
+	lastModified := response.Header.Get("Last-Modified")
+	require.Regexp(t, `^"[0-9a-f]+"$`, etag)
+	require.NotEmpty(t, lastModified)
+	require.Equal(t, "public, max-age=60", response.Header.Get("Cache-Control"))
+	require.Contains(t, response.Header.Get("Vary"), "Authorization")
+
+	t.Run("if_none_match_same_etag", func(t *testing.T) {
+		response, body := getMonsterConditional(t, url, "", map[string]string{"If-None-Match": etag})
+		require.Equal(t, http.StatusNotModified, response.StatusCode)
+		require.Empty(t, body)
+		require.Equal(t, etag, response.Header.Get("ETag"))
+		require.Equal(t, lastModified, response.Header.Get("Last-Modified"))
+		require.Equal(t, "public, max-age=60", response.Header.Get("Cache-Control"))
+	})
+
+	t.Run("if_none_match_in_list_and_weak", func(t *testing.T) {
+		response, _ := getMonsterConditional(t, url, "", map[string]string{"If-None-Match": `"other", W/` + etag})
+		require.Equal(t, http.StatusNotModified, response.StatusCode)
+	})
+
+	t.Run("if_none_match_other_etag", func(t *testing.T) {
+		response, body := getMonsterConditional(t, url, "", map[string]string{"If-None-Match": `"other"`})
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.NotEmpty(t, body)
+	})
+
+	t.Run("if_modified_since", func(t *testing.T) {
+		response, body := getMonsterConditional(t, url, "", map[string]string{"If-Modified-Since": lastModified})
+		require.Equal(t, http.StatusNotModified, response.StatusCode)
+		require.Empty(t, body)
+
+		// Modified after the date
+		before := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+		response, body = getMonsterConditional(t, url, "", map[string]string{"If-Modified-Since": before})
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.NotEmpty(t, body)
+	})
+
+	// If-Modified-Since is ignored when If-None-Match is present
+	t.Run("if_none_match_precedence", func(t *testing.T) {
+		response, body := getMonsterConditional(t, url, "", map[string]string{
+			"If-None-Match":     `"other"`,
+			"If-Modified-Since": lastModified,
+		})
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.NotEmpty(t, body)
+	})
+
+	t.Run("user_login_is_private", func(t *testing.T) {
+		user := RandomCreateUser(t, "user")
+		token := GetToken(web.UserLoginRequest{Username: user.Username, Password: "password"})
+
+		response, _ := getMonsterConditional(t, url, token, nil)
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.Equal(t, "private, no-cache", response.Header.Get("Cache-Control"))
+		// Catched changes without last modified
+		require.Empty(t, response.Header.Get("Last-Modified"))
+		userETag := response.Header.Get("ETag")
+
+		response, _ = getMonsterConditional(t, url, token, map[string]string{"If-None-Match": userETag})
+		require.Equal(t, http.StatusNotModified, response.StatusCode)
+	})
+}
+
+func TestConditionalMonsterChangedHandler(t *testing.T) {
+	newMonster, _ := RandomCreateMonster(t)
+	url := "http://localhost:3000/api/v1/monster/" + newMonster.ID
+
+	response, _ := getMonsterConditional(t, url, "", nil)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	etag := response.Header.Get("ETag")
+
+	// Update name of monster
+	token := GetToken(web.UserLoginRequest{Username: "admin", Password: "password"})
+	bodyRequest := new(bytes.Buffer)
+	writer := multipart.NewWriter(bodyRequest)
+	writer.WriteField("name", util.RandomString(10))
+	writer.Close()
+
+	request := httptest.NewRequest(http.MethodPatch, url, bodyRequest)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	request.Header.Set("If-Match", etag)
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	recorder := httptest.NewRecorder()
+	RouteTest.ServeHTTP(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Result().StatusCode)
+
+	// Old ETag is not valid anymore
+	response, body := getMonsterConditional(t, url, "", map[string]string{"If-None-Match": etag})
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.NotEmpty(t, body)
+	require.NotEqual(t, etag, response.Header.Get("ETag"))
+}
+
+func TestConditionalEvolutionDeletedHandler(t *testing.T) {
+	t.Parallel()
+	monsters := RandomEvolutionMonsters(t, 2)
+	first, second := monsters[0], monsters[1]
+	url := "http://localhost:3000/api/v1/monster/" + first.ID
+	token := GetToken(web.UserLoginRequest{Username: "admin", Password: "password"})
+
+	sendEvolution := func(method string, url string, body string) *http.Response {
+		request := httptest.NewRequest(method, url, strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+		recorder := httptest.NewRecorder()
+		RouteTest.ServeHTTP(recorder, request)
+		return recorder.Result()
+	}
+
+	reqBody := fmt.Sprintf(`{"from_monster_id": %q, "to_monster_id": %q, "trigger": "level", "level": 16}`, first.ID, second.ID)
+	response := sendEvolution(http.MethodPost, "http://localhost:3000/api/v1/evolution", reqBody)
+	require.Equal(t, http.StatusCreated, response.StatusCode)
+
+	var created web.ResponseWithData
+	err := json.NewDecoder(response.Body).Decode(&created)
+	require.NoError(t, err)
+	evolutionID := created.Data.(map[string]interface{})["id"].(string)
+
+	response, _ = getMonsterConditional(t, url, "", nil)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	lastModified := response.Header.Get("Last-Modified")
+	require.NotEmpty(t, lastModified)
+
+	// Last-Modified has precision of second
+	time.Sleep(time.Second)
+
+	// Deleted evolution move last modified of every monster in the chain
+	response = sendEvolution(http.MethodDelete, "http://localhost:3000/api/v1/evolution/"+evolutionID, "")
+	require.Equal(t, http.StatusOK, response.StatusCode)
+
+	response, body := getMonsterConditional(t, url, "", map[string]string{"If-Modified-Since": lastModified})
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.NotEmpty(t, body)
+	require.NotEqual(t, lastModified, response.Header.Get("Last-Modified"))
+}
+
+func TestConditionalFindAllMonsterHandler(t *testing.T) {
+	RandomCreateMonster(t)
+	url := "http://localhost:3000/api/v1/monster?limit=5"
+
+	response, body := getMonsterConditional(t, url, "", nil)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.NotEmpty(t, body)
+
+	etag := response.Header.Get("ETag")
+	require.NotEmpty(t, etag)
+	// Deleted monster does not move last modified of list
+	require.Empty(t, response.Header.Get("Last-Modified"))
+	require.Equal(t, "public, max-age=60", response.Header.Get("Cache-Control"))
+
+	response, body = getMonsterConditional(t, url, "", map[string]string{"If-None-Match": etag})
+	require.Equal(t, http.StatusNotModified, response.StatusCode)
+	require.Empty(t, body)
+
+	// Other page is other representation
+	response, _ = getMonsterConditional(t, url+"&page=2", "", map[string]string{"If-None-Match": etag})
+	require.NotEqual(t, http.StatusNotModified, response.StatusCode)
+}
@@ -0,0 +1,199 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/letenk/pokedex/models/domain"
+	"github.com/letenk/pokedex/models/web"
+	"github.com/letenk/pokedex/repository"
+	"github.com/letenk/pokedex/usecase"
+	"github.com/letenk/pokedex/util"
+	"github.com/stretchr/testify/require"
+)
+
+// RandomEvolutionMonsters create n monsters for evolution chain, test is not marked parallel
+func RandomEvolutionMonsters(t *testing.T, n int) []domain.Monster {
+	repositoryMonster := repository.NewMonsterRespository(ConnTest)
+
+	var monsters []domain.Monster
+	for i := 0; i < n; i++ {
+		randCategory, randType := RandomCategoryAndType()
+		monster, err := repositoryMonster.Create(context.Background(), domain.Monster{
+			Name:        util.RandomString(10),
+			CategoryID:  randCategory,
+			Description: util.RandomString(20),
+			Length:      54.3,
+			Weight:      uint16(util.RandomInt(50, 500)),
+			Hp:          uint16(util.RandomInt(50, 500)),
+			Attack:      uint16(util.RandomInt(50, 500)),
+			Defends:     uint16(util.RandomInt(50, 500)),
+			Speed:       uint16(util.RandomInt(50, 500)),
+			ImageName:   util.RandomString(10),
+			ImageURL:    util.RandomString(10),
+			TypeID:      []string{randType},
+		})
+		require.NoError(t, err)
+		monsters = append(monsters, monster)
+	}
+
+	return monsters
+}
+
+func TestEvolutionUsecase(t *testing.T) {
+	t.Parallel()
+	repositoryMonster := repository.NewMonsterRespository(ConnTest)
+	repositoryEvolution := repository.NewEvolutionRepository(ConnTest)
+	usecase := usecase.NewUsecaseEvolution(repositoryEvolution, repositoryMonster, AuditTest)
+	ctx := context.Background()
+
+	// Create monsters for the chain
+	monsters := RandomEvolutionMonsters(t, 4)
+	first, second, third, other := monsters[0], monsters[1], monsters[2], monsters[3]
+
+	level := 16
+	request := func(from, to domain.Monster, trigger web.EvolutionTriggerRequest) web.EvolutionCreateRequest {
+		return web.EvolutionCreateRequest{
+			FromMonsterID:           from.ID,
+			ToMonsterID:             to.ID,
+			EvolutionTriggerRequest: trigger,
+		}
+	}
+
+	// Success, first -> second -> third
+	evolution, err := usecase.Create(ctx, request(first, second, web.EvolutionTriggerRequest{Trigger: "level", Level: &level}))
+	require.NoError(t, err)
+	require.NotEmpty(t, evolution.ID)
+	require.Equal(t, first.Name, evolution.FromMonster.Name)
+	require.Equal(t, second.Name, evolution.ToMonster.Name)
+	require.Equal(t, level, *evolution.Level)
+
+	_, err = usecase.Create(ctx, request(second, third, web.EvolutionTriggerRequest{Trigger: "item", Item: "Fire Stone"}))
+	require.NoError(t, err)
+
+	// Failed trigger without value
+	_, err = usecase.Create(ctx, request(third, other, web.EvolutionTriggerRequest{Trigger: "level"}))
+	require.Error(t, err)
+	require.Equal(t, "level is required for trigger level", err.Error())
+
+	// Failed evolve into itself
+	_, err = usecase.Create(ctx, request(first, first, web.EvolutionTriggerRequest{Trigger: "condition", Condition: "night"}))
+	require.Error(t, err)
+	require.Equal(t, "monster can not evolve into itself", err.Error())
+
+	// Failed cycle
+	_, err = usecase.Create(ctx, request(third, first, web.EvolutionTriggerRequest{Trigger: "condition", Condition: "night"}))
+	require.Error(t, err)
+	require.Equal(t, fmt.Sprintf("evolution from %s to %s creates a cycle", third.Name, first.Name), err.Error())
+
+	// Failed monster already evolves from other monster
+	_, err = usecase.Create(ctx, request(other, second, web.EvolutionTriggerRequest{Trigger: "condition", Condition: "night"}))
+	require.Error(t, err)
+	require.Equal(t, fmt.Sprintf("monster with id %s already evolves from other monster", second.ID), err.Error())
+
+	// Full chain from any monster in the chain
+	for _, monster := range []domain.Monster{first, second, third} {
+		chain, err := repositoryEvolution.FindChain(ctx, monster.ID)
+		require.NoError(t, err)
+		require.Equal(t, 2, len(chain))
+		require.Equal(t, first.ID, chain[0].FromMonsterID)
+		require.Equal(t, second.ID, chain[0].ToMonsterID)
+		require.Equal(t, second.ID, chain[1].FromMonsterID)
+		require.Equal(t, third.ID, chain[1].ToMonsterID)
+		require.Equal(t, "Fire Stone", *chain[1].Item)
+	}
+
+	chain, err := repositoryEvolution.FindChain(ctx, other.ID)
+	require.NoError(t, err)
+	require.Equal(t, 0, len(chain))
+
+	// Update trigger
+	evolutionUpdated, err := usecase.Update(ctx, evolution.ID, web.EvolutionTriggerRequest{Trigger: "condition", Condition: "high friendship"})
+	require.NoError(t, err)
+	require.Equal(t, "condition", evolutionUpdated.Trigger)
+	require.Nil(t, evolutionUpdated.Level)
+	require.Equal(t, "high friendship", *evolutionUpdated.Condition)
+
+	// Create and update are recorded with changes
+	auditLogs, _, err := AuditTest.FindAll(ctx, web.AuditQueryRequest{Entity: domain.AuditEntityEvolution, EntityID: evolution.ID})
+	require.NoError(t, err)
+	require.Equal(t, 2, len(auditLogs))
+	actions := map[string]domain.AuditChanges{}
+	for _, auditLog := range auditLogs {
+		actions[auditLog.Action] = auditLog.Changes
+	}
+	require.Equal(t, first.ID, actions[domain.AuditActionCreate]["FromMonsterID"].After)
+	require.Equal(t, "level", actions[domain.AuditActionUpdate]["Trigger"].Before)
+	require.Equal(t, "condition", actions[domain.AuditActionUpdate]["Trigger"].After)
+
+	// Delete
+	ok, err := usecase.Delete(ctx, evolution.ID)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	auditLogs, _, err = AuditTest.FindAll(ctx, web.AuditQueryRequest{Entity: domain.AuditEntityEvolution, EntityID: evolution.ID, Action: domain.AuditActionDelete})
+	require.NoError(t, err)
+	require.Equal(t, 1, len(auditLogs))
+
+	chain, err = repositoryEvolution.FindChain(ctx, first.ID)
+	require.NoError(t, err)
+	require.Equal(t, 0, len(chain))
+
+	// Clean up, evolutions are deleted with the purged monsters
+	for _, monster := range monsters {
+		_, err = repositoryMonster.Delete(ctx, monster)
+		require.NoError(t, err)
+		_, err = repositoryMonster.Purge(ctx, monster, time.Now().Add(time.Hour))
+		require.NoError(t, err)
+	}
+}
+
+func TestEvolutionConcurrentCycleUsecase(t *testing.T) {
+	t.Parallel()
+	repositoryMonster := repository.NewMonsterRespository(ConnTest)
+	repositoryEvolution := repository.NewEvolutionRepository(ConnTest)
+	usecase := usecase.NewUsecaseEvolution(repositoryEvolution, repositoryMonster, AuditTest)
+	ctx := context.Background()
+
+	monsters := RandomEvolutionMonsters(t, 3)
+	first, second, third := monsters[0], monsters[1], monsters[2]
+
+	trigger := web.EvolutionTriggerRequest{Trigger: "condition", Condition: "night"}
+	_, err := usecase.Create(ctx, web.EvolutionCreateRequest{FromMonsterID: first.ID, ToMonsterID: second.ID, EvolutionTriggerRequest: trigger})
+	require.NoError(t, err)
+
+	// Each of evolutions is valid alone, together they are a cycle
+	requests := []web.EvolutionCreateRequest{
+		{FromMonsterID: second.ID, ToMonsterID: third.ID, EvolutionTriggerRequest: trigger},
+		{FromMonsterID: third.ID, ToMonsterID: first.ID, EvolutionTriggerRequest: trigger},
+	}
+	var wg sync.WaitGroup
+	errs := make([]error, len(requests))
+	for i := range requests {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = usecase.Create(ctx, requests[i])
+		}(i)
+	}
+	wg.Wait()
+
+	created := 0
+	for _, err := range errs {
+		if err == nil {
+			created++
+		}
+	}
+	require.Equal(t, 1, created)
+
+	// Clean up, evolutions are deleted with the purged monsters
+	for _, monster := range monsters {
+		_, err = repositoryMonster.Delete(ctx, monster)
+		require.NoError(t, err)
+		_, err = repositoryMonster.Purge(ctx, monster, time.Now().Add(time.Hour))
+		require.NoError(t, err)
+	}
+}
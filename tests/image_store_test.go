@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/letenk/pokedex/usecase"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalImageStore(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	imageStore := usecase.NewLocalImageStore(dir, "http://localhost:3000/static/images/")
+
+	testCases := []struct {
+		name     string
+		fileName string
+	}{
+		{
+			name:     "upload_and_delete_success",
+			fileName: "image.png",
+		},
+		{
+			name:     "upload_and_delete_success_path_traversal_cleaned",
+			fileName: "../../image_traversal.png",
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			// Read file from local
+			file, _ := os.Open("file_sample/image.png")
+			defer file.Close()
+
+			// Upload
+			location, err := imageStore.Upload(context.Background(), file, tc.fileName)
+			require.NoError(t, err)
+
+			baseName := filepath.Base(tc.fileName)
+			require.Equal(t, "http://localhost:3000/static/images/"+baseName, location)
+
+			// File must be inside dir
+			_, err = os.Stat(filepath.Join(dir, baseName))
+			require.NoError(t, err)
+
+			// Delete
+			err = imageStore.Delete(context.Background(), tc.fileName)
+			require.NoError(t, err)
+
+			_, err = os.Stat(filepath.Join(dir, baseName))
+			require.True(t, os.IsNotExist(err))
+
+			// Delete file not exist is not error
+			err = imageStore.Delete(context.Background(), tc.fileName)
+			require.NoError(t, err)
+		})
+	}
+}
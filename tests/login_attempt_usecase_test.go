@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/letenk/pokedex/models/web"
+	"github.com/letenk/pokedex/repository"
+	"github.com/letenk/pokedex/usecase"
+	"github.com/letenk/pokedex/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginAttemptPerUsernameUsecase(t *testing.T) {
+	t.Parallel()
+	repositoryToken := repository.NewTokenRepository(ConnTest)
+	repositoryPermission := repository.NewPermissionRepository(ConnTest)
+	repositoryLoginAttempt := repository.NewLoginAttemptRepository(ConnTest)
+	repositoryMFA := repository.NewMFARepository(ConnTest)
+	repositoryUser := repository.NewUserRepository(ConnTest)
+	ctx := context.Background()
+
+	// Two failures are free, then wait 1 second, locked after four failures
+	config := ConfigTest
+	config.LOGIN_MAX_ATTEMPTS = 4
+	config.LOGIN_LOCKOUT_DURATION = time.Minute
+	usecaseUser := usecase.NewUsecaseUser(repositoryUser, repositoryToken, repositoryPermission, repositoryLoginAttempt, repositoryMFA, AuditTest, KeySetTest, config)
+
+	user := RandomCreateUser(t, "user")
+	wrong := web.UserLoginRequest{Username: user.Username, Password: "wrong"}
+	correct := web.UserLoginRequest{Username: user.Username, Password: "password"}
+
+	for i := 0; i < 3; i++ {
+		_, err := usecaseUser.Login(ctx, wrong)
+		require.Error(t, err)
+		require.Equal(t, "username or password incorrect", err.Error())
+	}
+
+	// Backoff after third failure, even with correct password
+	_, err := usecaseUser.Login(ctx, correct)
+	var lockedErr *usecase.LoginLockedError
+	require.True(t, errors.As(err, &lockedErr))
+	require.LessOrEqual(t, lockedErr.RetryAfter, time.Second)
+	require.Equal(t, 1, lockedErr.RetryAfterSeconds())
+
+	// Locked after fourth failure
+	time.Sleep(time.Second)
+	_, err = usecaseUser.Login(ctx, wrong)
+	require.Error(t, err)
+
+	_, err = usecaseUser.Login(ctx, correct)
+	require.True(t, errors.As(err, &lockedErr))
+	require.Greater(t, lockedErr.RetryAfter, 50*time.Second)
+
+	// Username is not case sensitive
+	_, err = usecaseUser.Login(ctx, web.UserLoginRequest{Username: strings.ToUpper(user.Username), Password: "password"})
+	require.True(t, errors.As(err, &lockedErr))
+
+	// Admin unlock
+	_, err = usecaseUser.Unlock(ctx, user.ID)
+	require.NoError(t, err)
+
+	authToken, err := usecaseUser.Login(ctx, correct)
+	require.NoError(t, err)
+	require.NotEmpty(t, authToken.AccessToken)
+}
+
+func TestLoginAttemptConcurrentUsecase(t *testing.T) {
+	t.Parallel()
+	repositoryToken := repository.NewTokenRepository(ConnTest)
+	repositoryPermission := repository.NewPermissionRepository(ConnTest)
+	repositoryLoginAttempt := repository.NewLoginAttemptRepository(ConnTest)
+	repositoryMFA := repository.NewMFARepository(ConnTest)
+	repositoryUser := repository.NewUserRepository(ConnTest)
+	ctx := context.Background()
+
+	config := ConfigTest
+	config.LOGIN_MAX_ATTEMPTS = 4
+	config.LOGIN_LOCKOUT_DURATION = time.Minute
+	usecaseUser := usecase.NewUsecaseUser(repositoryUser, repositoryToken, repositoryPermission, repositoryLoginAttempt, repositoryMFA, AuditTest, KeySetTest, config)
+
+	user := RandomCreateUser(t, "user")
+	wrong := web.UserLoginRequest{Username: user.Username, Password: "wrong"}
+
+	// Parallel guesses are counted before password is compared, only the free failures compare password
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = usecaseUser.Login(ctx, wrong)
+		}(i)
+	}
+	wg.Wait()
+
+	compared := 0
+	for _, err := range errs {
+		var lockedErr *usecase.LoginLockedError
+		if !errors.As(err, &lockedErr) {
+			require.Equal(t, "username or password incorrect", err.Error())
+			compared++
+		}
+	}
+	require.Equal(t, 3, compared)
+}
+
+func TestLoginAttemptPerIPUsecase(t *testing.T) {
+	t.Parallel()
+	repositoryToken := repository.NewTokenRepository(ConnTest)
+	repositoryPermission := repository.NewPermissionRepository(ConnTest)
+	repositoryLoginAttempt := repository.NewLoginAttemptRepository(ConnTest)
+	repositoryMFA := repository.NewMFARepository(ConnTest)
+	repositoryUser := repository.NewUserRepository(ConnTest)
+	ctx := context.Background()
+
+	config := ConfigTest
+	config.LOGIN_MAX_ATTEMPTS_PER_IP = 2
+	config.LOGIN_LOCKOUT_DURATION = time.Minute
+	usecaseUser := usecase.NewUsecaseUser(repositoryUser, repositoryToken, repositoryPermission, repositoryLoginAttempt, repositoryMFA, AuditTest, KeySetTest, config)
+
+	// Failed login with different usernames from the same ip
+	ip := "ip-" + util.RandomString(10)
+	for i := 0; i < 2; i++ {
+		_, err := usecaseUser.Login(ctx, web.UserLoginRequest{Username: util.RandomString(10), Password: "wrong", IP: ip})
+		require.Error(t, err)
+	}
+
+	// Locked for the ip
+	user := RandomCreateUser(t, "user")
+	_, err := usecaseUser.Login(ctx, web.UserLoginRequest{Username: user.Username, Password: "password", IP: ip})
+	var lockedErr *usecase.LoginLockedError
+	require.True(t, errors.As(err, &lockedErr))
+
+	// Other ip can login
+	_, err = usecaseUser.Login(ctx, web.UserLoginRequest{Username: user.Username, Password: "password", IP: "ip-" + util.RandomString(10)})
+	require.NoError(t, err)
+}
@@ -2,16 +2,24 @@ package tests
 
 import (
 	"log"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/letenk/pokedex/repository"
 	"github.com/letenk/pokedex/router"
+	"github.com/letenk/pokedex/usecase"
 	"github.com/letenk/pokedex/util"
 	"gorm.io/gorm"
 )
 
 var ConnTest *gorm.DB
 var RouteTest *gin.Engine
+var ImageStoreTest usecase.ImageStore
+var ConfigTest util.Config
+var KeySetTest *usecase.KeySet
+var AuditTest usecase.AuditUsecase
 
 func TestMain(m *testing.M) {
 	// Load Config
@@ -23,8 +31,30 @@ func TestMain(m *testing.M) {
 	db := util.SetupDB(config.DB_SOURCE_TEST)
 	ConnTest = db
 
+	// Store images on local disk, so test not need credentials aws
+	config.IMAGE_STORAGE = "local"
+	config.IMAGE_LOCAL_DIR = filepath.Join(os.TempDir(), "pokedex_images_test")
+	config.IMAGE_BASE_URL = "http://localhost:3000/static/images"
+	ImageStoreTest = usecase.NewLocalImageStore(config.IMAGE_LOCAL_DIR, config.IMAGE_BASE_URL)
+
+	// All requests of test come from the same ip, limit per ip is tested with own config
+	config.LOGIN_MAX_ATTEMPTS_PER_IP = 1000
+	// Admin of test login without TOTP, policy of MFA is tested with own config
+	config.MFA_REQUIRED_ROLES = nil
+
+	ConfigTest = config
+
+	// Keys for sign access token
+	KeySetTest, err = usecase.NewKeySet(config)
+	if err != nil {
+		log.Fatal("cannot load jwt keys:", err)
+	}
+
+	// Audit log for usecase of test
+	AuditTest = usecase.NewUsecaseAudit(repository.NewAuditRepository(db))
+
 	// Setup router
-	RouteTest = router.SetupRouter(db)
+	RouteTest = router.SetupRouter(db, config, usecase.NewMemoryCache(0))
 
 	m.Run()
 }
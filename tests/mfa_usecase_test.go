@@ -0,0 +1,184 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/letenk/pokedex/models/web"
+	"github.com/letenk/pokedex/repository"
+	"github.com/letenk/pokedex/usecase"
+	"github.com/letenk/pokedex/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOptionalMFAUsecase(t *testing.T) {
+	t.Parallel()
+	repositoryToken := repository.NewTokenRepository(ConnTest)
+	repositoryPermission := repository.NewPermissionRepository(ConnTest)
+	repositoryLoginAttempt := repository.NewLoginAttemptRepository(ConnTest)
+	repositoryMFA := repository.NewMFARepository(ConnTest)
+	repositoryUser := repository.NewUserRepository(ConnTest)
+	usecaseUser := usecase.NewUsecaseUser(repositoryUser, repositoryToken, repositoryPermission, repositoryLoginAttempt, repositoryMFA, AuditTest, KeySetTest, ConfigTest)
+	ctx := context.Background()
+
+	user := RandomCreateUser(t, "user")
+	credential := web.UserLoginRequest{Username: user.Username, Password: "password"}
+
+	// Failed enroll with wrong password
+	_, err := usecaseUser.EnrollMFA(ctx, user.ID, web.MFAEnrollRequest{Password: "wrong"})
+	require.Error(t, err)
+	require.Equal(t, "password incorrect", err.Error())
+
+	// Enroll
+	enroll, err := usecaseUser.EnrollMFA(ctx, user.ID, web.MFAEnrollRequest{Password: "password"})
+	require.NoError(t, err)
+	require.NotEmpty(t, enroll.Secret)
+	require.True(t, strings.HasPrefix(enroll.URI, "otpauth://totp/"))
+	require.Contains(t, enroll.URI, enroll.Secret)
+
+	// MFA is not enabled before verified
+	authToken, err := usecaseUser.Login(ctx, credential)
+	require.NoError(t, err)
+	require.NotEmpty(t, authToken.AccessToken)
+
+	// Failed wrong code
+	_, err = usecaseUser.ConfirmMFA(ctx, user.ID, web.MFACodeRequest{Code: "000000"})
+	require.Error(t, err)
+
+	// Verify with code of previous period, for not collide with code of login below
+	step := util.TOTPStep(time.Now())
+	code, err := util.TOTPCode(enroll.Secret, step-1)
+	require.NoError(t, err)
+	recoveryCodes, err := usecaseUser.ConfirmMFA(ctx, user.ID, web.MFACodeRequest{Code: code})
+	require.NoError(t, err)
+	require.Len(t, recoveryCodes, 10)
+
+	// Login return pending token
+	authToken, err = usecaseUser.Login(ctx, credential)
+	require.NoError(t, err)
+	require.Empty(t, authToken.AccessToken)
+	require.NotEmpty(t, authToken.MFAToken)
+	require.False(t, authToken.MFAEnrollmentRequired)
+
+	// Pending token can not be used as access token
+	_, _, err = usecaseUser.Authenticate(ctx, authToken.MFAToken)
+	require.Error(t, err)
+
+	// Failed code is already used
+	_, _, err = usecaseUser.LoginMFA(ctx, web.MFALoginRequest{MFAToken: authToken.MFAToken, Code: code})
+	require.Error(t, err)
+	require.Equal(t, "code has been used", err.Error())
+
+	// Login with current code
+	code, err = util.TOTPCode(enroll.Secret, step)
+	require.NoError(t, err)
+	mfaAuthToken, codes, err := usecaseUser.LoginMFA(ctx, web.MFALoginRequest{MFAToken: authToken.MFAToken, Code: code})
+	require.NoError(t, err)
+	require.NotEmpty(t, mfaAuthToken.AccessToken)
+	require.Empty(t, codes)
+
+	// Failed pending token is exchanged once
+	_, _, err = usecaseUser.LoginMFA(ctx, web.MFALoginRequest{MFAToken: authToken.MFAToken, Code: recoveryCodes[0]})
+	require.Error(t, err)
+	require.Equal(t, "invalid mfa token", err.Error())
+
+	// Login with recovery code, only once
+	authToken, err = usecaseUser.Login(ctx, credential)
+	require.NoError(t, err)
+	_, _, err = usecaseUser.LoginMFA(ctx, web.MFALoginRequest{MFAToken: authToken.MFAToken, Code: strings.ToUpper(recoveryCodes[0])})
+	require.NoError(t, err)
+
+	authToken, err = usecaseUser.Login(ctx, credential)
+	require.NoError(t, err)
+	_, _, err = usecaseUser.LoginMFA(ctx, web.MFALoginRequest{MFAToken: authToken.MFAToken, Code: recoveryCodes[0]})
+	require.Error(t, err)
+	require.Equal(t, "invalid code", err.Error())
+
+	// Disable
+	_, err = usecaseUser.DisableMFA(ctx, user.ID, web.MFADisableRequest{Password: "wrong"})
+	require.Error(t, err)
+	ok, err := usecaseUser.DisableMFA(ctx, user.ID, web.MFADisableRequest{Password: "password"})
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	authToken, err = usecaseUser.Login(ctx, credential)
+	require.NoError(t, err)
+	require.NotEmpty(t, authToken.AccessToken)
+}
+
+func TestConfirmMFAAttemptUsecase(t *testing.T) {
+	t.Parallel()
+	repositoryToken := repository.NewTokenRepository(ConnTest)
+	repositoryPermission := repository.NewPermissionRepository(ConnTest)
+	repositoryLoginAttempt := repository.NewLoginAttemptRepository(ConnTest)
+	repositoryMFA := repository.NewMFARepository(ConnTest)
+	repositoryUser := repository.NewUserRepository(ConnTest)
+	ctx := context.Background()
+
+	config := ConfigTest
+	config.LOGIN_MAX_ATTEMPTS = 2
+	config.LOGIN_LOCKOUT_DURATION = time.Minute
+	usecaseUser := usecase.NewUsecaseUser(repositoryUser, repositoryToken, repositoryPermission, repositoryLoginAttempt, repositoryMFA, AuditTest, KeySetTest, config)
+
+	user := RandomCreateUser(t, "user")
+	enroll, err := usecaseUser.EnrollMFA(ctx, user.ID, web.MFAEnrollRequest{Password: "password"})
+	require.NoError(t, err)
+
+	// Wrong codes are counted like failed login
+	for i := 0; i < 2; i++ {
+		_, err = usecaseUser.ConfirmMFA(ctx, user.ID, web.MFACodeRequest{Code: "000000"})
+		require.Error(t, err)
+	}
+
+	// Locked, even with correct code
+	code, err := util.TOTPCode(enroll.Secret, util.TOTPStep(time.Now()))
+	require.NoError(t, err)
+	_, err = usecaseUser.ConfirmMFA(ctx, user.ID, web.MFACodeRequest{Code: code})
+	var lockedErr *usecase.LoginLockedError
+	require.True(t, errors.As(err, &lockedErr))
+}
+
+func TestRequiredMFAUsecase(t *testing.T) {
+	t.Parallel()
+	repositoryToken := repository.NewTokenRepository(ConnTest)
+	repositoryPermission := repository.NewPermissionRepository(ConnTest)
+	repositoryLoginAttempt := repository.NewLoginAttemptRepository(ConnTest)
+	repositoryMFA := repository.NewMFARepository(ConnTest)
+	repositoryUser := repository.NewUserRepository(ConnTest)
+	ctx := context.Background()
+
+	config := ConfigTest
+	config.MFA_REQUIRED_ROLES = []string{"admin"}
+	usecaseUser := usecase.NewUsecaseUser(repositoryUser, repositoryToken, repositoryPermission, repositoryLoginAttempt, repositoryMFA, AuditTest, KeySetTest, config)
+
+	// Role user is not required
+	user := RandomCreateUser(t, "user")
+	authToken, err := usecaseUser.Login(ctx, web.UserLoginRequest{Username: user.Username, Password: "password"})
+	require.NoError(t, err)
+	require.NotEmpty(t, authToken.AccessToken)
+
+	// Admin must enroll on login
+	admin := RandomCreateUser(t, "admin")
+	authToken, err = usecaseUser.Login(ctx, web.UserLoginRequest{Username: admin.Username, Password: "password"})
+	require.NoError(t, err)
+	require.Empty(t, authToken.AccessToken)
+	require.True(t, authToken.MFAEnrollmentRequired)
+
+	enroll, err := usecaseUser.EnrollMFAWithToken(ctx, web.MFAEnrollLoginRequest{MFAToken: authToken.MFAToken})
+	require.NoError(t, err)
+
+	code, err := util.TOTPCode(enroll.Secret, util.TOTPStep(time.Now()))
+	require.NoError(t, err)
+	mfaAuthToken, recoveryCodes, err := usecaseUser.LoginMFA(ctx, web.MFALoginRequest{MFAToken: authToken.MFAToken, Code: code})
+	require.NoError(t, err)
+	require.NotEmpty(t, mfaAuthToken.AccessToken)
+	require.Len(t, recoveryCodes, 10)
+
+	// Failed disable because of policy
+	_, err = usecaseUser.DisableMFA(ctx, admin.ID, web.MFADisableRequest{Password: "password"})
+	require.Error(t, err)
+	require.Equal(t, "mfa is required for role admin", err.Error())
+}
@@ -0,0 +1,375 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"mime/multipart"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/letenk/pokedex/models/domain"
+	"github.com/letenk/pokedex/models/web"
+	"github.com/letenk/pokedex/usecase"
+	"github.com/stretchr/testify/require"
+)
+
+// countingMonsterUsecase count calls of each method, used as next of cached usecase
+type countingMonsterUsecase struct {
+	mu    sync.Mutex
+	calls map[string]int
+	delay time.Duration
+}
+
+func newCountingMonsterUsecase(delay time.Duration) *countingMonsterUsecase {
+	return &countingMonsterUsecase{calls: map[string]int{}, delay: delay}
+}
+
+func (u *countingMonsterUsecase) count(method string) {
+	u.mu.Lock()
+	u.calls[method]++
+	u.mu.Unlock()
+	time.Sleep(u.delay)
+}
+
+func (u *countingMonsterUsecase) Calls(method string) int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.calls[method]
+}
+
+func (u *countingMonsterUsecase) FindAll(ctx context.Context, reqQuery web.MonsterQueryRequest) ([]domain.Monster, web.Pagination, error) {
+	u.count("FindAll")
+	monsters := []domain.Monster{{ID: "1", Name: "bulbasaur" + reqQuery.Name, Catched: reqQuery.UserID != ""}}
+	return monsters, web.Pagination{Total: 1, Limit: 20, Page: 1, TotalPages: 1}, nil
+}
+
+func (u *countingMonsterUsecase) FindByID(ctx context.Context, ID string, userID string) (domain.Monster, error) {
+	u.count("FindByID")
+	// Like database, canceled context fails the query
+	if err := ctx.Err(); err != nil {
+		return domain.Monster{}, err
+	}
+	if ID == "missing" {
+		return domain.Monster{}, errors.New("monster not found")
+	}
+	return domain.Monster{ID: ID, Name: "bulbasaur", TypeID: []string{"grass"}, Catched: userID != ""}, nil
+}
+
+func (u *countingMonsterUsecase) Create(ctx context.Context, monster web.MonsterCreateRequest, file multipart.File, fileName string) (domain.Monster, error) {
+	u.count("Create")
+	return domain.Monster{ID: "2"}, nil
+}
+
+func (u *countingMonsterUsecase) Update(ctx context.Context, ID string, reqUpdate web.MonsterUpdateRequest, file multipart.File, fileName string) (domain.Monster, error) {
+	u.mu.Lock()
+	u.calls["Update"]++
+	u.mu.Unlock()
+	return domain.Monster{ID: ID}, nil
+}
+
+func (u *countingMonsterUsecase) UpdateMarkMonsterCaptured(ctx context.Context, ID string, userID string, reqUpdate web.MonsterUpdateRequestMonsterCapture) (bool, error) {
+	u.count("UpdateMarkMonsterCaptured")
+	return true, nil
+}
+
+func (u *countingMonsterUsecase) Delete(ctx context.Context, ID string) (bool, error) {
+	u.count("Delete")
+	return true, nil
+}
+
+func (u *countingMonsterUsecase) FindTrash(ctx context.Context, reqQuery web.MonsterTrashQueryRequest) ([]domain.Monster, web.Pagination, error) {
+	u.count("FindTrash")
+	return []domain.Monster{}, web.Pagination{}, nil
+}
+
+func (u *countingMonsterUsecase) Restore(ctx context.Context, ID string) (domain.Monster, error) {
+	u.count("Restore")
+	return domain.Monster{ID: ID}, nil
+}
+
+func (u *countingMonsterUsecase) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int, error) {
+	u.count("PurgeTrash")
+	return 0, nil
+}
+
+func (u *countingMonsterUsecase) MarkCatched(ctx context.Context, userID string, monsters []domain.Monster) error {
+	u.count("MarkCatched")
+	for i := range monsters {
+		monsters[i].Catched = userID != ""
+	}
+	return nil
+}
+
+func TestCachedMonsterUsecase(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("list_cached_by_full_query", func(t *testing.T) {
+		next := newCountingMonsterUsecase(0)
+		cached := usecase.NewCachedMonsterUsecase(next, usecase.NewMemoryCache(0))
+
+		monsters, pagination, err := cached.FindAll(ctx, web.MonsterQueryRequest{})
+		require.NoError(t, err)
+		require.Equal(t, "bulbasaur", monsters[0].Name)
+		require.Equal(t, int64(1), pagination.Total)
+
+		// Same query from cache
+		monsters, pagination, err = cached.FindAll(ctx, web.MonsterQueryRequest{})
+		require.NoError(t, err)
+		require.Equal(t, "bulbasaur", monsters[0].Name)
+		require.Equal(t, 1, pagination.TotalPages)
+		require.Equal(t, 1, next.Calls("FindAll"))
+
+		// Filtered list and list of user login have own cache
+		hpMin := 10.0
+		monsters, _, err = cached.FindAll(ctx, web.MonsterQueryRequest{Name: "_filtered", HpMin: &hpMin})
+		require.NoError(t, err)
+		require.Equal(t, "bulbasaur_filtered", monsters[0].Name)
+		_, _, err = cached.FindAll(ctx, web.MonsterQueryRequest{Name: "_filtered", HpMin: &hpMin})
+		require.NoError(t, err)
+		require.Equal(t, 2, next.Calls("FindAll"))
+
+		// Normalised query has the same key
+		_, _, err = cached.FindAll(ctx, web.MonsterQueryRequest{Name: "_FILTERED", HpMin: &hpMin, Page: 1})
+		require.NoError(t, err)
+		require.Equal(t, 2, next.Calls("FindAll"))
+
+		// User login share the list, only catched is set for the user
+		monsters, _, err = cached.FindAll(ctx, web.MonsterQueryRequest{UserID: "user"})
+		require.NoError(t, err)
+		require.True(t, monsters[0].Catched)
+		require.Equal(t, 2, next.Calls("FindAll"))
+
+		monsters, _, err = cached.FindAll(ctx, web.MonsterQueryRequest{})
+		require.NoError(t, err)
+		require.False(t, monsters[0].Catched)
+
+		// Filter of catched by user login is not cached
+		_, _, err = cached.FindAll(ctx, web.MonsterQueryRequest{UserID: "user", Catched: "true"})
+		require.NoError(t, err)
+		_, _, err = cached.FindAll(ctx, web.MonsterQueryRequest{UserID: "user", Catched: "true"})
+		require.NoError(t, err)
+		require.Equal(t, 4, next.Calls("FindAll"))
+
+		// New monster remove all lists
+		_, err = cached.Create(ctx, web.MonsterCreateRequest{}, nil, "")
+		require.NoError(t, err)
+		_, _, err = cached.FindAll(ctx, web.MonsterQueryRequest{})
+		require.NoError(t, err)
+		require.Equal(t, 5, next.Calls("FindAll"))
+	})
+
+	t.Run("concurrent_reads_load_once", func(t *testing.T) {
+		next := newCountingMonsterUsecase(100 * time.Millisecond)
+		cached := usecase.NewCachedMonsterUsecase(next, usecase.NewMemoryCache(0))
+
+		var wg sync.WaitGroup
+		results := make([]domain.Monster, 20)
+		for i := range results {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				monster, err := cached.FindByID(ctx, "1", "")
+				require.NoError(t, err)
+				results[i] = monster
+			}(i)
+		}
+		wg.Wait()
+
+		require.Equal(t, 1, next.Calls("FindByID"))
+
+		// Each caller has own copy
+		results[0].TypeID[0] = "changed"
+		for _, monster := range results[1:] {
+			require.Equal(t, "bulbasaur", monster.Name)
+			require.Equal(t, []string{"grass"}, monster.TypeID)
+		}
+	})
+
+	t.Run("write_remove_cache_before_return", func(t *testing.T) {
+		next := newCountingMonsterUsecase(0)
+		cached := usecase.NewCachedMonsterUsecase(next, usecase.NewMemoryCache(0))
+
+		_, err := cached.FindByID(ctx, "1", "")
+		require.NoError(t, err)
+		_, _, err = cached.FindAll(ctx, web.MonsterQueryRequest{})
+		require.NoError(t, err)
+
+		_, err = cached.Update(ctx, "1", web.MonsterUpdateRequest{}, nil, "")
+		require.NoError(t, err)
+
+		_, err = cached.FindByID(ctx, "1", "")
+		require.NoError(t, err)
+		_, _, err = cached.FindAll(ctx, web.MonsterQueryRequest{})
+		require.NoError(t, err)
+		require.Equal(t, 2, next.Calls("FindByID"))
+		require.Equal(t, 2, next.Calls("FindAll"))
+
+		_, err = cached.Delete(ctx, "1")
+		require.NoError(t, err)
+		_, err = cached.FindByID(ctx, "1", "")
+		require.NoError(t, err)
+		require.Equal(t, 3, next.Calls("FindByID"))
+
+		// Restored monster is back in the lists
+		_, err = cached.Restore(ctx, "2")
+		require.NoError(t, err)
+		_, _, err = cached.FindAll(ctx, web.MonsterQueryRequest{})
+		require.NoError(t, err)
+		require.Equal(t, 3, next.Calls("FindAll"))
+	})
+
+	t.Run("value_loaded_before_write_is_not_cached", func(t *testing.T) {
+		next := newCountingMonsterUsecase(100 * time.Millisecond)
+		cached := usecase.NewCachedMonsterUsecase(next, usecase.NewMemoryCache(0))
+
+		// Update while the read is loading
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, err := cached.FindByID(ctx, "1", "")
+			require.NoError(t, err)
+		}()
+		time.Sleep(20 * time.Millisecond)
+		_, err := cached.Update(ctx, "1", web.MonsterUpdateRequest{}, nil, "")
+		require.NoError(t, err)
+		<-done
+
+		_, err = cached.FindByID(ctx, "1", "")
+		require.NoError(t, err)
+		require.Equal(t, 2, next.Calls("FindByID"))
+	})
+
+	t.Run("canceled_caller_does_not_fail_others", func(t *testing.T) {
+		next := newCountingMonsterUsecase(100 * time.Millisecond)
+		cached := usecase.NewCachedMonsterUsecase(next, usecase.NewMemoryCache(0))
+
+		// First caller start the load, then cancel the request
+		canceledCtx, cancel := context.WithCancel(ctx)
+		canceled := make(chan error)
+		go func() {
+			_, err := cached.FindByID(canceledCtx, "1", "")
+			canceled <- err
+		}()
+		time.Sleep(20 * time.Millisecond)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			monster, err := cached.FindByID(ctx, "1", "")
+			require.NoError(t, err)
+			require.Equal(t, "bulbasaur", monster.Name)
+		}()
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+
+		require.ErrorIs(t, <-canceled, context.Canceled)
+		<-done
+		require.Equal(t, 1, next.Calls("FindByID"))
+
+		// Loaded value is cached
+		_, err := cached.FindByID(ctx, "1", "")
+		require.NoError(t, err)
+		require.Equal(t, 1, next.Calls("FindByID"))
+	})
+
+	t.Run("value_loaded_before_write_of_other_instance_is_not_cached", func(t *testing.T) {
+		// Two instances with the same shared cache
+		cache := usecase.NewMemoryCache(0)
+		next := newCountingMonsterUsecase(100 * time.Millisecond)
+		cachedA := usecase.NewCachedMonsterUsecase(next, cache)
+		cachedB := usecase.NewCachedMonsterUsecase(newCountingMonsterUsecase(0), cache)
+
+		// Update on other instance while the read is loading
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, err := cachedA.FindByID(ctx, "1", "")
+			require.NoError(t, err)
+		}()
+		time.Sleep(20 * time.Millisecond)
+		_, err := cachedB.Update(ctx, "1", web.MonsterUpdateRequest{}, nil, "")
+		require.NoError(t, err)
+		<-done
+
+		_, err = cachedA.FindByID(ctx, "1", "")
+		require.NoError(t, err)
+		require.Equal(t, 2, next.Calls("FindByID"))
+
+		// Without write, the value is cached
+		_, err = cachedA.FindByID(ctx, "1", "")
+		require.NoError(t, err)
+		require.Equal(t, 2, next.Calls("FindByID"))
+	})
+
+	t.Run("invalidate_monsters_remove_lists_and_details", func(t *testing.T) {
+		next := newCountingMonsterUsecase(0)
+		cached := usecase.NewCachedMonsterUsecase(next, usecase.NewMemoryCache(0))
+
+		_, err := cached.FindByID(ctx, "1", "user")
+		require.NoError(t, err)
+		_, _, err = cached.FindAll(ctx, web.MonsterQueryRequest{})
+		require.NoError(t, err)
+
+		// Write of category, type or evolution
+		var invalidator usecase.MonsterCacheInvalidator = cached
+		invalidator.InvalidateMonsters(ctx)
+
+		_, err = cached.FindByID(ctx, "1", "user")
+		require.NoError(t, err)
+		_, _, err = cached.FindAll(ctx, web.MonsterQueryRequest{})
+		require.NoError(t, err)
+		require.Equal(t, 2, next.Calls("FindByID"))
+		require.Equal(t, 2, next.Calls("FindAll"))
+	})
+
+	t.Run("detail_shared_by_users", func(t *testing.T) {
+		next := newCountingMonsterUsecase(0)
+		cached := usecase.NewCachedMonsterUsecase(next, usecase.NewMemoryCache(0))
+
+		monster, err := cached.FindByID(ctx, "1", "user")
+		require.NoError(t, err)
+		require.True(t, monster.Catched)
+		monster, err = cached.FindByID(ctx, "1", "")
+		require.NoError(t, err)
+		require.False(t, monster.Catched)
+		require.Equal(t, 1, next.Calls("FindByID"))
+
+		// Capture doesn't change cached monster
+		_, err = cached.UpdateMarkMonsterCaptured(ctx, "1", "user", web.MonsterUpdateRequestMonsterCapture{Catched: true})
+		require.NoError(t, err)
+
+		monster, err = cached.FindByID(ctx, "1", "other")
+		require.NoError(t, err)
+		require.True(t, monster.Catched)
+		require.Equal(t, 1, next.Calls("FindByID"))
+	})
+
+	t.Run("memory_cache_has_size_limit", func(t *testing.T) {
+		cache := usecase.NewMemoryCache(2)
+		for _, key := range []string{"a", "b", "c"} {
+			err := cache.Set(ctx, key, []byte(key), time.Hour)
+			require.NoError(t, err)
+		}
+
+		found := 0
+		for _, key := range []string{"a", "b", "c"} {
+			if _, err := cache.Get(ctx, key); err == nil {
+				found++
+			}
+		}
+		require.Equal(t, 2, found)
+	})
+
+	t.Run("error_is_not_cached", func(t *testing.T) {
+		next := newCountingMonsterUsecase(0)
+		cached := usecase.NewCachedMonsterUsecase(next, usecase.NewMemoryCache(0))
+
+		_, err := cached.FindByID(ctx, "missing", "")
+		require.Error(t, err)
+		_, err = cached.FindByID(ctx, "missing", "")
+		require.Error(t, err)
+		require.Equal(t, 2, next.Calls("FindByID"))
+	})
+}
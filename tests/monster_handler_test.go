@@ -396,6 +396,79 @@ func TestFindAllMonsterHandler(t *testing.T) {
 	}
 }
 
+func TestFindAllMonsterHandlerPagination(t *testing.T) {
+	// Create random monsters
+	RandomCreateMonster(t)
+
+	testCases := []struct {
+		name      string
+		urlTarget string
+	}{
+		{
+			name:      "find_all_monsters_with_page_and_limit",
+			urlTarget: "http://localhost:3000/api/v1/monster?page=1&limit=1",
+		},
+		{
+			name:      "find_all_monsters_failed_limit_too_big",
+			urlTarget: "http://localhost:3000/api/v1/monster?limit=101",
+		},
+		{
+			name:      "find_all_monsters_failed_page_not_number",
+			urlTarget: "http://localhost:3000/api/v1/monster?page=abc",
+		},
+		{
+			name:      "find_all_monsters_failed_sort_field_not_allowed",
+			urlTarget: "http://localhost:3000/api/v1/monster?sort=-password",
+		},
+	}
+
+	// Test
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			request := httptest.NewRequest(http.MethodGet, tc.urlTarget, nil)
+
+			// Create new recorder
+			recorder := httptest.NewRecorder()
+
+			// Run http test
+			RouteTest.ServeHTTP(recorder, request)
+
+			// Get response
+			response := recorder.Result()
+
+			// Read all response
+			body, _ := io.ReadAll(response.Body)
+			var responseBody map[string]interface{}
+			json.Unmarshal(body, &responseBody)
+
+			if tc.name == "find_all_monsters_with_page_and_limit" {
+				require.Equal(t, 200, response.StatusCode)
+				require.Equal(t, "List of monsters", responseBody["message"])
+				require.Equal(t, 1, len(responseBody["data"].([]any)))
+
+				pagination := responseBody["pagination"].(map[string]any)
+				require.Equal(t, 1, int(pagination["page"].(float64)))
+				require.Equal(t, 1, int(pagination["limit"].(float64)))
+				require.NotEqual(t, 0, int(pagination["total"].(float64)))
+				require.Equal(t, int(pagination["total"].(float64)), int(pagination["total_pages"].(float64)))
+
+				// Header link
+				require.Contains(t, response.Header.Get("Link"), `rel="first"`)
+				require.Contains(t, response.Header.Get("Link"), `rel="last"`)
+			} else {
+				require.Equal(t, 400, response.StatusCode)
+				require.Equal(t, 400, int(responseBody["code"].(float64)))
+				require.Equal(t, "error", responseBody["status"])
+				require.Equal(t, "bad request", responseBody["message"])
+			}
+		})
+	}
+}
+
 func TestFindByIDMonsterHandler(t *testing.T) {
 	// Create random monsters
 	newMonster, _ := RandomCreateMonster(t)
@@ -466,6 +539,9 @@ func TestFindByIDMonsterHandler(t *testing.T) {
 					listType := ty.(map[string]any)
 					require.NotEmpty(t, listType["name"])
 				}
+
+				// Monster without evolution has empty chain
+				require.Equal(t, 0, len(contextData["evolution_chain"].([]any)))
 			} else {
 				require.Equal(t, 400, response.StatusCode)
 				require.Equal(t, 400, int(responseBody["code"].(float64)))
@@ -513,7 +589,6 @@ func TestUpdateMonsterHandler(t *testing.T) {
 				Attack:      "",
 				Defends:     "",
 				Speed:       "",
-				Catched:     "",
 			},
 		},
 		{
@@ -534,7 +609,6 @@ func TestUpdateMonsterHandler(t *testing.T) {
 				Attack:      strconv.Itoa(int(util.RandomInt(50, 500))),
 				Defends:     strconv.Itoa(int(util.RandomInt(50, 500))),
 				Speed:       strconv.Itoa(int(util.RandomInt(50, 500))),
-				Catched:     "true",
 				TypeID:      randTypes,
 			},
 		},
@@ -555,7 +629,6 @@ func TestUpdateMonsterHandler(t *testing.T) {
 				Attack:      strconv.Itoa(int(util.RandomInt(50, 500))),
 				Defends:     strconv.Itoa(int(util.RandomInt(50, 500))),
 				Speed:       strconv.Itoa(int(util.RandomInt(50, 500))),
-				Catched:     "true",
 				TypeID:      randTypes,
 			},
 		},
@@ -573,7 +646,6 @@ func TestUpdateMonsterHandler(t *testing.T) {
 				Attack:      strconv.Itoa(int(util.RandomInt(50, 500))),
 				Defends:     strconv.Itoa(int(util.RandomInt(50, 500))),
 				Speed:       strconv.Itoa(int(util.RandomInt(50, 500))),
-				Catched:     "true",
 				TypeID:      randTypes,
 			},
 		},
@@ -594,7 +666,6 @@ func TestUpdateMonsterHandler(t *testing.T) {
 				Attack:      strconv.Itoa(int(util.RandomInt(50, 500))),
 				Defends:     strconv.Itoa(int(util.RandomInt(50, 500))),
 				Speed:       strconv.Itoa(int(util.RandomInt(50, 500))),
-				Catched:     "true",
 				TypeID:      randTypes,
 			},
 		},
@@ -615,7 +686,6 @@ func TestUpdateMonsterHandler(t *testing.T) {
 				Attack:      strconv.Itoa(int(util.RandomInt(50, 500))),
 				Defends:     strconv.Itoa(int(util.RandomInt(50, 500))),
 				Speed:       strconv.Itoa(int(util.RandomInt(50, 500))),
-				Catched:     "true",
 				TypeID:      []string{"558160ef-e8f5-4951-b5f4-feeb0815b511", "d5a8d4bb-eb0a-44a4-ae46-eb2af2b2002c"},
 			},
 		},
@@ -649,7 +719,6 @@ func TestUpdateMonsterHandler(t *testing.T) {
 				writer.WriteField("attack", tc.reqCreateMonster.Attack)
 				writer.WriteField("defends", tc.reqCreateMonster.Defends)
 				writer.WriteField("speed", tc.reqCreateMonster.Speed)
-				writer.WriteField("catched", tc.reqCreateMonster.Catched)
 
 				writer.Close()
 			} else if tc.name != "success_with_role_admin_with_image" {
@@ -663,7 +732,6 @@ func TestUpdateMonsterHandler(t *testing.T) {
 				writer.WriteField("attack", tc.reqCreateMonster.Attack)
 				writer.WriteField("defends", tc.reqCreateMonster.Defends)
 				writer.WriteField("speed", tc.reqCreateMonster.Speed)
-				writer.WriteField("catched", tc.reqCreateMonster.Catched)
 				writer.WriteField("type_id", tc.reqCreateMonster.TypeID[0])
 				writer.WriteField("type_id", tc.reqCreateMonster.TypeID[1])
 
@@ -679,7 +747,6 @@ func TestUpdateMonsterHandler(t *testing.T) {
 				writer.WriteField("attack", tc.reqCreateMonster.Attack)
 				writer.WriteField("defends", tc.reqCreateMonster.Defends)
 				writer.WriteField("speed", tc.reqCreateMonster.Speed)
-				writer.WriteField("catched", tc.reqCreateMonster.Catched)
 				writer.WriteField("type_id", tc.reqCreateMonster.TypeID[0])
 				writer.WriteField("type_id", tc.reqCreateMonster.TypeID[1])
 
@@ -705,6 +772,8 @@ func TestUpdateMonsterHandler(t *testing.T) {
 			request := httptest.NewRequest(http.MethodPatch, "http://localhost:3000/api/v1/monster/"+newMonster.ID, bodyRequest)
 			// Added header content type
 			request.Header.Set("Content-Type", writer.FormDataContentType())
+			// Update any current version
+			request.Header.Set("If-Match", "*")
 
 			// if tc.name same failed_unauthorized_as_guest dont set header
 			if tc.name != "failed_unauthorized_as_guest" {
@@ -762,7 +831,6 @@ func TestUpdateMonsterHandler(t *testing.T) {
 				require.NotEqual(t, newMonster.Attack, uint16(contextData["attack"].(float64)))
 				require.NotEqual(t, newMonster.Defends, uint16(contextData["defends"].(float64)))
 				require.NotEqual(t, newMonster.Speed, uint16(contextData["speed"].(float64)))
-				require.NotEqual(t, newMonster.Catched, contextData["catched"].(bool))
 
 				require.NotEqual(t, newMonster.ImageURL, contextData["image_url"])
 
@@ -809,6 +877,8 @@ func TestUpdateMonsterHandler(t *testing.T) {
 
 func TestUpdateMarkMonsterCapturedHandler(t *testing.T) {
 	newMonster, _ := RandomCreateMonster(t)
+	// Role without any permission
+	userWithoutPermission := RandomCreateUser(t, "viewer")
 
 	// Test Cases
 	testCases := []struct {
@@ -840,7 +910,7 @@ func TestUpdateMarkMonsterCapturedHandler(t *testing.T) {
 			},
 		},
 		{
-			name:      "failed_forbidden_with_role_admin",
+			name:      "update_mark_monster_captured_monster_success_with_role_admin",
 			idMonster: newMonster.ID,
 			reqLogin: web.UserLoginRequest{
 				Username: "admin",
@@ -850,6 +920,17 @@ func TestUpdateMarkMonsterCapturedHandler(t *testing.T) {
 				Catched: true,
 			},
 		},
+		{
+			name:      "failed_forbidden_without_permission",
+			idMonster: newMonster.ID,
+			reqLogin: web.UserLoginRequest{
+				Username: userWithoutPermission.Username,
+				Password: "password",
+			},
+			reqUpdate: web.MonsterUpdateRequestMonsterCapture{
+				Catched: true,
+			},
+		},
 		{
 			name:      "failed_unauthorized_as_guest",
 			idMonster: newMonster.ID,
@@ -882,6 +963,8 @@ func TestUpdateMarkMonsterCapturedHandler(t *testing.T) {
 			url := fmt.Sprintf("http://localhost:3000/api/v1/monster/%s/captured", tc.idMonster)
 			// Test access categories
 			request := httptest.NewRequest(http.MethodPatch, url, requestBody)
+			// Captured is changed on any current version
+			request.Header.Set("If-Match", "*")
 
 			// if tc.name same failed_unauthorized_as_guest dont set header
 			if tc.name != "failed_unauthorized_as_guest" {
@@ -904,7 +987,7 @@ func TestUpdateMarkMonsterCapturedHandler(t *testing.T) {
 			var responseBody map[string]interface{}
 			json.Unmarshal(body, &responseBody)
 
-			if tc.name == "failed_forbidden_with_role_admin" {
+			if tc.name == "failed_forbidden_without_permission" {
 				require.Equal(t, 403, response.StatusCode)
 				require.Equal(t, 403, int(responseBody["code"].(float64)))
 				require.Equal(t, "error", responseBody["status"])
@@ -1041,3 +1124,179 @@ func TestDeleteMonsterHandler(t *testing.T) {
 		})
 	}
 }
+
+// patchMonster send update of monster as multipart form, ifMatch is not sent when empty
+func patchMonster(t *testing.T, url string, token string, fields map[string]string, ifMatch string) (*http.Response, map[string]interface{}) {
+	bodyRequest := new(bytes.Buffer)
+	writer := multipart.NewWriter(bodyRequest)
+	for key, value := range fields {
+		writer.WriteField(key, value)
+	}
+	writer.Close()
+
+	request := httptest.NewRequest(http.MethodPatch, url, bodyRequest)
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	if ifMatch != "" {
+		request.Header.Set("If-Match", ifMatch)
+	}
+
+	recorder := httptest.NewRecorder()
+	RouteTest.ServeHTTP(recorder, request)
+
+	response := recorder.Result()
+	body, _ := io.ReadAll(response.Body)
+	var responseBody map[string]interface{}
+	json.Unmarshal(body, &responseBody)
+	return response, responseBody
+}
+
+func TestUpdateMonsterConcurrencyHandler(t *testing.T) {
+	newMonster, _ := RandomCreateMonster(t)
+	url := "http://localhost:3000/api/v1/monster/" + newMonster.ID
+	token := GetToken(web.UserLoginRequest{Username: "admin", Password: "password"})
+
+	// Both admins read the same representation
+	request := httptest.NewRequest(http.MethodGet, url, nil)
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	recorder := httptest.NewRecorder()
+	RouteTest.ServeHTTP(recorder, request)
+	require.Equal(t, 200, recorder.Result().StatusCode)
+	etag := recorder.Result().Header.Get("ETag")
+	require.NotEmpty(t, etag)
+
+	// Without If-Match and version
+	response, responseBody := patchMonster(t, url, token, map[string]string{"name": util.RandomString(10)}, "")
+	require.Equal(t, http.StatusPreconditionRequired, response.StatusCode)
+	require.Equal(t, "update monster failed", responseBody["message"])
+
+	// First admin update
+	firstName := util.RandomString(10)
+	response, responseBody = patchMonster(t, url, token, map[string]string{"name": firstName}, etag)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	version := responseBody["data"].(map[string]interface{})["version"].(float64)
+	require.Equal(t, float64(newMonster.Version+1), version)
+
+	// Second admin update with stale ETag, current representation is returned
+	response, responseBody = patchMonster(t, url, token, map[string]string{"name": util.RandomString(10)}, etag)
+	require.Equal(t, http.StatusPreconditionFailed, response.StatusCode)
+	require.Equal(t, "error", responseBody["status"])
+	current := responseBody["data"].(map[string]interface{})
+	require.Equal(t, firstName, current["name"])
+	require.Equal(t, version, current["version"])
+	currentETag := response.Header.Get("ETag")
+	require.NotEqual(t, etag, currentETag)
+
+	// Stale version field
+	response, responseBody = patchMonster(t, url, token, map[string]string{
+		"name":    util.RandomString(10),
+		"version": strconv.Itoa(int(newMonster.Version)),
+	}, "")
+	require.Equal(t, http.StatusConflict, response.StatusCode)
+	require.Equal(t, firstName, responseBody["data"].(map[string]interface{})["name"])
+
+	// Retry with current ETag
+	secondName := util.RandomString(10)
+	response, responseBody = patchMonster(t, url, token, map[string]string{"name": secondName}, currentETag)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.Equal(t, secondName, responseBody["data"].(map[string]interface{})["name"])
+
+	// Retry with current version field
+	version = responseBody["data"].(map[string]interface{})["version"].(float64)
+	response, _ = patchMonster(t, url, token, map[string]string{
+		"name":    util.RandomString(10),
+		"version": strconv.Itoa(int(version)),
+	}, "")
+	require.Equal(t, http.StatusOK, response.StatusCode)
+
+	// Captured mark with stale version
+	response, responseBody = patchMonster(t, url+"/captured", token, map[string]string{
+		"catched": "true",
+		"version": strconv.Itoa(int(newMonster.Version)),
+	}, "")
+	require.Equal(t, http.StatusConflict, response.StatusCode)
+	require.False(t, responseBody["data"].(map[string]interface{})["catched"].(bool))
+
+	// Captured mark without If-Match and version
+	response, _ = patchMonster(t, url+"/captured", token, map[string]string{"catched": "true"}, "")
+	require.Equal(t, http.StatusPreconditionRequired, response.StatusCode)
+}
+
+// monsterRequest send request of monster endpoint with token, token is empty as guest
+func monsterRequest(t *testing.T, method string, url string, token string) (*http.Response, map[string]interface{}) {
+	request := httptest.NewRequest(method, url, nil)
+	if token != "" {
+		request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+	}
+
+	recorder := httptest.NewRecorder()
+	RouteTest.ServeHTTP(recorder, request)
+
+	response := recorder.Result()
+	body, _ := io.ReadAll(response.Body)
+	var responseBody map[string]interface{}
+	json.Unmarshal(body, &responseBody)
+	return response, responseBody
+}
+
+// inTrash find monster on every page of trash
+func inTrash(t *testing.T, token string, ID string) bool {
+	for page := 1; ; page++ {
+		url := fmt.Sprintf("http://localhost:3000/api/v1/monster/trash?limit=100&page=%d", page)
+		response, responseBody := monsterRequest(t, http.MethodGet, url, token)
+		require.Equal(t, 200, response.StatusCode)
+
+		for _, item := range responseBody["data"].([]interface{}) {
+			monster := item.(map[string]interface{})
+			if monster["id"] == ID {
+				require.NotEmpty(t, monster["deleted_at"])
+				return true
+			}
+		}
+
+		pagination := responseBody["pagination"].(map[string]interface{})
+		totalPages, _ := pagination["total_pages"].(float64)
+		if page >= int(totalPages) {
+			return false
+		}
+	}
+}
+
+func TestTrashMonsterHandler(t *testing.T) {
+	newMonster, _ := RandomCreateMonster(t)
+	url := "http://localhost:3000/api/v1/monster/" + newMonster.ID
+	adminToken := GetToken(web.UserLoginRequest{Username: "admin", Password: "password"})
+	userToken := GetToken(web.UserLoginRequest{Username: "user", Password: "password"})
+
+	// Delete move monster into trash
+	response, _ := monsterRequest(t, http.MethodDelete, url, adminToken)
+	require.Equal(t, 200, response.StatusCode)
+	response, _ = monsterRequest(t, http.MethodGet, url, "")
+	require.Equal(t, 400, response.StatusCode)
+	require.True(t, inTrash(t, adminToken, newMonster.ID))
+
+	// Trash and restore need permission monster:trash
+	response, responseBody := monsterRequest(t, http.MethodGet, "http://localhost:3000/api/v1/monster/trash", userToken)
+	require.Equal(t, 403, response.StatusCode)
+	require.Equal(t, "forbidden", responseBody["message"])
+	response, _ = monsterRequest(t, http.MethodPost, url+"/restore", userToken)
+	require.Equal(t, 403, response.StatusCode)
+	response, _ = monsterRequest(t, http.MethodGet, "http://localhost:3000/api/v1/monster/trash", "")
+	require.Equal(t, 401, response.StatusCode)
+
+	// Restore
+	response, responseBody = monsterRequest(t, http.MethodPost, url+"/restore", adminToken)
+	require.Equal(t, 200, response.StatusCode)
+	require.Equal(t, "monster restored", responseBody["message"])
+	require.Equal(t, newMonster.ID, responseBody["data"].(map[string]interface{})["id"])
+	require.False(t, inTrash(t, adminToken, newMonster.ID))
+
+	response, responseBody = monsterRequest(t, http.MethodGet, url, "")
+	require.Equal(t, 200, response.StatusCode)
+	require.Equal(t, newMonster.Name, responseBody["data"].(map[string]interface{})["name"])
+
+	// Monster not in trash
+	response, responseBody = monsterRequest(t, http.MethodPost, url+"/restore", adminToken)
+	require.Equal(t, 400, response.StatusCode)
+	require.Equal(t, "restore monster failed", responseBody["message"])
+}
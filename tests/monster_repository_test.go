@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/letenk/pokedex/models/domain"
 	"github.com/letenk/pokedex/models/web"
@@ -217,7 +218,7 @@ func TestFindAllMonsterRepository(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 			// Find All
-			monsters, err := repositoryMonster.FindAll(ctx, tc.queryParameter)
+			monsters, _, err := repositoryMonster.FindAll(ctx, tc.queryParameter)
 			require.NoError(t, err)
 
 			for _, monster := range monsters {
@@ -236,6 +237,376 @@ func TestFindAllMonsterRepository(t *testing.T) {
 	}
 }
 
+func TestFindAllMonsterRepositoryPagination(t *testing.T) {
+	// Create random monsters, at least two monsters for two pages
+	newMonster, randTypes := RandomCreateMonster(t)
+
+	repositoryMonster := repository.NewMonsterRespository(ConnTest)
+	ctx := context.Background()
+
+	otherMonster := newMonster
+	otherMonster.ID = ""
+	otherMonster.Name = util.RandomString(10)
+	otherMonster.TypeID = randTypes
+	_, err := repositoryMonster.Create(ctx, otherMonster)
+	require.NoError(t, err)
+
+	t.Run("offset_pagination", func(t *testing.T) {
+		monsters, pagination, err := repositoryMonster.FindAll(ctx, web.MonsterQueryRequest{Page: 1, Limit: 1})
+		require.NoError(t, err)
+		require.Equal(t, 1, len(monsters))
+		require.Equal(t, 1, pagination.Page)
+		require.Equal(t, 1, pagination.Limit)
+		require.NotEqual(t, int64(0), pagination.Total)
+		require.Equal(t, int(pagination.Total), pagination.TotalPages)
+
+		// Second page is other monster
+		nextMonsters, pagination, err := repositoryMonster.FindAll(ctx, web.MonsterQueryRequest{Page: 2, Limit: 1})
+		require.NoError(t, err)
+		require.Equal(t, 1, len(nextMonsters))
+		require.Equal(t, 2, pagination.Page)
+		require.NotEqual(t, monsters[0].ID, nextMonsters[0].ID)
+	})
+
+	t.Run("cursor_pagination", func(t *testing.T) {
+		seen := map[string]bool{}
+		query := web.MonsterQueryRequest{Limit: 2}
+
+		for {
+			monsters, pagination, err := repositoryMonster.FindAll(ctx, query)
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(monsters), 2)
+
+			for _, monster := range monsters {
+				require.False(t, seen[monster.ID])
+				seen[monster.ID] = true
+			}
+
+			if pagination.NextCursor == "" {
+				break
+			}
+			query = web.MonsterQueryRequest{Limit: 2, Cursor: pagination.NextCursor}
+		}
+
+		require.NotEqual(t, 0, len(seen))
+	})
+
+	testCases := []struct {
+		name           string
+		queryParameter web.MonsterQueryRequest
+		errMessage     string
+	}{
+		{
+			name:           "failed_limit_too_big",
+			queryParameter: web.MonsterQueryRequest{Limit: 101},
+			errMessage:     "limit must be between 1 and 100",
+		},
+		{
+			name:           "failed_page_negative",
+			queryParameter: web.MonsterQueryRequest{Page: -1},
+			errMessage:     "page must be greater than 0",
+		},
+		{
+			name:           "failed_page_with_cursor",
+			queryParameter: web.MonsterQueryRequest{Page: 1, Cursor: "abc"},
+			errMessage:     "page and cursor can not be used together",
+		},
+		{
+			name:           "failed_invalid_cursor",
+			queryParameter: web.MonsterQueryRequest{Cursor: "invalid"},
+			errMessage:     "invalid cursor",
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			_, _, err := repositoryMonster.FindAll(ctx, tc.queryParameter)
+			require.Error(t, err)
+			require.Equal(t, tc.errMessage, err.Error())
+		})
+	}
+}
+
+func TestFindAllMonsterRepositorySort(t *testing.T) {
+	// Create random monsters
+	RandomCreateMonster(t)
+
+	repositoryMonster := repository.NewMonsterRespository(ConnTest)
+	ctx := context.Background()
+
+	t.Run("sort_by_attack_desc_and_name", func(t *testing.T) {
+		monsters, _, err := repositoryMonster.FindAll(ctx, web.MonsterQueryRequest{Sort: "-attack,name", Limit: 100})
+		require.NoError(t, err)
+
+		for i := 1; i < len(monsters); i++ {
+			require.GreaterOrEqual(t, monsters[i-1].Attack, monsters[i].Attack)
+		}
+	})
+
+	t.Run("sort_by_total_stats", func(t *testing.T) {
+		monsters, _, err := repositoryMonster.FindAll(ctx, web.MonsterQueryRequest{Sort: "-total", Limit: 100})
+		require.NoError(t, err)
+
+		total := func(m domain.Monster) int {
+			return int(m.Hp) + int(m.Attack) + int(m.Defends) + int(m.Speed)
+		}
+		for i := 1; i < len(monsters); i++ {
+			require.GreaterOrEqual(t, total(monsters[i-1]), total(monsters[i]))
+		}
+	})
+
+	t.Run("cursor_pagination_with_sort", func(t *testing.T) {
+		seen := map[string]bool{}
+		query := web.MonsterQueryRequest{Sort: "-length,speed", Limit: 2}
+
+		for {
+			monsters, pagination, err := repositoryMonster.FindAll(ctx, query)
+			require.NoError(t, err)
+
+			for _, monster := range monsters {
+				require.False(t, seen[monster.ID])
+				seen[monster.ID] = true
+			}
+
+			if pagination.NextCursor == "" {
+				break
+			}
+			query.Cursor = pagination.NextCursor
+		}
+
+		require.NotEqual(t, 0, len(seen))
+	})
+
+	testCases := []struct {
+		name           string
+		queryParameter web.MonsterQueryRequest
+		errMessage     string
+	}{
+		{
+			name:           "failed_sort_field_not_allowed",
+			queryParameter: web.MonsterQueryRequest{Sort: "image_name"},
+			errMessage:     `invalid sort field "image_name", allowed fields are id, name, hp, attack, defends, speed, weight, length, created_at, total`,
+		},
+		{
+			name:           "failed_sort_sql_injection",
+			queryParameter: web.MonsterQueryRequest{Sort: "name; DROP TABLE monsters"},
+			errMessage:     `invalid sort field "name; DROP TABLE monsters", allowed fields are id, name, hp, attack, defends, speed, weight, length, created_at, total`,
+		},
+		{
+			name:           "failed_sort_duplicate_field",
+			queryParameter: web.MonsterQueryRequest{Sort: "name,-name"},
+			errMessage:     `duplicate sort field "name"`,
+		},
+		{
+			name:           "failed_order_invalid",
+			queryParameter: web.MonsterQueryRequest{Sort: "name", Order: "random"},
+			errMessage:     "order must be asc or desc",
+		},
+		{
+			name:           "failed_order_without_sort",
+			queryParameter: web.MonsterQueryRequest{Order: "desc"},
+			errMessage:     "for use order, query parameter sort is required",
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			_, _, err := repositoryMonster.FindAll(ctx, tc.queryParameter)
+			require.Error(t, err)
+			require.Equal(t, tc.errMessage, err.Error())
+		})
+	}
+}
+
+func TestFindAllMonsterRepositoryFilterRange(t *testing.T) {
+	// Create random monsters
+	newMonster, _ := RandomCreateMonster(t)
+
+	repositoryMonster := repository.NewMonsterRespository(ConnTest)
+	ctx := context.Background()
+
+	float := func(value float64) *float64 {
+		return &value
+	}
+
+	t.Run("filter_range_match_monster", func(t *testing.T) {
+		monsters, _, err := repositoryMonster.FindAll(ctx, web.MonsterQueryRequest{
+			Name:       newMonster.Name,
+			CategoryID: newMonster.CategoryID,
+			HpMin:      float(float64(newMonster.Hp)),
+			HpMax:      float(float64(newMonster.Hp)),
+			AttackMin:  float(float64(newMonster.Attack)),
+			DefendsMax: float(float64(newMonster.Defends)),
+			SpeedMin:   float(float64(newMonster.Speed)),
+			WeightMax:  float(float64(newMonster.Weight)),
+			LengthMin:  float(54),
+			LengthMax:  float(55),
+		})
+		require.NoError(t, err)
+		require.Equal(t, 1, len(monsters))
+		require.Equal(t, newMonster.ID, monsters[0].ID)
+	})
+
+	t.Run("filter_range_not_match_monster", func(t *testing.T) {
+		monsters, _, err := repositoryMonster.FindAll(ctx, web.MonsterQueryRequest{
+			Name:  newMonster.Name,
+			HpMin: float(float64(newMonster.Hp) + 1),
+		})
+		require.NoError(t, err)
+		require.Equal(t, 0, len(monsters))
+	})
+
+	t.Run("filter_speed_min_and_weight_max", func(t *testing.T) {
+		monsters, _, err := repositoryMonster.FindAll(ctx, web.MonsterQueryRequest{
+			SpeedMin:  float(100),
+			WeightMax: float(300),
+			Limit:     100,
+		})
+		require.NoError(t, err)
+
+		for _, monster := range monsters {
+			require.GreaterOrEqual(t, monster.Speed, uint16(100))
+			require.LessOrEqual(t, monster.Weight, uint16(300))
+		}
+	})
+
+	t.Run("filter_category", func(t *testing.T) {
+		monsters, _, err := repositoryMonster.FindAll(ctx, web.MonsterQueryRequest{
+			CategoryID: newMonster.CategoryID,
+			Limit:      100,
+		})
+		require.NoError(t, err)
+		require.NotEqual(t, 0, len(monsters))
+
+		for _, monster := range monsters {
+			require.Equal(t, newMonster.CategoryID, monster.CategoryID)
+		}
+	})
+
+	testCases := []struct {
+		name           string
+		queryParameter web.MonsterQueryRequest
+		errMessage     string
+	}{
+		{
+			name:           "failed_min_greater_than_max",
+			queryParameter: web.MonsterQueryRequest{AttackMin: float(100), AttackMax: float(50)},
+			errMessage:     "attack_min must be less than or equal to attack_max",
+		},
+		{
+			name:           "failed_min_negative",
+			queryParameter: web.MonsterQueryRequest{HpMin: float(-1)},
+			errMessage:     "hp_min must be greater than or equal to 0",
+		},
+		{
+			name:           "failed_max_negative",
+			queryParameter: web.MonsterQueryRequest{LengthMax: float(-0.5)},
+			errMessage:     "length_max must be greater than or equal to 0",
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			_, _, err := repositoryMonster.FindAll(ctx, tc.queryParameter)
+			require.Error(t, err)
+			require.Equal(t, tc.errMessage, err.Error())
+		})
+	}
+}
+
+func TestFindAllMonsterRepositoryFilterTypes(t *testing.T) {
+	// Create random monsters
+	newMonster, randTypes := RandomCreateMonster(t)
+
+	repositoryMonster := repository.NewMonsterRespository(ConnTest)
+	ctx := context.Background()
+
+	// Find type which is not owned by monster
+	repositoryType := repository.NewTypeRespository(ConnTest)
+	types, _ := repositoryType.FindAll(ctx)
+	var otherType string
+	for _, data := range types {
+		owned := false
+		for _, typeID := range randTypes {
+			if data.ID == typeID {
+				owned = true
+			}
+		}
+		if !owned {
+			otherType = data.ID
+			break
+		}
+	}
+	require.NotEmpty(t, otherType)
+
+	testCases := []struct {
+		name           string
+		queryParameter web.MonsterQueryRequest
+		found          bool
+	}{
+		{
+			name:           "types_mode_any_with_other_type",
+			queryParameter: web.MonsterQueryRequest{Types: []string{randTypes[0], otherType}, TypesMode: "any"},
+			found:          true,
+		},
+		{
+			name:           "types_mode_all_with_owned_types",
+			queryParameter: web.MonsterQueryRequest{Types: randTypes, TypesMode: "all"},
+			found:          true,
+		},
+		{
+			name:           "types_mode_all_with_other_type",
+			queryParameter: web.MonsterQueryRequest{Types: append([]string{otherType}, randTypes...), TypesMode: "all"},
+			found:          false,
+		},
+		{
+			name:           "exclude_types_with_owned_type",
+			queryParameter: web.MonsterQueryRequest{ExcludeTypes: []string{randTypes[0]}},
+			found:          false,
+		},
+		{
+			name:           "exclude_types_with_other_type",
+			queryParameter: web.MonsterQueryRequest{ExcludeTypes: []string{otherType}},
+			found:          true,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			// Only find the new monster
+			tc.queryParameter.Name = newMonster.Name
+
+			monsters, _, err := repositoryMonster.FindAll(ctx, tc.queryParameter)
+			require.NoError(t, err)
+
+			if tc.found {
+				require.Equal(t, 1, len(monsters))
+				require.Equal(t, newMonster.ID, monsters[0].ID)
+			} else {
+				require.Equal(t, 0, len(monsters))
+			}
+		})
+	}
+
+	t.Run("failed_types_mode_invalid", func(t *testing.T) {
+		_, _, err := repositoryMonster.FindAll(ctx, web.MonsterQueryRequest{Types: randTypes, TypesMode: "none"})
+		require.Error(t, err)
+		require.Equal(t, "types_mode must be any or all", err.Error())
+	})
+}
+
 func TestFindByIDMonsterRepository(t *testing.T) {
 	// Create random monsters
 	newMonster, _ := RandomCreateMonster(t)
@@ -330,7 +701,6 @@ func TestUpdateMonsterRepository(t *testing.T) {
 				Attack:      1,
 				Defends:     1,
 				Speed:       1,
-				Catched:     true,
 				ImageName:   "UPDATED",
 				ImageURL:    "UPDATED",
 				TypeID:      randTypes,
@@ -349,7 +719,6 @@ func TestUpdateMonsterRepository(t *testing.T) {
 				Attack:      1,
 				Defends:     1,
 				Speed:       1,
-				Catched:     true,
 				ImageName:   "UPDATED",
 				ImageURL:    "UPDATED",
 				// TypeID:      randTypes,
@@ -368,7 +737,6 @@ func TestUpdateMonsterRepository(t *testing.T) {
 				Attack:      1,
 				Defends:     1,
 				Speed:       1,
-				Catched:     true,
 				ImageName:   "UPDATED",
 				ImageURL:    "UPDATED",
 				TypeID:      randTypes,
@@ -387,12 +755,28 @@ func TestUpdateMonsterRepository(t *testing.T) {
 				Attack:      1,
 				Defends:     1,
 				Speed:       1,
-				Catched:     true,
 				ImageName:   "UPDATED",
 				ImageURL:    "UPDATED",
 				TypeID:      []string{"558160ef-e8f5-4951-b5f4-feeb0815b510", "d5a8d4bb-eb0a-44a4-ae46-eb2af2b2002d"},
 			},
 		},
+		{
+			name: "update_failed_version_conflict",
+			dataUpdate: domain.Monster{
+				ID:          newMonster.ID,
+				Name:        "UPDATED",
+				CategoryID:  randCategories[0],
+				Description: "UPDATED",
+				Length:      1.1,
+				Weight:      1,
+				Hp:          1,
+				Attack:      1,
+				Defends:     1,
+				Speed:       1,
+				ImageName:   "UPDATED",
+				ImageURL:    "UPDATED",
+			},
+		},
 	}
 
 	// Test
@@ -400,14 +784,27 @@ func TestUpdateMonsterRepository(t *testing.T) {
 		tc := testCases[i]
 
 		t.Run(tc.name, func(t *testing.T) {
-			t.Parallel()
+			// Each case update own monster, update is rejected when version is changed by other case
+			newMonster, _ := RandomCreateMonster(t)
+			tc.dataUpdate.ID = newMonster.ID
+			tc.dataUpdate.Version = newMonster.Version
+			if tc.name == "update_failed_version_conflict" {
+				tc.dataUpdate.Version = newMonster.Version + 1
+			}
+
 			// Update
 			_, err := repositoryMonster.Update(ctx, tc.dataUpdate)
 
 			// Find monster by id
 			updatedMonster, _ := repositoryMonster.FindByID(ctx, tc.dataUpdate.ID)
 
-			if tc.name == "update_success_without_types" {
+			if tc.name == "update_failed_version_conflict" {
+				require.ErrorIs(t, err, domain.ErrMonsterVersionConflict)
+
+				// Monster is not changed
+				require.Equal(t, newMonster.Name, updatedMonster.Name)
+				require.Equal(t, newMonster.Version, updatedMonster.Version)
+			} else if tc.name == "update_success_without_types" {
 				require.NoError(t, err)
 
 				require.Equal(t, newMonster.ID, updatedMonster.ID)
@@ -421,7 +818,6 @@ func TestUpdateMonsterRepository(t *testing.T) {
 				require.NotEqual(t, newMonster.Attack, updatedMonster.Attack)
 				require.NotEqual(t, newMonster.Defends, updatedMonster.Defends)
 				require.NotEqual(t, newMonster.Speed, updatedMonster.Speed)
-				require.NotEqual(t, newMonster.Catched, updatedMonster.Catched)
 				require.NotEqual(t, newMonster.ImageName, updatedMonster.ImageName)
 				require.NotEqual(t, newMonster.ImageURL, updatedMonster.ImageURL)
 
@@ -448,7 +844,6 @@ func TestUpdateMonsterRepository(t *testing.T) {
 				require.NotEqual(t, newMonster.Attack, updatedMonster.Attack)
 				require.NotEqual(t, newMonster.Defends, updatedMonster.Defends)
 				require.NotEqual(t, newMonster.Speed, updatedMonster.Speed)
-				require.NotEqual(t, newMonster.Catched, updatedMonster.Catched)
 				require.NotEqual(t, newMonster.ImageName, updatedMonster.ImageName)
 				require.NotEqual(t, newMonster.ImageURL, updatedMonster.ImageURL)
 
@@ -476,3 +871,49 @@ func TestDeleteMonsterRepository(t *testing.T) {
 	require.Empty(t, monster.ID)
 
 }
+
+func TestPurgeMonsterRepository(t *testing.T) {
+	newMonster, _ := RandomCreateMonster(t)
+
+	repositoryMonster := repository.NewMonsterRespository(ConnTest)
+	ctx := context.Background()
+
+	ok, err := repositoryMonster.Delete(ctx, newMonster)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// Monster deleted after deletedBefore is not purged
+	ok, err = repositoryMonster.Purge(ctx, newMonster, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// Monster restored after it is found is not purged
+	_, err = repositoryMonster.Restore(ctx, newMonster.ID)
+	require.NoError(t, err)
+	ok, err = repositoryMonster.Purge(ctx, newMonster, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// Types are kept in trash
+	monster, err := repositoryMonster.FindByID(ctx, newMonster.ID)
+	require.NoError(t, err)
+	require.NotEmpty(t, monster.Types)
+
+	// Monster in trash
+	_, err = repositoryMonster.Delete(ctx, newMonster)
+	require.NoError(t, err)
+	monsters, err := repositoryMonster.FindPurgeable(ctx, time.Now().Add(time.Hour), 1000)
+	require.NoError(t, err)
+	found := false
+	for _, m := range monsters {
+		found = found || m.ID == newMonster.ID
+	}
+	require.True(t, found)
+
+	ok, err = repositoryMonster.Purge(ctx, newMonster, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	_, err = repositoryMonster.Restore(ctx, newMonster.ID)
+	require.Error(t, err)
+}
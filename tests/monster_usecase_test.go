@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"mime/multipart"
 	"os"
+	"path/filepath"
 	"strconv"
+	"sync"
 	"testing"
 	"time"
 
@@ -31,7 +33,9 @@ func RandomCreateMonsterUsecase(t *testing.T) domain.Monster {
 	fileName := fmt.Sprintf(`%s_%v_%s`, "usecase_create_test", nowRFC3339, "image.png")
 
 	repositoryMonster := repository.NewMonsterRespository(ConnTest)
-	usecaseMonster := usecase.NewUsecaseMonster(repositoryMonster)
+	repositoryCapture := repository.NewCaptureRepository(ConnTest)
+	repositoryEvolution := repository.NewEvolutionRepository(ConnTest)
+	usecaseMonster := usecase.NewUsecaseMonster(repositoryMonster, repositoryCapture, repositoryEvolution, ImageStoreTest, AuditTest)
 
 	var randTypes []string
 	var randCategories []string
@@ -146,7 +150,9 @@ func TestFindAllMonsterUsecase(t *testing.T) {
 	newMonster, randTypes := RandomCreateMonster(t)
 
 	repositoryMonster := repository.NewMonsterRespository(ConnTest)
-	usecaseMonster := usecase.NewUsecaseMonster(repositoryMonster)
+	repositoryCapture := repository.NewCaptureRepository(ConnTest)
+	repositoryEvolution := repository.NewEvolutionRepository(ConnTest)
+	usecaseMonster := usecase.NewUsecaseMonster(repositoryMonster, repositoryCapture, repositoryEvolution, ImageStoreTest, AuditTest)
 	ctx := context.Background()
 
 	testCases := []struct {
@@ -207,7 +213,7 @@ func TestFindAllMonsterUsecase(t *testing.T) {
 
 		t.Run(tc.name, func(t *testing.T) {
 			// Find All
-			monsters, err := usecaseMonster.FindAll(ctx, tc.queryParameter)
+			monsters, _, err := usecaseMonster.FindAll(ctx, tc.queryParameter)
 			require.NoError(t, err)
 
 			for _, monster := range monsters {
@@ -231,7 +237,9 @@ func TestFindByIDMonsterUsecase(t *testing.T) {
 	// Create random monsters
 	newMonster, _ := RandomCreateMonster(t)
 	repositoryMonster := repository.NewMonsterRespository(ConnTest)
-	usecaseMonster := usecase.NewUsecaseMonster(repositoryMonster)
+	repositoryCapture := repository.NewCaptureRepository(ConnTest)
+	repositoryEvolution := repository.NewEvolutionRepository(ConnTest)
+	usecaseMonster := usecase.NewUsecaseMonster(repositoryMonster, repositoryCapture, repositoryEvolution, ImageStoreTest, AuditTest)
 	ctx := context.Background()
 
 	testCases := []struct {
@@ -254,7 +262,7 @@ func TestFindByIDMonsterUsecase(t *testing.T) {
 
 		t.Run(tc.name, func(t *testing.T) {
 			// Find by id
-			monster, err := usecaseMonster.FindByID(ctx, tc.idMonster)
+			monster, err := usecaseMonster.FindByID(ctx, tc.idMonster, "")
 
 			if tc.name == "find_by_id_monster_success" {
 				require.NoError(t, err)
@@ -297,7 +305,9 @@ func TestUpdateMonsterUsecase(t *testing.T) {
 	// Create random monsters
 	newMonster, _ := RandomCreateMonster(t)
 	repositoryMonster := repository.NewMonsterRespository(ConnTest)
-	usecaseMonster := usecase.NewUsecaseMonster(repositoryMonster)
+	repositoryCapture := repository.NewCaptureRepository(ConnTest)
+	repositoryEvolution := repository.NewEvolutionRepository(ConnTest)
+	usecaseMonster := usecase.NewUsecaseMonster(repositoryMonster, repositoryCapture, repositoryEvolution, ImageStoreTest, AuditTest)
 
 	var randTypes []string
 	var randCategories []string
@@ -328,7 +338,6 @@ func TestUpdateMonsterUsecase(t *testing.T) {
 				Attack:      "",
 				Defends:     "",
 				Speed:       "",
-				Catched:     "",
 			},
 		},
 		{
@@ -345,7 +354,6 @@ func TestUpdateMonsterUsecase(t *testing.T) {
 				Attack:      "1",
 				Defends:     "1",
 				Speed:       "1",
-				Catched:     "true",
 				TypeID:      randTypes,
 			},
 		},
@@ -363,7 +371,6 @@ func TestUpdateMonsterUsecase(t *testing.T) {
 				Attack:      "1",
 				Defends:     "1",
 				Speed:       "1",
-				Catched:     "true",
 				TypeID:      randTypes,
 			},
 		},
@@ -381,7 +388,6 @@ func TestUpdateMonsterUsecase(t *testing.T) {
 				Attack:      "1",
 				Defends:     "1",
 				Speed:       "1",
-				Catched:     "true",
 				TypeID:      []string{"558160ef-e8f5-4951-b5f4-feeb0815b510", "d5a8d4bb-eb0a-44a4-ae46-eb2af2b2002d"},
 			},
 		},
@@ -439,7 +445,6 @@ func TestUpdateMonsterUsecase(t *testing.T) {
 				require.NotEqual(t, newMonster.Attack, updatedMonster.Attack)
 				require.NotEqual(t, newMonster.Defends, updatedMonster.Defends)
 				require.NotEqual(t, newMonster.Speed, updatedMonster.Speed)
-				require.NotEqual(t, newMonster.Catched, updatedMonster.Catched)
 
 				require.NotEqual(t, newMonster.ImageName, updatedMonster.ImageName)
 				require.NotEqual(t, newMonster.ImageURL, updatedMonster.ImageURL)
@@ -480,7 +485,14 @@ func TestUpdateMarkMonsterCapturedUsecase(t *testing.T) {
 	// Create random monsters
 	newMonster, _ := RandomCreateMonster(t)
 	repositoryMonster := repository.NewMonsterRespository(ConnTest)
-	usecaseMonster := usecase.NewUsecaseMonster(repositoryMonster)
+	repositoryCapture := repository.NewCaptureRepository(ConnTest)
+	repositoryEvolution := repository.NewEvolutionRepository(ConnTest)
+	usecaseMonster := usecase.NewUsecaseMonster(repositoryMonster, repositoryCapture, repositoryEvolution, ImageStoreTest, AuditTest)
+
+	// Captured is relative to user, use user and admin
+	repositoryUser := repository.NewUserRepository(ConnTest)
+	user, _ := repositoryUser.FindByUsername(context.Background(), "user")
+	admin, _ := repositoryUser.FindByUsername(context.Background(), "admin")
 
 	testCases := []struct {
 		name      string
@@ -494,6 +506,13 @@ func TestUpdateMarkMonsterCapturedUsecase(t *testing.T) {
 				Catched: true,
 			},
 		},
+		{
+			name:      "update_mark_monster_released_monster_success",
+			idMonster: newMonster.ID,
+			reqUpdate: web.MonsterUpdateRequestMonsterCapture{
+				Catched: false,
+			},
+		},
 		{
 			name:      "update_mark_monster_captured_monster_failed_monster_not_found",
 			idMonster: "368bd987-dec6-4405-a036-bc1232db21b2",
@@ -503,20 +522,44 @@ func TestUpdateMarkMonsterCapturedUsecase(t *testing.T) {
 		},
 	}
 
-	// Test
+	// Test, run in order because captured and released is the same monster
 	for i := range testCases {
 		tc := testCases[i]
 
 		t.Run(tc.name, func(t *testing.T) {
-			t.Parallel()
-			ok, err := usecaseMonster.UpdateMarkMonsterCaptured(context.Background(), tc.idMonster, tc.reqUpdate)
+			ok, err := usecaseMonster.UpdateMarkMonsterCaptured(context.Background(), tc.idMonster, user.ID, tc.reqUpdate)
 
-			monsterUpdated, _ := usecaseMonster.FindByID(context.Background(), newMonster.ID)
+			monsterByUser, _ := usecaseMonster.FindByID(context.Background(), newMonster.ID, user.ID)
+			monsterByAdmin, _ := usecaseMonster.FindByID(context.Background(), newMonster.ID, admin.ID)
+			monsterByGuest, _ := usecaseMonster.FindByID(context.Background(), newMonster.ID, "")
 
 			if tc.name == "update_mark_monster_captured_monster_success" {
 				require.NoError(t, err)
 				require.True(t, ok)
-				require.True(t, monsterUpdated.Catched)
+				require.True(t, monsterByUser.Catched)
+				// Other user and guest not captured
+				require.False(t, monsterByAdmin.Catched)
+				require.False(t, monsterByGuest.Catched)
+
+				// Find all with filter catched only for user
+				monsters, _, err := usecaseMonster.FindAll(context.Background(), web.MonsterQueryRequest{Catched: "true", UserID: user.ID})
+				require.NoError(t, err)
+				var found bool
+				for _, monster := range monsters {
+					require.True(t, monster.Catched)
+					if monster.ID == newMonster.ID {
+						found = true
+					}
+				}
+				require.True(t, found)
+
+				monsters, _, err = usecaseMonster.FindAll(context.Background(), web.MonsterQueryRequest{Catched: "true"})
+				require.NoError(t, err)
+				require.Equal(t, 0, len(monsters))
+			} else if tc.name == "update_mark_monster_released_monster_success" {
+				require.NoError(t, err)
+				require.True(t, ok)
+				require.False(t, monsterByUser.Catched)
 			} else {
 				require.Error(t, err)
 				var errTest error
@@ -532,7 +575,9 @@ func TestDeleteMonsterUsecase(t *testing.T) {
 	newMonster := RandomCreateMonsterUsecase(t)
 
 	repositoryMonster := repository.NewMonsterRespository(ConnTest)
-	usecaseMonster := usecase.NewUsecaseMonster(repositoryMonster)
+	repositoryCapture := repository.NewCaptureRepository(ConnTest)
+	repositoryEvolution := repository.NewEvolutionRepository(ConnTest)
+	usecaseMonster := usecase.NewUsecaseMonster(repositoryMonster, repositoryCapture, repositoryEvolution, ImageStoreTest, AuditTest)
 
 	testCases := []struct {
 		name      string
@@ -561,7 +606,7 @@ func TestDeleteMonsterUsecase(t *testing.T) {
 				require.True(t, ok)
 
 				// Find by id
-				monster, err := usecaseMonster.FindByID(context.Background(), tc.idMonster)
+				monster, err := usecaseMonster.FindByID(context.Background(), tc.idMonster, "")
 				require.Empty(t, monster.ID)
 
 				msg := fmt.Sprintf("monster with id %s not found", tc.idMonster)
@@ -575,3 +620,144 @@ func TestDeleteMonsterUsecase(t *testing.T) {
 		})
 	}
 }
+
+func TestTrashMonsterUsecase(t *testing.T) {
+	t.Parallel()
+	repositoryMonster := repository.NewMonsterRespository(ConnTest)
+	repositoryCapture := repository.NewCaptureRepository(ConnTest)
+	repositoryEvolution := repository.NewEvolutionRepository(ConnTest)
+	usecaseMonster := usecase.NewUsecaseMonster(repositoryMonster, repositoryCapture, repositoryEvolution, ImageStoreTest, AuditTest)
+	ctx := context.Background()
+
+	// Create monster with own image, image of other monster must not be removed by purge
+	file, err := os.Open("file_sample/image.png")
+	require.NoError(t, err)
+	defer file.Close()
+	fileName := util.RandomString(12) + ".png"
+	imagePath := filepath.Join(ConfigTest.IMAGE_LOCAL_DIR, fileName)
+
+	randCategory, randType := RandomCategoryAndType()
+	newMonster, err := usecaseMonster.Create(ctx, web.MonsterCreateRequest{
+		Name:        util.RandomString(10),
+		CategoryID:  randCategory,
+		Description: util.RandomString(20),
+		Length:      1.5,
+		Weight:      100,
+		Hp:          100,
+		Attack:      100,
+		Defends:     100,
+		Speed:       100,
+		TypeID:      []string{randType},
+	}, file, fileName)
+	require.NoError(t, err)
+
+	// Delete move monster into trash, image is kept
+	ok, err := usecaseMonster.Delete(ctx, newMonster.ID)
+	require.NoError(t, err)
+	require.True(t, ok)
+	_, err = usecaseMonster.FindByID(ctx, newMonster.ID, "")
+	require.Error(t, err)
+	require.FileExists(t, imagePath)
+
+	// Restore with types
+	restored, err := usecaseMonster.Restore(ctx, newMonster.ID)
+	require.NoError(t, err)
+	require.Equal(t, newMonster.Name, restored.Name)
+	require.Equal(t, newMonster.Version+1, restored.Version)
+	require.Len(t, restored.Types, 1)
+	require.Equal(t, randType, restored.Types[0].ID)
+
+	// Monster not in trash cannot be restored
+	_, err = usecaseMonster.Restore(ctx, newMonster.ID)
+	require.EqualError(t, err, fmt.Sprintf("monster with id %s not found in trash", newMonster.ID))
+
+	// Monster in trash before retention is not purged
+	_, err = usecaseMonster.Delete(ctx, newMonster.ID)
+	require.NoError(t, err)
+	_, err = usecaseMonster.PurgeTrash(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	_, err = usecaseMonster.Restore(ctx, newMonster.ID)
+	require.NoError(t, err)
+
+	// Monster in trash after retention is purged with the image
+	_, err = usecaseMonster.Delete(ctx, newMonster.ID)
+	require.NoError(t, err)
+	err = ConnTest.Exec("UPDATE monsters SET deleted_at = now() - interval '2 hours' WHERE id = ?", newMonster.ID).Error
+	require.NoError(t, err)
+
+	purged, err := usecaseMonster.PurgeTrash(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, purged, 1)
+	require.NoFileExists(t, imagePath)
+
+	var count int64
+	err = ConnTest.Unscoped().Model(&domain.Monster{}).Where("id = ?", newMonster.ID).Count(&count).Error
+	require.NoError(t, err)
+	require.Equal(t, int64(0), count)
+
+	_, err = usecaseMonster.Restore(ctx, newMonster.ID)
+	require.Error(t, err)
+}
+
+// recordingImageStore keep names of uploaded and deleted images, upload fails when err is set
+type recordingImageStore struct {
+	mu       sync.Mutex
+	err      error
+	uploaded []string
+	deleted  []string
+}
+
+func (s *recordingImageStore) Upload(ctx context.Context, file multipart.File, fileName string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return "", s.err
+	}
+	s.uploaded = append(s.uploaded, fileName)
+	return "http://localhost/" + fileName, nil
+}
+
+func (s *recordingImageStore) Delete(ctx context.Context, fileName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deleted = append(s.deleted, fileName)
+	return nil
+}
+
+func TestUpdateMonsterImageUsecase(t *testing.T) {
+	newMonster, _ := RandomCreateMonster(t)
+	repositoryMonster := repository.NewMonsterRespository(ConnTest)
+	repositoryCapture := repository.NewCaptureRepository(ConnTest)
+	repositoryEvolution := repository.NewEvolutionRepository(ConnTest)
+	imageStore := &recordingImageStore{err: errors.New("upload failed")}
+	usecaseMonster := usecase.NewUsecaseMonster(repositoryMonster, repositoryCapture, repositoryEvolution, imageStore, AuditTest)
+	ctx := context.Background()
+
+	file, err := os.Open("file_sample/image.png")
+	require.NoError(t, err)
+	defer file.Close()
+
+	// Failed upload keeps monster and its old image
+	fileName := "usecase_update_image_" + util.RandomString(10) + ".png"
+	_, err = usecaseMonster.Update(ctx, newMonster.ID, web.MonsterUpdateRequest{Name: "UPDATED"}, file, fileName)
+	require.Error(t, err)
+	require.Empty(t, imageStore.deleted)
+
+	monster, err := repositoryMonster.FindByID(ctx, newMonster.ID)
+	require.NoError(t, err)
+	require.Equal(t, newMonster.Name, monster.Name)
+	require.Equal(t, newMonster.ImageName, monster.ImageName)
+	require.Equal(t, newMonster.Version, monster.Version)
+
+	// Image and fields are updated in one version, old image is removed after
+	imageStore.err = nil
+	version := newMonster.Version
+	updatedMonster, err := usecaseMonster.Update(ctx, newMonster.ID, web.MonsterUpdateRequest{Name: "UPDATED", Version: &version}, file, fileName)
+	require.NoError(t, err)
+	require.Equal(t, "UPDATED", updatedMonster.Name)
+	require.Equal(t, fileName, updatedMonster.ImageName)
+	require.Equal(t, "http://localhost/"+fileName, updatedMonster.ImageURL)
+	require.Equal(t, newMonster.Version+1, updatedMonster.Version)
+	require.Equal(t, []string{fileName}, imageStore.uploaded)
+	require.Equal(t, []string{newMonster.ImageName}, imageStore.deleted)
+}
@@ -0,0 +1,320 @@
+package tests
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/letenk/pokedex/router"
+	"github.com/letenk/pokedex/usecase"
+	"github.com/letenk/pokedex/util"
+	"github.com/stretchr/testify/require"
+)
+
+// stubIdentityProvider is local OpenID Connect provider, code is registered by test instead of login page
+type stubIdentityProvider struct {
+	server   *httptest.Server
+	key      *rsa.PrivateKey
+	clientID string
+	secret   string
+
+	mu    sync.Mutex
+	codes map[string]stubAuthorization
+}
+
+type stubAuthorization struct {
+	Nonce         string
+	CodeChallenge string
+	Claims        jwt.MapClaims
+}
+
+func newStubIdentityProvider(t *testing.T) *stubIdentityProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	idp := &stubIdentityProvider{
+		key:      key,
+		clientID: "pokedex",
+		secret:   "secret",
+		codes:    map[string]stubAuthorization{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "stub",
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		clientID, secret, ok := r.BasicAuth()
+		if !ok || clientID != idp.clientID || secret != idp.secret {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		idp.mu.Lock()
+		authorization, ok := idp.codes[r.FormValue("code")]
+		delete(idp.codes, r.FormValue("code"))
+		idp.mu.Unlock()
+
+		// PKCE
+		challenge := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+		if !ok || base64.RawURLEncoding.EncodeToString(challenge[:]) != authorization.CodeChallenge {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		claims := jwt.MapClaims{
+			"iss":   idp.server.URL,
+			"aud":   idp.clientID,
+			"exp":   time.Now().Add(time.Minute).Unix(),
+			"iat":   time.Now().Unix(),
+			"nonce": authorization.Nonce,
+		}
+		for k, v := range authorization.Claims {
+			claims[k] = v
+		}
+
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "stub"
+		idToken, _ := token.SignedString(key)
+		json.NewEncoder(w).Encode(map[string]string{"id_token": idToken, "token_type": "Bearer"})
+	})
+
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+
+	return idp
+}
+
+// authorize register code for the authorization url, like user login on the provider
+func (idp *stubIdentityProvider) authorize(t *testing.T, authURL string, claims jwt.MapClaims) string {
+	location, err := url.Parse(authURL)
+	require.NoError(t, err)
+	require.Equal(t, idp.clientID, location.Query().Get("client_id"))
+	require.Equal(t, "S256", location.Query().Get("code_challenge_method"))
+
+	code := util.RandomString(20)
+	idp.mu.Lock()
+	idp.codes[code] = stubAuthorization{
+		Nonce:         location.Query().Get("nonce"),
+		CodeChallenge: location.Query().Get("code_challenge"),
+		Claims:        claims,
+	}
+	idp.mu.Unlock()
+
+	return code
+}
+
+// oidcLogin login with provider through route, return response of callback
+func oidcLogin(t *testing.T, route http.Handler, idp *stubIdentityProvider, claims jwt.MapClaims, changeState bool) (*http.Response, map[string]interface{}) {
+	request := httptest.NewRequest(http.MethodGet, "http://localhost:3000/api/v1/oidc/login", nil)
+	recorder := httptest.NewRecorder()
+	route.ServeHTTP(recorder, request)
+	response := recorder.Result()
+	require.Equal(t, http.StatusFound, response.StatusCode)
+
+	authURL := response.Header.Get("Location")
+	require.True(t, strings.HasPrefix(authURL, idp.server.URL+"/authorize"))
+	location, _ := url.Parse(authURL)
+	state := location.Query().Get("state")
+	if changeState {
+		state = util.RandomString(10)
+	}
+	code := idp.authorize(t, authURL, claims)
+
+	request = httptest.NewRequest(http.MethodGet, fmt.Sprintf("http://localhost:3000/api/v1/oidc/callback?code=%s&state=%s", code, state), nil)
+	for _, cookie := range response.Cookies() {
+		request.AddCookie(cookie)
+	}
+	recorder = httptest.NewRecorder()
+	route.ServeHTTP(recorder, request)
+
+	response = recorder.Result()
+	body, _ := io.ReadAll(response.Body)
+	var responseBody map[string]interface{}
+	json.Unmarshal(body, &responseBody)
+	return response, responseBody
+}
+
+// oidcConfig is config of login with the stub provider
+func oidcConfig(idp *stubIdentityProvider) util.Config {
+	config := ConfigTest
+	config.OIDC_ISSUER = idp.server.URL
+	config.OIDC_CLIENT_ID = idp.clientID
+	config.OIDC_CLIENT_SECRET = idp.secret
+	config.OIDC_REDIRECT_URL = "http://localhost:3000/api/v1/oidc/callback"
+	config.OIDC_AUTO_PROVISION = true
+	config.OIDC_DEFAULT_ROLE = "user"
+	config.OIDC_GROUP_ROLES = []string{"pokedex-admins=admin"}
+	return config
+}
+
+func TestOIDCLoginHandler(t *testing.T) {
+	t.Parallel()
+	idp := newStubIdentityProvider(t)
+
+	// Second factor is tested in TestOIDCLoginMFAHandler
+	config := oidcConfig(idp)
+	config.MFA_REQUIRED_ROLES = nil
+	route := router.SetupRouter(ConnTest, config, usecase.NewMemoryCache(0))
+
+	// Helper for login with provider, return response of callback
+	login := func(claims jwt.MapClaims, changeState bool) (*http.Response, map[string]interface{}) {
+		return oidcLogin(t, route, idp, claims, changeState)
+	}
+
+	// Helper for get profile
+	me := func(token string) map[string]interface{} {
+		request := httptest.NewRequest(http.MethodGet, "http://localhost:3000/api/v1/me", nil)
+		request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+		recorder := httptest.NewRecorder()
+		route.ServeHTTP(recorder, request)
+
+		response := recorder.Result()
+		require.Equal(t, 200, response.StatusCode)
+		body, _ := io.ReadAll(response.Body)
+		var responseBody map[string]interface{}
+		json.Unmarshal(body, &responseBody)
+		return responseBody["data"].(map[string]interface{})
+	}
+
+	subject := util.RandomString(12)
+
+	// First login provision user with role of group
+	response, responseBody := login(jwt.MapClaims{
+		"sub":                subject,
+		"preferred_username": "Ash.Ketchum",
+		"name":               "Ash Ketchum",
+		"groups":             []string{"trainers", "pokedex-admins"},
+	}, false)
+	require.Equal(t, 200, response.StatusCode)
+	profile := me(responseBody["data"].(map[string]interface{})["token"].(string))
+	require.True(t, strings.HasPrefix(profile["username"].(string), "ashketchum"))
+	require.Equal(t, "Ash Ketchum", profile["fullname"])
+	require.Equal(t, "admin", profile["role"])
+
+	// Next login use the same user, role is synced with groups
+	response, responseBody = login(jwt.MapClaims{
+		"sub":    subject,
+		"groups": []string{"trainers"},
+	}, false)
+	require.Equal(t, 200, response.StatusCode)
+	nextProfile := me(responseBody["data"].(map[string]interface{})["token"].(string))
+	require.Equal(t, profile["id"], nextProfile["id"])
+	require.Equal(t, "user", nextProfile["role"])
+
+	// Failed state is not matched
+	response, _ = login(jwt.MapClaims{"sub": subject}, true)
+	require.Equal(t, 401, response.StatusCode)
+
+	// Failed callback without state cookie
+	request := httptest.NewRequest(http.MethodGet, "http://localhost:3000/api/v1/oidc/callback?code=abc&state=abc", nil)
+	recorder := httptest.NewRecorder()
+	route.ServeHTTP(recorder, request)
+	require.Equal(t, 401, recorder.Result().StatusCode)
+}
+
+func TestOIDCLoginMFAHandler(t *testing.T) {
+	t.Parallel()
+	idp := newStubIdentityProvider(t)
+
+	// Admin must login with second factor
+	claims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"sub":    util.RandomString(12),
+			"groups": []string{"pokedex-admins"},
+		}
+	}
+
+	t.Run("local", func(t *testing.T) {
+		config := oidcConfig(idp)
+		config.MFA_REQUIRED_ROLES = []string{"admin"}
+		config.OIDC_MFA_MODE = "local"
+		route := router.SetupRouter(ConnTest, config, usecase.NewMemoryCache(0))
+
+		// Pending token even when provider has verified second factor
+		adminClaims := claims()
+		adminClaims["amr"] = []string{"pwd", "mfa"}
+		response, responseBody := oidcLogin(t, route, idp, adminClaims, false)
+		require.Equal(t, 200, response.StatusCode)
+		require.Equal(t, "mfa required", responseBody["message"])
+		data := responseBody["data"].(map[string]interface{})
+		require.NotEmpty(t, data["mfa_token"])
+		require.Nil(t, data["token"])
+		require.Equal(t, true, data["enrollment_required"])
+
+		// Role without MFA get access token
+		userClaims := claims()
+		userClaims["groups"] = []string{"trainers"}
+		response, responseBody = oidcLogin(t, route, idp, userClaims, false)
+		require.Equal(t, 200, response.StatusCode)
+		require.NotEmpty(t, responseBody["data"].(map[string]interface{})["token"])
+	})
+
+	t.Run("provider", func(t *testing.T) {
+		config := oidcConfig(idp)
+		config.MFA_REQUIRED_ROLES = []string{"admin"}
+		config.OIDC_MFA_MODE = "provider"
+		config.OIDC_MFA_ACR_VALUES = []string{"urn:pokedex:mfa"}
+		route := router.SetupRouter(ConnTest, config, usecase.NewMemoryCache(0))
+
+		// Failed id token without second factor
+		response, _ := oidcLogin(t, route, idp, claims(), false)
+		require.Equal(t, 401, response.StatusCode)
+
+		adminClaims := claims()
+		adminClaims["amr"] = []string{"pwd", "otp"}
+		response, _ = oidcLogin(t, route, idp, adminClaims, false)
+		require.Equal(t, 401, response.StatusCode)
+
+		// Access token with amr mfa
+		adminClaims = claims()
+		adminClaims["amr"] = []string{"pwd", "mfa"}
+		response, responseBody := oidcLogin(t, route, idp, adminClaims, false)
+		require.Equal(t, 200, response.StatusCode)
+		require.NotEmpty(t, responseBody["data"].(map[string]interface{})["token"])
+
+		// Access token with acr of config
+		adminClaims = claims()
+		adminClaims["acr"] = "urn:pokedex:mfa"
+		response, responseBody = oidcLogin(t, route, idp, adminClaims, false)
+		require.Equal(t, 200, response.StatusCode)
+		require.NotEmpty(t, responseBody["data"].(map[string]interface{})["token"])
+	})
+}
+
+// Route of OIDC is not registered without issuer
+func TestOIDCDisabledHandler(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "http://localhost:3000/api/v1/oidc/login", nil)
+	recorder := httptest.NewRecorder()
+	RouteTest.ServeHTTP(recorder, request)
+	require.Equal(t, 404, recorder.Result().StatusCode)
+}
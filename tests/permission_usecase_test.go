@@ -0,0 +1,67 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/letenk/pokedex/models/domain"
+	"github.com/letenk/pokedex/repository"
+	"github.com/letenk/pokedex/usecase"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHasPermissionUsecase(t *testing.T) {
+	repository := repository.NewPermissionRepository(ConnTest)
+	usecase := usecase.NewUsecasePermission(repository)
+
+	// Test Cases
+	testCases := []struct {
+		name       string
+		role       string
+		permission string
+		expected   bool
+	}{
+		{
+			name:       "admin_can_write_monster",
+			role:       "admin",
+			permission: domain.PermissionMonsterWrite,
+			expected:   true,
+		},
+		{
+			name:       "admin_can_capture_monster",
+			role:       "admin",
+			permission: domain.PermissionMonsterCapture,
+			expected:   true,
+		},
+		{
+			name:       "user_can_capture_monster",
+			role:       "user",
+			permission: domain.PermissionMonsterCapture,
+			expected:   true,
+		},
+		{
+			name:       "user_can_not_read_category",
+			role:       "user",
+			permission: domain.PermissionCategoryRead,
+			expected:   false,
+		},
+		{
+			name:       "unknown_role_has_no_permission",
+			role:       "viewer",
+			permission: domain.PermissionMonsterCapture,
+			expected:   false,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ok, err := usecase.HasPermission(context.Background(), tc.role, tc.permission)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, ok)
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package tests
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/letenk/pokedex/models/web"
+	"github.com/letenk/pokedex/repository"
+	"github.com/letenk/pokedex/router"
+	"github.com/letenk/pokedex/usecase"
+	"github.com/stretchr/testify/require"
+)
+
+// writePrivateKey save private key as PKCS8 PEM `<kid>.pem` inside dir
+func writePrivateKey(t *testing.T, dir string, kid string, privateKey interface{}) {
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	err = os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0600)
+	require.NoError(t, err)
+}
+
+func TestSigningKeyRotation(t *testing.T) {
+	t.Parallel()
+	repositoryToken := repository.NewTokenRepository(ConnTest)
+	repositoryPermission := repository.NewPermissionRepository(ConnTest)
+	repositoryLoginAttempt := repository.NewLoginAttemptRepository(ConnTest)
+	repositoryMFA := repository.NewMFARepository(ConnTest)
+	repositoryUser := repository.NewUserRepository(ConnTest)
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	// First key is RSA
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	writePrivateKey(t, dir, "key-1", rsaKey)
+
+	config := ConfigTest
+	config.JWT_KEYS_DIR = dir
+	config.JWT_ACTIVE_KID = "key-1"
+	keySet, err := usecase.NewKeySet(config)
+	require.NoError(t, err)
+	usecaseOld := usecase.NewUsecaseUser(repositoryUser, repositoryToken, repositoryPermission, repositoryLoginAttempt, repositoryMFA, AuditTest, keySet, config)
+
+	authToken, err := usecaseOld.Login(ctx, web.UserLoginRequest{Username: "user", Password: "password"})
+	require.NoError(t, err)
+
+	// Header of token has kid and algorithm of key
+	token, _, err := new(jwt.Parser).ParseUnverified(authToken.AccessToken, &usecase.Claim{})
+	require.NoError(t, err)
+	require.Equal(t, "key-1", token.Header["kid"])
+	require.Equal(t, "RS256", token.Header["alg"])
+
+	// Failed token signed with secret
+	_, _, err = usecaseOld.Authenticate(ctx, GetToken(web.UserLoginRequest{Username: "user", Password: "password"}))
+	require.Error(t, err)
+
+	// Rotate to ed25519 key, the old key still verify
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	writePrivateKey(t, dir, "key-2", edKey)
+
+	config.JWT_ACTIVE_KID = "key-2"
+	keySet, err = usecase.NewKeySet(config)
+	require.NoError(t, err)
+	usecaseNew := usecase.NewUsecaseUser(repositoryUser, repositoryToken, repositoryPermission, repositoryLoginAttempt, repositoryMFA, AuditTest, keySet, config)
+
+	_, _, err = usecaseNew.Authenticate(ctx, authToken.AccessToken)
+	require.NoError(t, err)
+
+	newAuthToken, err := usecaseNew.Login(ctx, web.UserLoginRequest{Username: "user", Password: "password"})
+	require.NoError(t, err)
+	token, _, err = new(jwt.Parser).ParseUnverified(newAuthToken.AccessToken, &usecase.Claim{})
+	require.NoError(t, err)
+	require.Equal(t, "key-2", token.Header["kid"])
+	require.Equal(t, "EdDSA", token.Header["alg"])
+
+	_, _, err = usecaseNew.Authenticate(ctx, newAuthToken.AccessToken)
+	require.NoError(t, err)
+
+	// Both keys are published, active key first
+	jwks := usecaseNew.JSONWebKeys()
+	require.Len(t, jwks, 2)
+	require.Equal(t, "key-2", jwks[0].KeyID)
+	require.Equal(t, "OKP", jwks[0].KeyType)
+	require.Equal(t, "key-1", jwks[1].KeyID)
+	require.Equal(t, "RSA", jwks[1].KeyType)
+
+	// Old key removed, token signed by it is rejected
+	err = os.Remove(filepath.Join(dir, "key-1.pem"))
+	require.NoError(t, err)
+	keySet, err = usecase.NewKeySet(config)
+	require.NoError(t, err)
+	usecaseNew = usecase.NewUsecaseUser(repositoryUser, repositoryToken, repositoryPermission, repositoryLoginAttempt, repositoryMFA, AuditTest, keySet, config)
+
+	_, _, err = usecaseNew.Authenticate(ctx, authToken.AccessToken)
+	require.Error(t, err)
+
+	// Failed active key not found
+	config.JWT_ACTIVE_KID = "key-1"
+	_, err = usecase.NewKeySet(config)
+	require.Error(t, err)
+}
+
+func TestJWKSHandler(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	writePrivateKey(t, dir, "key-1", edKey)
+
+	config := ConfigTest
+	config.JWT_KEYS_DIR = dir
+	config.JWT_ACTIVE_KID = "key-1"
+	route := router.SetupRouter(ConnTest, config, usecase.NewMemoryCache(0))
+
+	request := httptest.NewRequest(http.MethodGet, "http://localhost:3000/.well-known/jwks.json", nil)
+	recorder := httptest.NewRecorder()
+	route.ServeHTTP(recorder, request)
+
+	response := recorder.Result()
+	require.Equal(t, 200, response.StatusCode)
+
+	body, _ := io.ReadAll(response.Body)
+	var responseBody web.JWKSResponse
+	err = json.Unmarshal(body, &responseBody)
+	require.NoError(t, err)
+	require.Len(t, responseBody.Keys, 1)
+	require.Equal(t, "key-1", responseBody.Keys[0].KeyID)
+	require.Equal(t, "EdDSA", responseBody.Keys[0].Algorithm)
+	require.Equal(t, "Ed25519", responseBody.Keys[0].Curve)
+	require.False(t, strings.Contains(string(body), "\"d\""))
+}
@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshTokenAndLogoutHandler(t *testing.T) {
+	t.Parallel()
+
+	// Helper for send request
+	send := func(method, target, token, dataBody string) (*http.Response, map[string]interface{}) {
+		request := httptest.NewRequest(method, target, strings.NewReader(dataBody))
+		request.Header.Add("Content-Type", "application/json")
+		if token != "" {
+			request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+		}
+		recorder := httptest.NewRecorder()
+		RouteTest.ServeHTTP(recorder, request)
+
+		response := recorder.Result()
+		body, _ := io.ReadAll(response.Body)
+		var responseBody map[string]interface{}
+		json.Unmarshal(body, &responseBody)
+		return response, responseBody
+	}
+
+	// Login
+	response, responseBody := send(http.MethodPost, "http://localhost:3000/api/v1/login", "", `{"username": "user", "password": "password"}`)
+	require.Equal(t, 200, response.StatusCode)
+	data := responseBody["data"].(map[string]interface{})
+	require.NotEmpty(t, data["token"])
+	require.NotEmpty(t, data["refresh_token"])
+	require.Equal(t, "Bearer", data["token_type"])
+
+	// Refresh
+	response, responseBody = send(http.MethodPost, "http://localhost:3000/api/v1/token/refresh", "", fmt.Sprintf(`{"refresh_token": "%s"}`, data["refresh_token"]))
+	require.Equal(t, 200, response.StatusCode)
+	require.Equal(t, "refresh token success", responseBody["message"])
+	refreshed := responseBody["data"].(map[string]interface{})
+
+	// Failed refresh with the old refresh token
+	response, _ = send(http.MethodPost, "http://localhost:3000/api/v1/token/refresh", "", fmt.Sprintf(`{"refresh_token": "%s"}`, data["refresh_token"]))
+	require.Equal(t, 401, response.StatusCode)
+
+	// Login again, the previous family is revoked because of reuse
+	response, responseBody = send(http.MethodPost, "http://localhost:3000/api/v1/login", "", `{"username": "user", "password": "password"}`)
+	require.Equal(t, 200, response.StatusCode)
+	data = responseBody["data"].(map[string]interface{})
+	token := data["token"].(string)
+
+	response, _ = send(http.MethodGet, "http://localhost:3000/api/v1/me", token, "")
+	require.Equal(t, 200, response.StatusCode)
+
+	// Logout
+	response, responseBody = send(http.MethodPost, "http://localhost:3000/api/v1/logout", token, fmt.Sprintf(`{"refresh_token": "%s"}`, data["refresh_token"]))
+	require.Equal(t, 200, response.StatusCode)
+	require.Equal(t, "logout success", responseBody["message"])
+
+	// Access token is rejected after logout
+	response, _ = send(http.MethodGet, "http://localhost:3000/api/v1/me", token, "")
+	require.Equal(t, 401, response.StatusCode)
+
+	// Refresh token is rejected after logout
+	response, _ = send(http.MethodPost, "http://localhost:3000/api/v1/token/refresh", "", fmt.Sprintf(`{"refresh_token": "%s"}`, data["refresh_token"]))
+	require.Equal(t, 401, response.StatusCode)
+
+	// Refreshed access token of other family is still valid
+	response, _ = send(http.MethodGet, "http://localhost:3000/api/v1/me", refreshed["token"].(string), "")
+	require.Equal(t, 200, response.StatusCode)
+}
@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/letenk/pokedex/models/web"
+	"github.com/letenk/pokedex/repository"
+	"github.com/letenk/pokedex/usecase"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshTokenUsecase(t *testing.T) {
+	t.Parallel()
+	repositoryToken := repository.NewTokenRepository(ConnTest)
+	repositoryPermission := repository.NewPermissionRepository(ConnTest)
+	repositoryLoginAttempt := repository.NewLoginAttemptRepository(ConnTest)
+	repositoryMFA := repository.NewMFARepository(ConnTest)
+	repository := repository.NewUserRepository(ConnTest)
+	usecase := usecase.NewUsecaseUser(repository, repositoryToken, repositoryPermission, repositoryLoginAttempt, repositoryMFA, AuditTest, KeySetTest, ConfigTest)
+	ctx := context.Background()
+
+	// Login
+	authToken, err := usecase.Login(ctx, web.UserLoginRequest{Username: "user", Password: "password"})
+	require.NoError(t, err)
+
+	user, claim, err := usecase.Authenticate(ctx, authToken.AccessToken)
+	require.NoError(t, err)
+	require.Equal(t, "user", user.Username)
+	require.NotEmpty(t, claim.Id)
+
+	// Rotate
+	rotatedToken, err := usecase.RefreshToken(ctx, authToken.RefreshToken)
+	require.NoError(t, err)
+	require.NotEqual(t, authToken.RefreshToken, rotatedToken.RefreshToken)
+	require.NotEqual(t, authToken.AccessToken, rotatedToken.AccessToken)
+
+	// Failed invalid refresh token
+	_, err = usecase.RefreshToken(ctx, "invalid")
+	require.Error(t, err)
+	require.Equal(t, "invalid refresh token", err.Error())
+
+	// Reuse of rotated refresh token revoke all the family
+	_, err = usecase.RefreshToken(ctx, authToken.RefreshToken)
+	require.Error(t, err)
+	require.Equal(t, "refresh token has been revoked", err.Error())
+
+	_, err = usecase.RefreshToken(ctx, rotatedToken.RefreshToken)
+	require.Error(t, err)
+	require.Equal(t, "refresh token has been revoked", err.Error())
+}
+
+func TestLogoutUsecase(t *testing.T) {
+	t.Parallel()
+	repositoryToken := repository.NewTokenRepository(ConnTest)
+	repositoryPermission := repository.NewPermissionRepository(ConnTest)
+	repositoryLoginAttempt := repository.NewLoginAttemptRepository(ConnTest)
+	repositoryMFA := repository.NewMFARepository(ConnTest)
+	repository := repository.NewUserRepository(ConnTest)
+	usecase := usecase.NewUsecaseUser(repository, repositoryToken, repositoryPermission, repositoryLoginAttempt, repositoryMFA, AuditTest, KeySetTest, ConfigTest)
+	ctx := context.Background()
+
+	// Login
+	authToken, err := usecase.Login(ctx, web.UserLoginRequest{Username: "user", Password: "password"})
+	require.NoError(t, err)
+
+	user, claim, err := usecase.Authenticate(ctx, authToken.AccessToken)
+	require.NoError(t, err)
+
+	// Failed refresh token from other user
+	adminToken, err := usecase.Login(ctx, web.UserLoginRequest{Username: "admin", Password: "password"})
+	require.NoError(t, err)
+	ok, err := usecase.Logout(ctx, user.ID, adminToken.RefreshToken, claim)
+	require.Error(t, err)
+	require.False(t, ok)
+	require.Equal(t, "invalid refresh token", err.Error())
+
+	// Logout
+	ok, err = usecase.Logout(ctx, user.ID, authToken.RefreshToken, claim)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// Access token is in denylist
+	_, _, err = usecase.Authenticate(ctx, authToken.AccessToken)
+	require.Error(t, err)
+	require.Equal(t, "token has been revoked", err.Error())
+
+	// Refresh token is revoked
+	_, err = usecase.RefreshToken(ctx, authToken.RefreshToken)
+	require.Error(t, err)
+	require.Equal(t, "refresh token has been revoked", err.Error())
+}
@@ -0,0 +1,114 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/letenk/pokedex/models/web"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypeMatchupsHandler(t *testing.T) {
+	t.Parallel()
+	tokenAdmin := GetToken(web.UserLoginRequest{Username: "admin", Password: "password"})
+	tokenUser := GetToken(web.UserLoginRequest{Username: "user", Password: "password"})
+
+	_, randType := RandomCategoryAndType()
+	matchupURL := fmt.Sprintf("http://localhost:3000/api/v1/type/%s/matchups", randType)
+
+	// Test Cases
+	testCases := []struct {
+		name   string
+		method string
+		target string
+		token  string
+		body   string
+	}{
+		{
+			name:   "success_get_matchups_with_role_user",
+			method: http.MethodGet,
+			target: matchupURL,
+			token:  tokenUser,
+		},
+		{
+			name:   "failed_save_forbidden_with_role_user",
+			method: http.MethodPut,
+			target: fmt.Sprintf("%s/%s", matchupURL, randType),
+			token:  tokenUser,
+			body:   `{"multiplier": 2}`,
+		},
+		{
+			name:   "failed_save_negative_multiplier",
+			method: http.MethodPut,
+			target: fmt.Sprintf("%s/%s", matchupURL, randType),
+			token:  tokenAdmin,
+			body:   `{"multiplier": -1}`,
+		},
+		{
+			name:   "failed_save_invalid_defender_id",
+			method: http.MethodPut,
+			target: fmt.Sprintf("%s/abc", matchupURL),
+			token:  tokenAdmin,
+			body:   `{"multiplier": 2}`,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			request.Header.Add("Content-Type", "application/json")
+			request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tc.token))
+			recorder := httptest.NewRecorder()
+			RouteTest.ServeHTTP(recorder, request)
+
+			response := recorder.Result()
+			body, _ := io.ReadAll(response.Body)
+			var responseBody map[string]interface{}
+			json.Unmarshal(body, &responseBody)
+
+			if tc.name == "success_get_matchups_with_role_user" {
+				require.Equal(t, 200, response.StatusCode)
+				require.Equal(t, "matchups of type", responseBody["message"])
+				data := responseBody["data"].(map[string]interface{})
+				require.Equal(t, randType, data["id"])
+				require.NotNil(t, data["offense"])
+				require.NotNil(t, data["defense"])
+			} else if tc.name == "failed_save_forbidden_with_role_user" {
+				require.Equal(t, 403, response.StatusCode)
+				require.Equal(t, "forbidden", responseBody["message"])
+			} else {
+				require.Equal(t, 400, response.StatusCode)
+				require.Equal(t, "save matchup failed", responseBody["message"])
+			}
+		})
+	}
+}
+
+func TestMonsterWeaknessesHandler(t *testing.T) {
+	monster, _ := RandomCreateMonster(t)
+
+	// Guest can see weaknesses of monster
+	request := httptest.NewRequest(http.MethodGet, fmt.Sprintf("http://localhost:3000/api/v1/monster/%s/weaknesses", monster.ID), nil)
+	recorder := httptest.NewRecorder()
+	RouteTest.ServeHTTP(recorder, request)
+
+	response := recorder.Result()
+	body, _ := io.ReadAll(response.Body)
+	var responseBody map[string]interface{}
+	json.Unmarshal(body, &responseBody)
+
+	require.Equal(t, 200, response.StatusCode)
+	require.Equal(t, "weaknesses of monster", responseBody["message"])
+	data := responseBody["data"].(map[string]interface{})
+	require.Equal(t, monster.ID, data["id"])
+	require.NotNil(t, data["weaknesses"])
+	require.NotNil(t, data["resistances"])
+	require.NotNil(t, data["immunities"])
+}
@@ -0,0 +1,122 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/letenk/pokedex/models/domain"
+	"github.com/letenk/pokedex/models/web"
+	"github.com/letenk/pokedex/repository"
+	"github.com/letenk/pokedex/usecase"
+	"github.com/letenk/pokedex/util"
+	"github.com/stretchr/testify/require"
+)
+
+// Not parallel, so created types are never picked by random monster in other tests
+func TestTypeEffectivenessUsecase(t *testing.T) {
+	repositoryType := repository.NewTypeRespository(ConnTest)
+	repositoryMonster := repository.NewMonsterRespository(ConnTest)
+	repositoryTypeEffectiveness := repository.NewTypeEffectivenessRepository(ConnTest)
+	usecase := usecase.NewUsecaseTypeEffectiveness(repositoryTypeEffectiveness, repositoryType, repositoryMonster, AuditTest)
+	ctx := context.Background()
+
+	// Create types, monster is defender with two types
+	var types []domain.Type
+	for i := 0; i < 4; i++ {
+		newType, err := repositoryType.Create(ctx, domain.Type{Name: strings.ToUpper(util.RandomString(10))})
+		require.NoError(t, err)
+		types = append(types, newType)
+	}
+	defenderA, defenderB, attacker, immune := types[0], types[1], types[2], types[3]
+
+	multiplier := func(m float64) web.TypeEffectivenessRequest {
+		return web.TypeEffectivenessRequest{Multiplier: &m}
+	}
+
+	// Attacker is super effective against both of defender
+	_, err := usecase.Save(ctx, attacker.ID, defenderA.ID, multiplier(2))
+	require.NoError(t, err)
+	_, err = usecase.Save(ctx, attacker.ID, defenderB.ID, multiplier(1))
+	require.NoError(t, err)
+	// Save again update the multiplier
+	effectiveness, err := usecase.Save(ctx, attacker.ID, defenderB.ID, multiplier(2))
+	require.NoError(t, err)
+	require.Equal(t, attacker.Name, effectiveness.AttackerType.Name)
+	require.Equal(t, defenderB.Name, effectiveness.DefenderType.Name)
+	require.Equal(t, float64(2), effectiveness.Multiplier)
+
+	// Defender A resist itself, defender B is immune to immune type
+	_, err = usecase.Save(ctx, defenderA.ID, defenderA.ID, multiplier(0.5))
+	require.NoError(t, err)
+	_, err = usecase.Save(ctx, immune.ID, defenderB.ID, multiplier(0))
+	require.NoError(t, err)
+
+	// Failed type not found
+	_, err = usecase.Save(ctx, attacker.ID, "4562482c-7acd-4daf-901f-d95c7a7afd65", multiplier(2))
+	require.Error(t, err)
+
+	// Matchups
+	matchupType, matchups, err := usecase.Matchups(ctx, defenderA.ID)
+	require.NoError(t, err)
+	response := web.FormatTypeMatchupsResponse(matchupType, matchups)
+	require.Equal(t, 1, len(response.Offense))
+	require.Equal(t, defenderA.Name, response.Offense[0].Name)
+	require.Equal(t, 2, len(response.Defense))
+
+	// Create monster
+	randCategory, _ := RandomCategoryAndType()
+	monster, err := repositoryMonster.Create(ctx, domain.Monster{
+		Name:        util.RandomString(10),
+		CategoryID:  randCategory,
+		Description: util.RandomString(20),
+		Length:      54.3,
+		Weight:      uint16(util.RandomInt(50, 500)),
+		Hp:          uint16(util.RandomInt(50, 500)),
+		Attack:      uint16(util.RandomInt(50, 500)),
+		Defends:     uint16(util.RandomInt(50, 500)),
+		Speed:       uint16(util.RandomInt(50, 500)),
+		ImageName:   util.RandomString(10),
+		ImageURL:    util.RandomString(10),
+		TypeID:      []string{defenderA.ID, defenderB.ID},
+	})
+	require.NoError(t, err)
+
+	// Weaknesses, multipliers of each monster type are multiplied
+	_, weaknesses, err := usecase.Weaknesses(ctx, monster.ID)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(weaknesses.Weaknesses))
+	require.Equal(t, attacker.ID, weaknesses.Weaknesses[0].Type.ID)
+	require.Equal(t, float64(4), weaknesses.Weaknesses[0].Multiplier)
+	require.Equal(t, 1, len(weaknesses.Resistances))
+	require.Equal(t, defenderA.ID, weaknesses.Resistances[0].Type.ID)
+	require.Equal(t, 0.5, weaknesses.Resistances[0].Multiplier)
+	require.Equal(t, 1, len(weaknesses.Immunities))
+	require.Equal(t, immune.ID, weaknesses.Immunities[0].Type.ID)
+	require.Equal(t, float64(0), weaknesses.Immunities[0].Multiplier)
+
+	weaknessesResponse := web.FormatMonsterWeaknessesResponse(monster, weaknesses)
+	require.Equal(t, 1, len(weaknessesResponse.Weaknesses))
+	require.Equal(t, 1, len(weaknessesResponse.Resistances))
+	require.Equal(t, 1, len(weaknessesResponse.Immunities))
+
+	// Delete
+	ok, err := usecase.Delete(ctx, immune.ID, defenderB.ID)
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = usecase.Delete(ctx, immune.ID, defenderB.ID)
+	require.Error(t, err)
+	require.False(t, ok)
+	require.Equal(t, "type effectiveness not found", err.Error())
+
+	// Clean up, matchups are deleted with the types
+	_, err = repositoryMonster.Delete(ctx, monster)
+	require.NoError(t, err)
+	_, err = repositoryMonster.Purge(ctx, monster, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	for _, data := range types {
+		_, err = repositoryType.Delete(ctx, data, true)
+		require.NoError(t, err)
+	}
+}
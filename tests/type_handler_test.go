@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/letenk/pokedex/models/web"
+	"github.com/letenk/pokedex/util"
 	"github.com/stretchr/testify/require"
 )
 
@@ -128,3 +129,77 @@ func TestFindAllTypeHandlerTest(t *testing.T) {
 		})
 	}
 }
+
+// Not parallel, so created types are never picked by random monster in other tests
+func TestTypeHandlerCRUD(t *testing.T) {
+	tokenAdmin := GetToken(web.UserLoginRequest{Username: "admin", Password: "password"})
+	tokenUser := GetToken(web.UserLoginRequest{Username: "user", Password: "password"})
+
+	// Helper for send request
+	send := func(method, target, token, dataBody string) (*http.Response, map[string]interface{}) {
+		request := httptest.NewRequest(method, target, strings.NewReader(dataBody))
+		request.Header.Add("Content-Type", "application/json")
+		request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+		recorder := httptest.NewRecorder()
+		RouteTest.ServeHTTP(recorder, request)
+
+		response := recorder.Result()
+		body, _ := io.ReadAll(response.Body)
+		var responseBody map[string]interface{}
+		json.Unmarshal(body, &responseBody)
+		return response, responseBody
+	}
+
+	name := strings.ToUpper(util.RandomString(10))
+
+	// Forbidden with role user
+	response, responseBody := send(http.MethodPost, "http://localhost:3000/api/v1/type", tokenUser, fmt.Sprintf(`{"name": "%s"}`, name))
+	require.Equal(t, 403, response.StatusCode)
+	require.Equal(t, "forbidden", responseBody["message"])
+
+	// Failed without name
+	response, responseBody = send(http.MethodPost, "http://localhost:3000/api/v1/type", tokenAdmin, `{}`)
+	require.Equal(t, 400, response.StatusCode)
+	require.Equal(t, "create type failed", responseBody["message"])
+
+	// Success create
+	response, responseBody = send(http.MethodPost, "http://localhost:3000/api/v1/type", tokenAdmin, fmt.Sprintf(`{"name": "%s"}`, name))
+	require.Equal(t, 201, response.StatusCode)
+	require.Equal(t, "Type has been created", responseBody["message"])
+	types := responseBody["data"].(map[string]interface{})
+	require.Equal(t, name, types["name"])
+	typeURL := fmt.Sprintf("http://localhost:3000/api/v1/type/%s", types["id"])
+
+	// Failed duplicate name
+	response, responseBody = send(http.MethodPost, "http://localhost:3000/api/v1/type", tokenAdmin, fmt.Sprintf(`{"name": "%s"}`, name))
+	require.Equal(t, 400, response.StatusCode)
+	require.Equal(t, fmt.Sprintf("type with name %s already exists", name), responseBody["data"].(map[string]interface{})["errors"])
+
+	// Rename
+	newName := strings.ToUpper(util.RandomString(10))
+	response, responseBody = send(http.MethodPatch, typeURL, tokenAdmin, fmt.Sprintf(`{"name": "%s"}`, newName))
+	require.Equal(t, 200, response.StatusCode)
+	require.Equal(t, newName, responseBody["data"].(map[string]interface{})["name"])
+
+	// Get by id
+	response, responseBody = send(http.MethodGet, typeURL, tokenAdmin, "")
+	require.Equal(t, 200, response.StatusCode)
+	require.Equal(t, "detail of type", responseBody["message"])
+	require.Equal(t, newName, responseBody["data"].(map[string]interface{})["name"])
+
+	// Failed invalid id
+	response, _ = send(http.MethodGet, "http://localhost:3000/api/v1/type/abc", tokenAdmin, "")
+	require.Equal(t, 400, response.StatusCode)
+
+	// Failed invalid cascade
+	response, _ = send(http.MethodDelete, typeURL+"?cascade=abc", tokenAdmin, "")
+	require.Equal(t, 400, response.StatusCode)
+
+	// Delete
+	response, responseBody = send(http.MethodDelete, typeURL, tokenAdmin, "")
+	require.Equal(t, 200, response.StatusCode)
+	require.Equal(t, "type deleted", responseBody["message"])
+
+	response, _ = send(http.MethodGet, typeURL, tokenAdmin, "")
+	require.Equal(t, 400, response.StatusCode)
+}
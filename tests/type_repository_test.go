@@ -2,9 +2,13 @@ package tests
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/letenk/pokedex/models/domain"
 	"github.com/letenk/pokedex/repository"
+	"github.com/letenk/pokedex/util"
 	"github.com/stretchr/testify/require"
 )
 
@@ -25,3 +29,40 @@ func TestFindAllType(t *testing.T) {
 		require.NotEmpty(t, data.UpdatedAt)
 	}
 }
+
+// Not parallel, so created types are never picked by random monster in other tests
+func TestTypeRepositoryCRUD(t *testing.T) {
+	repository := repository.NewTypeRespository(ConnTest)
+	ctx := context.Background()
+
+	// Create
+	name := strings.ToUpper(util.RandomString(10))
+	types, err := repository.Create(ctx, domain.Type{Name: name})
+	require.NoError(t, err)
+	require.NotEmpty(t, types.ID)
+	require.Equal(t, name, types.Name)
+
+	// Name is unique without case sensitive
+	_, err = repository.Create(ctx, domain.Type{Name: strings.ToLower(name)})
+	require.Error(t, err)
+	require.Equal(t, fmt.Sprintf("type with name %s already exists", strings.ToLower(name)), err.Error())
+
+	// Rename
+	types.Name = strings.ToUpper(util.RandomString(10))
+	typeUpdated, err := repository.Update(ctx, types)
+	require.NoError(t, err)
+
+	// Find by id
+	typeFound, err := repository.FindByID(ctx, types.ID)
+	require.NoError(t, err)
+	require.Equal(t, typeUpdated.Name, typeFound.Name)
+
+	// Delete
+	ok, err := repository.Delete(ctx, types, false)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	_, err = repository.FindByID(ctx, types.ID)
+	require.Error(t, err)
+	require.Equal(t, fmt.Sprintf("type with id %s not found", types.ID), err.Error())
+}
@@ -2,17 +2,22 @@ package tests
 
 import (
 	"context"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/letenk/pokedex/models/domain"
+	"github.com/letenk/pokedex/models/web"
 	"github.com/letenk/pokedex/repository"
 	"github.com/letenk/pokedex/usecase"
+	"github.com/letenk/pokedex/util"
 	"github.com/stretchr/testify/require"
 )
 
 func TestFindAllTypeUsecase(t *testing.T) {
 	t.Parallel()
 	repository := repository.NewTypeRespository(ConnTest)
-	usecase := usecase.NewUsecaseType(repository)
+	usecase := usecase.NewUsecaseType(repository, AuditTest)
 
 	// Find all
 	todos, err := usecase.FindAll(context.Background())
@@ -27,3 +32,57 @@ func TestFindAllTypeUsecase(t *testing.T) {
 		require.NotEmpty(t, data.UpdatedAt)
 	}
 }
+
+// Not parallel, so created types are never picked by random monster in other tests
+func TestDeleteTypeUsecase(t *testing.T) {
+	repositoryType := repository.NewTypeRespository(ConnTest)
+	repositoryMonster := repository.NewMonsterRespository(ConnTest)
+	usecase := usecase.NewUsecaseType(repositoryType, AuditTest)
+	ctx := context.Background()
+
+	// Create type, name is saved as upper case
+	name := util.RandomString(10)
+	types, err := usecase.Create(ctx, web.TypeRequest{Name: " " + name + " "})
+	require.NoError(t, err)
+	require.Equal(t, strings.ToUpper(name), types.Name)
+
+	// Create monster with the type
+	randCategory, randType := RandomCategoryAndType()
+	monster, err := repositoryMonster.Create(ctx, domain.Monster{
+		Name:        util.RandomString(10),
+		CategoryID:  randCategory,
+		Description: util.RandomString(20),
+		Length:      54.3,
+		Weight:      uint16(util.RandomInt(50, 500)),
+		Hp:          uint16(util.RandomInt(50, 500)),
+		Attack:      uint16(util.RandomInt(50, 500)),
+		Defends:     uint16(util.RandomInt(50, 500)),
+		Speed:       uint16(util.RandomInt(50, 500)),
+		ImageName:   util.RandomString(10),
+		ImageURL:    util.RandomString(10),
+		TypeID:      []string{randType, types.ID},
+	})
+	require.NoError(t, err)
+
+	// Refused, type is still used
+	ok, err := usecase.Delete(ctx, types.ID, false)
+	require.Error(t, err)
+	require.False(t, ok)
+	require.Equal(t, "type is still used by 1 monsters, please delete with cascade for remove the type from monsters", err.Error())
+
+	// Success with cascade
+	ok, err = usecase.Delete(ctx, types.ID, true)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	monsterFound, err := repositoryMonster.FindByID(ctx, monster.ID)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(monsterFound.Types))
+	require.Equal(t, randType, monsterFound.Types[0].ID)
+
+	// Clean up
+	_, err = repositoryMonster.Delete(ctx, monsterFound)
+	require.NoError(t, err)
+	_, err = repositoryMonster.Purge(ctx, monsterFound, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+}
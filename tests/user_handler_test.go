@@ -1,15 +1,22 @@
 package tests
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/letenk/pokedex/models/web"
+	"github.com/letenk/pokedex/repository"
+	"github.com/letenk/pokedex/router"
+	"github.com/letenk/pokedex/usecase"
+	"github.com/letenk/pokedex/util"
 	"github.com/stretchr/testify/require"
 )
 
@@ -102,3 +109,284 @@ func TestLoginUserHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestRegisterAndProfileUserHandler(t *testing.T) {
+	t.Parallel()
+
+	// Helper for send request
+	send := func(method, target, token, dataBody string) (*http.Response, map[string]interface{}) {
+		request := httptest.NewRequest(method, target, strings.NewReader(dataBody))
+		request.Header.Add("Content-Type", "application/json")
+		if token != "" {
+			request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+		}
+		recorder := httptest.NewRecorder()
+		RouteTest.ServeHTTP(recorder, request)
+
+		response := recorder.Result()
+		body, _ := io.ReadAll(response.Body)
+		var responseBody map[string]interface{}
+		json.Unmarshal(body, &responseBody)
+		return response, responseBody
+	}
+
+	// Failed validation, password too short
+	username := util.RandomString(10)
+	response, responseBody := send(http.MethodPost, "http://localhost:3000/api/v1/register", "", fmt.Sprintf(`{"fullname": "New User", "username": "%s", "password": "short"}`, username))
+	require.Equal(t, 400, response.StatusCode)
+	require.Equal(t, "register failed", responseBody["message"])
+
+	// Register
+	response, responseBody = send(http.MethodPost, "http://localhost:3000/api/v1/register", "", fmt.Sprintf(`{"fullname": "New User", "username": "%s", "password": "password123"}`, username))
+	require.Equal(t, 201, response.StatusCode)
+	require.Equal(t, "register success", responseBody["message"])
+	data := responseBody["data"].(map[string]interface{})
+	require.Equal(t, username, data["username"])
+	require.Equal(t, "user", data["role"])
+	require.Nil(t, data["password"])
+
+	// Failed register again with the same username
+	response, _ = send(http.MethodPost, "http://localhost:3000/api/v1/register", "", fmt.Sprintf(`{"fullname": "New User", "username": "%s", "password": "password123"}`, username))
+	require.Equal(t, 400, response.StatusCode)
+
+	token := GetToken(web.UserLoginRequest{Username: username, Password: "password123"})
+
+	// Failed me as guest
+	response, _ = send(http.MethodGet, "http://localhost:3000/api/v1/me", "", "")
+	require.Equal(t, 401, response.StatusCode)
+
+	// Me
+	response, responseBody = send(http.MethodGet, "http://localhost:3000/api/v1/me", token, "")
+	require.Equal(t, 200, response.StatusCode)
+	require.Equal(t, username, responseBody["data"].(map[string]interface{})["username"])
+
+	// Update profile
+	response, responseBody = send(http.MethodPatch, "http://localhost:3000/api/v1/me", token, `{"fullname": "Updated User"}`)
+	require.Equal(t, 200, response.StatusCode)
+	require.Equal(t, "Updated User", responseBody["data"].(map[string]interface{})["fullname"])
+
+	// Failed change password with wrong old password
+	response, responseBody = send(http.MethodPatch, "http://localhost:3000/api/v1/me/password", token, `{"old_password": "wrong", "new_password": "newpassword123"}`)
+	require.Equal(t, 400, response.StatusCode)
+	require.Equal(t, "old password incorrect", responseBody["data"].(map[string]interface{})["errors"])
+
+	// Change password
+	response, responseBody = send(http.MethodPatch, "http://localhost:3000/api/v1/me/password", token, `{"old_password": "password123", "new_password": "newpassword123"}`)
+	require.Equal(t, 200, response.StatusCode)
+	require.Equal(t, "change password success", responseBody["message"])
+}
+
+func TestManageUserHandler(t *testing.T) {
+	t.Parallel()
+
+	// Helper for send request
+	send := func(method, target, token, dataBody string) (*http.Response, map[string]interface{}) {
+		request := httptest.NewRequest(method, target, strings.NewReader(dataBody))
+		request.Header.Add("Content-Type", "application/json")
+		if token != "" {
+			request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+		}
+		recorder := httptest.NewRecorder()
+		RouteTest.ServeHTTP(recorder, request)
+
+		response := recorder.Result()
+		body, _ := io.ReadAll(response.Body)
+		var responseBody map[string]interface{}
+		json.Unmarshal(body, &responseBody)
+		return response, responseBody
+	}
+
+	tokenAdmin := GetToken(web.UserLoginRequest{Username: "admin", Password: "password"})
+	tokenUser := GetToken(web.UserLoginRequest{Username: "user", Password: "password"})
+
+	// Failed user without permission
+	response, _ := send(http.MethodGet, "http://localhost:3000/api/v1/users", tokenUser, "")
+	require.Equal(t, 403, response.StatusCode)
+
+	// Create user
+	username := util.RandomString(10)
+	response, responseBody := send(http.MethodPost, "http://localhost:3000/api/v1/users", tokenAdmin, fmt.Sprintf(`{"fullname": "Managed User", "username": "%s", "password": "password123", "role": "user"}`, username))
+	require.Equal(t, 201, response.StatusCode)
+	data := responseBody["data"].(map[string]interface{})
+	require.Equal(t, username, data["username"])
+	require.Equal(t, true, data["active"])
+	require.Nil(t, data["password"])
+	userID := data["id"].(string)
+
+	// Search
+	response, responseBody = send(http.MethodGet, fmt.Sprintf("http://localhost:3000/api/v1/users?search=%s", username), tokenAdmin, "")
+	require.Equal(t, 200, response.StatusCode)
+	require.Equal(t, "list of users", responseBody["message"])
+	require.Len(t, responseBody["data"], 1)
+
+	// Failed change role does not exist
+	response, _ = send(http.MethodPatch, fmt.Sprintf("http://localhost:3000/api/v1/users/%s/role", userID), tokenAdmin, `{"role": "unknown"}`)
+	require.Equal(t, 400, response.StatusCode)
+
+	// Login as the new user
+	tokenManaged := GetToken(web.UserLoginRequest{Username: username, Password: "password123"})
+	response, _ = send(http.MethodGet, "http://localhost:3000/api/v1/me", tokenManaged, "")
+	require.Equal(t, 200, response.StatusCode)
+
+	// Deactivate
+	response, responseBody = send(http.MethodPost, fmt.Sprintf("http://localhost:3000/api/v1/users/%s/deactivate", userID), tokenAdmin, "")
+	require.Equal(t, 200, response.StatusCode)
+	data = responseBody["data"].(map[string]interface{})
+	require.Equal(t, false, data["active"])
+
+	// Token of deactivated user is rejected
+	response, _ = send(http.MethodGet, "http://localhost:3000/api/v1/me", tokenManaged, "")
+	require.Equal(t, 401, response.StatusCode)
+
+	// Activate and reset password
+	response, _ = send(http.MethodPost, fmt.Sprintf("http://localhost:3000/api/v1/users/%s/activate", userID), tokenAdmin, "")
+	require.Equal(t, 200, response.StatusCode)
+	response, _ = send(http.MethodPatch, fmt.Sprintf("http://localhost:3000/api/v1/users/%s/password", userID), tokenAdmin, `{"password": "newpassword123"}`)
+	require.Equal(t, 200, response.StatusCode)
+
+	response, _ = send(http.MethodPost, "http://localhost:3000/api/v1/login", "", fmt.Sprintf(`{"username": "%s", "password": "newpassword123"}`, username))
+	require.Equal(t, 200, response.StatusCode)
+}
+
+func TestLoginLockedUserHandler(t *testing.T) {
+	t.Parallel()
+
+	// Locked after two failures
+	config := ConfigTest
+	config.LOGIN_MAX_ATTEMPTS = 2
+	route := router.SetupRouter(ConnTest, config, usecase.NewMemoryCache(0))
+
+	user := RandomCreateUser(t, "user")
+
+	// Helper for send login
+	login := func(password string) *http.Response {
+		dataBody := fmt.Sprintf(`{"username": "%s", "password": "%s"}`, user.Username, password)
+		request := httptest.NewRequest(http.MethodPost, "http://localhost:3000/api/v1/login", strings.NewReader(dataBody))
+		request.Header.Add("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		route.ServeHTTP(recorder, request)
+		return recorder.Result()
+	}
+
+	for i := 0; i < 2; i++ {
+		response := login("wrong")
+		require.Equal(t, 400, response.StatusCode)
+	}
+
+	// Locked even with correct password
+	response := login("password")
+	require.Equal(t, 429, response.StatusCode)
+	retryAfter, err := strconv.Atoi(response.Header.Get("Retry-After"))
+	require.NoError(t, err)
+	require.Greater(t, retryAfter, 0)
+
+	// Admin unlock
+	tokenAdmin := GetToken(web.UserLoginRequest{Username: "admin", Password: "password"})
+	request := httptest.NewRequest(http.MethodPost, fmt.Sprintf("http://localhost:3000/api/v1/users/%s/unlock", user.ID), nil)
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", tokenAdmin))
+	recorder := httptest.NewRecorder()
+	RouteTest.ServeHTTP(recorder, request)
+	require.Equal(t, 200, recorder.Result().StatusCode)
+
+	response = login("password")
+	require.Equal(t, 200, response.StatusCode)
+}
+
+func TestLoginClientIPUserHandler(t *testing.T) {
+	t.Parallel()
+	repositoryLoginAttempt := repository.NewLoginAttemptRepository(ConnTest)
+
+	// Random ip for remote address, proxy and forwarded client
+	randomIP := func() string {
+		return fmt.Sprintf("10.%d.%d.%d", util.RandomInt(0, 255), util.RandomInt(0, 255), util.RandomInt(1, 254))
+	}
+
+	// Helper for send failed login through route, return ip counted by the login
+	login := func(route http.Handler, remoteIP string, forwardedIP string) []string {
+		dataBody := fmt.Sprintf(`{"username": "%s", "password": "wrong"}`, util.RandomString(10))
+		request := httptest.NewRequest(http.MethodPost, "http://localhost:3000/api/v1/login", strings.NewReader(dataBody))
+		request.Header.Add("Content-Type", "application/json")
+		request.Header.Add("X-Forwarded-For", forwardedIP)
+		request.RemoteAddr = remoteIP + ":1234"
+		recorder := httptest.NewRecorder()
+		route.ServeHTTP(recorder, request)
+		require.Equal(t, 400, recorder.Result().StatusCode)
+
+		attempts, err := repositoryLoginAttempt.FindByKeys(context.Background(), []string{"ip:" + remoteIP, "ip:" + forwardedIP})
+		require.NoError(t, err)
+
+		var keys []string
+		for _, attempt := range attempts {
+			keys = append(keys, attempt.Key)
+		}
+		return keys
+	}
+
+	t.Run("spoofed_header_is_ignored", func(t *testing.T) {
+		remoteIP, spoofedIP := randomIP(), randomIP()
+		keys := login(RouteTest, remoteIP, spoofedIP)
+		require.Equal(t, []string{"ip:" + remoteIP}, keys)
+	})
+
+	t.Run("header_of_trusted_proxy", func(t *testing.T) {
+		proxyIP, clientIP := randomIP(), randomIP()
+		config := ConfigTest
+		config.TRUSTED_PROXIES = []string{proxyIP}
+		route := router.SetupRouter(ConnTest, config, usecase.NewMemoryCache(0))
+
+		keys := login(route, proxyIP, clientIP)
+		require.Equal(t, []string{"ip:" + clientIP}, keys)
+	})
+}
+
+func TestLoginMFAUserHandler(t *testing.T) {
+	t.Parallel()
+
+	// Admin must login with TOTP
+	config := ConfigTest
+	config.MFA_REQUIRED_ROLES = []string{"admin"}
+	route := router.SetupRouter(ConnTest, config, usecase.NewMemoryCache(0))
+
+	// Helper for send request
+	send := func(target, dataBody string) (*http.Response, map[string]interface{}) {
+		request := httptest.NewRequest(http.MethodPost, target, strings.NewReader(dataBody))
+		request.Header.Add("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		route.ServeHTTP(recorder, request)
+
+		response := recorder.Result()
+		body, _ := io.ReadAll(response.Body)
+		var responseBody map[string]interface{}
+		json.Unmarshal(body, &responseBody)
+		return response, responseBody
+	}
+
+	admin := RandomCreateUser(t, "admin")
+
+	// Login return mfa token
+	response, responseBody := send("http://localhost:3000/api/v1/login", fmt.Sprintf(`{"username": "%s", "password": "password"}`, admin.Username))
+	require.Equal(t, 200, response.StatusCode)
+	require.Equal(t, "mfa required", responseBody["message"])
+	data := responseBody["data"].(map[string]interface{})
+	require.Nil(t, data["token"])
+	require.Equal(t, true, data["enrollment_required"])
+	mfaToken := data["mfa_token"].(string)
+
+	// Enroll
+	response, responseBody = send("http://localhost:3000/api/v1/login/mfa/enroll", fmt.Sprintf(`{"mfa_token": "%s"}`, mfaToken))
+	require.Equal(t, 200, response.StatusCode)
+	secret := responseBody["data"].(map[string]interface{})["secret"].(string)
+
+	// Failed wrong code
+	response, _ = send("http://localhost:3000/api/v1/login/mfa", fmt.Sprintf(`{"mfa_token": "%s", "code": "abc"}`, mfaToken))
+	require.Equal(t, 401, response.StatusCode)
+
+	// Exchange with code
+	code, err := util.TOTPCode(secret, util.TOTPStep(time.Now()))
+	require.NoError(t, err)
+	response, responseBody = send("http://localhost:3000/api/v1/login/mfa", fmt.Sprintf(`{"mfa_token": "%s", "code": "%s"}`, mfaToken, code))
+	require.Equal(t, 200, response.StatusCode)
+	data = responseBody["data"].(map[string]interface{})
+	require.NotEmpty(t, data["token"])
+	require.Len(t, data["recovery_codes"], 10)
+}
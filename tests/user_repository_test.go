@@ -1,16 +1,37 @@
 package tests
 
 import (
+	"database/sql"
 	"testing"
+	"time"
 
 	"github.com/letenk/pokedex/models/domain"
 	"github.com/letenk/pokedex/repository"
+	"github.com/letenk/pokedex/util"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
 	"golang.org/x/net/context"
 )
 
+// RandomCreateUser create user with role, the password is `password`
+func RandomCreateUser(t *testing.T, role string) domain.User {
+	repository := repository.NewUserRepository(ConnTest)
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	user, err := repository.Create(context.Background(), domain.User{
+		Fullname: util.RandomString(10),
+		Username: util.RandomString(10),
+		Password: string(passwordHash),
+		Role:     role,
+	})
+	require.NoError(t, err)
+
+	return user
+}
+
 func TestFindByUsername(t *testing.T) {
 	repository := repository.NewUserRepository(ConnTest)
 	userRoleAdmin, _ := repository.FindByUsername(context.Background(), "admin")
@@ -120,3 +141,54 @@ func TestFindByID(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdateKeepAdmin(t *testing.T) {
+	ctx := context.Background()
+	admin := RandomCreateUser(t, "admin")
+	user := RandomCreateUser(t, "user")
+
+	// Other admins are deactivated inside a transaction which is rolled back, snapshot of repeatable read
+	// doesn't see admins created by parallel tests
+	tx := ConnTest.Begin(&sql.TxOptions{Isolation: sql.LevelRepeatableRead})
+	defer tx.Rollback()
+	err := tx.Exec(`
+		UPDATE users SET deactivated_at = now()
+		WHERE deactivated_at IS NULL AND id <> ? AND role IN (SELECT role FROM role_permissions WHERE permission = ?)
+	`, admin.ID, domain.PermissionUserManage).Error
+	require.NoError(t, err)
+	repository := repository.NewUserRepository(tx)
+
+	// Last admin can't lose role or be deactivated
+	changed := admin
+	changed.Role = "user"
+	_, err = repository.UpdateKeepAdmin(ctx, changed)
+	require.ErrorIs(t, err, domain.ErrLastAdmin)
+
+	now := time.Now()
+	changed = admin
+	changed.DeactivatedAt = &now
+	_, err = repository.UpdateKeepAdmin(ctx, changed)
+	require.ErrorIs(t, err, domain.ErrLastAdmin)
+
+	// Other update of the last admin and update of other user are allowed
+	changed = admin
+	changed.Fullname = util.RandomString(10)
+	_, err = repository.UpdateKeepAdmin(ctx, changed)
+	require.NoError(t, err)
+
+	changed = user
+	changed.DeactivatedAt = &now
+	_, err = repository.UpdateKeepAdmin(ctx, changed)
+	require.NoError(t, err)
+
+	// Other admin takes over, then the role can be changed
+	promoted := user
+	promoted.Role = "admin"
+	_, err = repository.UpdateKeepAdmin(ctx, promoted)
+	require.NoError(t, err)
+
+	changed = admin
+	changed.Role = "user"
+	_, err = repository.UpdateKeepAdmin(ctx, changed)
+	require.NoError(t, err)
+}
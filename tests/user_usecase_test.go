@@ -1,20 +1,27 @@
 package tests
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/letenk/pokedex/models/domain"
 	"github.com/letenk/pokedex/models/web"
 	"github.com/letenk/pokedex/repository"
 	"github.com/letenk/pokedex/usecase"
+	"github.com/letenk/pokedex/util"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
 	"golang.org/x/net/context"
 )
 
 func TestLogin(t *testing.T) {
+	repositoryToken := repository.NewTokenRepository(ConnTest)
+	repositoryPermission := repository.NewPermissionRepository(ConnTest)
+	repositoryLoginAttempt := repository.NewLoginAttemptRepository(ConnTest)
+	repositoryMFA := repository.NewMFARepository(ConnTest)
 	repository := repository.NewUserRepository(ConnTest)
-	usecase := usecase.NewUsecaseUser(repository)
+	usecase := usecase.NewUsecaseUser(repository, repositoryToken, repositoryPermission, repositoryLoginAttempt, repositoryMFA, AuditTest, KeySetTest, ConfigTest)
 
 	testCases := []struct {
 		name string
@@ -53,10 +60,11 @@ func TestLogin(t *testing.T) {
 			token, err := usecase.Login(ctx, tc.req)
 
 			if tc.name == "success_login" {
-				require.NotEmpty(t, token)
+				require.NotEmpty(t, token.AccessToken)
+				require.NotEmpty(t, token.RefreshToken)
 				require.NoError(t, err)
 			} else {
-				require.Empty(t, token)
+				require.Empty(t, token.AccessToken)
 				require.Error(t, err)
 				require.Equal(t, "username or password incorrect", err.Error())
 			}
@@ -65,8 +73,12 @@ func TestLogin(t *testing.T) {
 }
 
 func TestFindOneByID(t *testing.T) {
+	repositoryToken := repository.NewTokenRepository(ConnTest)
+	repositoryPermission := repository.NewPermissionRepository(ConnTest)
+	repositoryLoginAttempt := repository.NewLoginAttemptRepository(ConnTest)
+	repositoryMFA := repository.NewMFARepository(ConnTest)
 	repository := repository.NewUserRepository(ConnTest)
-	usecase := usecase.NewUsecaseUser(repository)
+	usecase := usecase.NewUsecaseUser(repository, repositoryToken, repositoryPermission, repositoryLoginAttempt, repositoryMFA, AuditTest, KeySetTest, ConfigTest)
 
 	userRoleAdmin, _ := repository.FindByUsername(context.Background(), "admin")
 	userRoleUser, _ := repository.FindByUsername(context.Background(), "user")
@@ -120,3 +132,153 @@ func TestFindOneByID(t *testing.T) {
 		})
 	}
 }
+
+func TestRegisterAndChangePasswordUsecase(t *testing.T) {
+	t.Parallel()
+	repositoryToken := repository.NewTokenRepository(ConnTest)
+	repositoryPermission := repository.NewPermissionRepository(ConnTest)
+	repositoryLoginAttempt := repository.NewLoginAttemptRepository(ConnTest)
+	repositoryMFA := repository.NewMFARepository(ConnTest)
+	repository := repository.NewUserRepository(ConnTest)
+	usecase := usecase.NewUsecaseUser(repository, repositoryToken, repositoryPermission, repositoryLoginAttempt, repositoryMFA, AuditTest, KeySetTest, ConfigTest)
+	ctx := context.Background()
+
+	// Register
+	username := util.RandomString(10)
+	user, err := usecase.Register(ctx, web.UserRegisterRequest{
+		Fullname: "New User",
+		Username: username,
+		Password: "password123",
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, user.ID)
+	require.Equal(t, "user", user.Role)
+	require.NotEqual(t, "password123", user.Password)
+
+	// Failed username already exists, without case sensitive
+	_, err = usecase.Register(ctx, web.UserRegisterRequest{
+		Fullname: "Other User",
+		Username: strings.ToUpper(username),
+		Password: "password123",
+	})
+	require.Error(t, err)
+	require.Equal(t, fmt.Sprintf("username %s already exists", strings.ToUpper(username)), err.Error())
+
+	// Login with new user
+	token, err := usecase.Login(ctx, web.UserLoginRequest{Username: username, Password: "password123"})
+	require.NoError(t, err)
+	require.NotEmpty(t, token.AccessToken)
+
+	// Update profile
+	userUpdated, err := usecase.UpdateProfile(ctx, user.ID, web.UserUpdateRequest{Fullname: "Updated User"})
+	require.NoError(t, err)
+	require.Equal(t, "Updated User", userUpdated.Fullname)
+	require.Equal(t, username, userUpdated.Username)
+
+	// Failed old password wrong
+	ok, err := usecase.ChangePassword(ctx, user.ID, web.UserChangePasswordRequest{OldPassword: "wrong", NewPassword: "newpassword123"})
+	require.Error(t, err)
+	require.False(t, ok)
+	require.Equal(t, "old password incorrect", err.Error())
+
+	// Change password
+	ok, err = usecase.ChangePassword(ctx, user.ID, web.UserChangePasswordRequest{OldPassword: "password123", NewPassword: "newpassword123"})
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// Refresh token of session before the change is revoked
+	_, err = usecase.RefreshToken(ctx, token.RefreshToken)
+	require.Error(t, err)
+
+	_, err = usecase.Login(ctx, web.UserLoginRequest{Username: username, Password: "password123"})
+	require.Error(t, err)
+	token, err = usecase.Login(ctx, web.UserLoginRequest{Username: username, Password: "newpassword123"})
+	require.NoError(t, err)
+	require.NotEmpty(t, token.AccessToken)
+}
+
+func TestManageUserUsecase(t *testing.T) {
+	t.Parallel()
+	repositoryToken := repository.NewTokenRepository(ConnTest)
+	repositoryPermission := repository.NewPermissionRepository(ConnTest)
+	repositoryLoginAttempt := repository.NewLoginAttemptRepository(ConnTest)
+	repositoryMFA := repository.NewMFARepository(ConnTest)
+	repository := repository.NewUserRepository(ConnTest)
+	usecase := usecase.NewUsecaseUser(repository, repositoryToken, repositoryPermission, repositoryLoginAttempt, repositoryMFA, AuditTest, KeySetTest, ConfigTest)
+	ctx := context.Background()
+
+	// Failed role does not exist
+	username := util.RandomString(10)
+	_, err := usecase.Create(ctx, web.UserCreateRequest{
+		Fullname: "Managed User",
+		Username: username,
+		Password: "password123",
+		Role:     "unknown",
+	})
+	require.Error(t, err)
+	require.Equal(t, "role unknown does not exist", err.Error())
+
+	// Create
+	user, err := usecase.Create(ctx, web.UserCreateRequest{
+		Fullname: "Managed User",
+		Username: username,
+		Password: "password123",
+		Role:     "user",
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, user.ID)
+	require.Equal(t, "user", user.Role)
+	require.Nil(t, user.DeactivatedAt)
+
+	// Search by username
+	users, pagination, err := usecase.FindAll(ctx, web.UserQueryRequest{Search: strings.ToUpper(username)})
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	require.Equal(t, user.ID, users[0].ID)
+	require.Equal(t, int64(1), pagination.Total)
+
+	// Change role
+	user, err = usecase.ChangeRole(ctx, user.ID, web.UserRoleRequest{Role: "admin"})
+	require.NoError(t, err)
+	require.Equal(t, "admin", user.Role)
+
+	// Reset password
+	ok, err := usecase.ResetPassword(ctx, user.ID, web.UserResetPasswordRequest{Password: "newpassword123"})
+	require.NoError(t, err)
+	require.True(t, ok)
+	token, err := usecase.Login(ctx, web.UserLoginRequest{Username: username, Password: "newpassword123"})
+	require.NoError(t, err)
+
+	// Failed deactivate own account
+	_, err = usecase.Deactivate(ctx, user.ID, user.ID)
+	require.Error(t, err)
+	require.Equal(t, "can not deactivate your own account", err.Error())
+
+	// Deactivate
+	user, err = usecase.Deactivate(ctx, user.ID, util.RandomString(10))
+	require.NoError(t, err)
+	require.NotNil(t, user.DeactivatedAt)
+
+	// Deactivated user can not login, access token and refresh token are rejected
+	_, err = usecase.Login(ctx, web.UserLoginRequest{Username: username, Password: "newpassword123"})
+	require.Error(t, err)
+	require.Equal(t, "user is deactivated", err.Error())
+	_, _, err = usecase.Authenticate(ctx, token.AccessToken)
+	require.Error(t, err)
+	require.Equal(t, "user is deactivated", err.Error())
+	_, err = usecase.RefreshToken(ctx, token.RefreshToken)
+	require.Error(t, err)
+
+	// Filter by active
+	active := false
+	users, _, err = usecase.FindAll(ctx, web.UserQueryRequest{Search: username, Active: &active})
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+
+	// Activate
+	user, err = usecase.Activate(ctx, user.ID)
+	require.NoError(t, err)
+	require.Nil(t, user.DeactivatedAt)
+	_, err = usecase.Login(ctx, web.UserLoginRequest{Username: username, Password: "newpassword123"})
+	require.NoError(t, err)
+}
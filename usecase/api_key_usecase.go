@@ -0,0 +1,183 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/letenk/pokedex/models/domain"
+	"github.com/letenk/pokedex/models/web"
+	"github.com/letenk/pokedex/repository"
+)
+
+const (
+	// Prefix of every api key, so leaked key is easy to recognize
+	apiKeyPrefix = "pdx_"
+	// Length of key saved as prefix, for recognize the key on list
+	apiKeyPrefixLength = 12
+	// Lifetime of api key when expires_in_days is empty
+	defaultAPIKeyDays = 90
+)
+
+type APIKeyUsecase interface {
+	FindAll(ctx context.Context, userID string) ([]domain.APIKey, error)
+	Create(ctx context.Context, user domain.User, req web.APIKeyCreateRequest) (domain.APIKey, string, error)
+	Revoke(ctx context.Context, userID string, id string) (domain.APIKey, error)
+	Authenticate(ctx context.Context, key string) (domain.User, domain.APIKey, error)
+}
+
+type apiKeyUsecase struct {
+	repository           repository.APIKeyRepository
+	userRepository       repository.UserRepository
+	permissionRepository repository.PermissionRepository
+	audit                AuditUsecase
+}
+
+func NewUsecaseAPIKey(repository repository.APIKeyRepository, userRepository repository.UserRepository, permissionRepository repository.PermissionRepository, audit AuditUsecase) *apiKeyUsecase {
+	return &apiKeyUsecase{repository, userRepository, permissionRepository, audit}
+}
+
+func (u *apiKeyUsecase) FindAll(ctx context.Context, userID string) ([]domain.APIKey, error) {
+	// Find all api keys of user
+	apiKeys, err := u.repository.FindByUserID(ctx, userID)
+	if err != nil {
+		return apiKeys, err
+	}
+
+	return apiKeys, nil
+}
+
+// Create return the new api key and the plain key, the plain key is never saved so only shown once
+func (u *apiKeyUsecase) Create(ctx context.Context, user domain.User, req web.APIKeyCreateRequest) (domain.APIKey, string, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return domain.APIKey{}, "", errors.New("name can not be empty")
+	}
+
+	// Scopes must be owned by role of the user
+	permissions, err := u.permissionRepository.FindByRole(ctx, user.Role)
+	if err != nil {
+		return domain.APIKey{}, "", err
+	}
+
+	owned := make(map[string]bool, len(permissions))
+	for _, data := range permissions {
+		owned[data.Permission] = true
+	}
+
+	var scopes []string
+	for _, scope := range req.Scopes {
+		if !owned[scope] {
+			return domain.APIKey{}, "", fmt.Errorf("scope %s is not permitted", scope)
+		}
+		scopes = append(scopes, scope)
+	}
+
+	days := req.ExpiresInDays
+	if days == 0 {
+		days = defaultAPIKeyDays
+	}
+
+	// Generate key
+	secret, err := randomToken(32)
+	if err != nil {
+		return domain.APIKey{}, "", err
+	}
+	key := apiKeyPrefix + secret
+
+	apiKey := domain.APIKey{
+		UserID:    user.ID,
+		Name:      name,
+		Prefix:    key[:apiKeyPrefixLength],
+		KeyHash:   hashToken(key),
+		Scopes:    strings.Join(scopes, " "),
+		ExpiresAt: time.Now().AddDate(0, 0, days),
+	}
+
+	// Create
+	apiKey, err = u.repository.Create(ctx, apiKey)
+	if err != nil {
+		return apiKey, "", err
+	}
+
+	u.audit.Record(ctx, domain.AuditActionCreate, domain.AuditEntityAPIKey, apiKey.ID, nil, apiKey)
+
+	return apiKey, key, nil
+}
+
+func (u *apiKeyUsecase) Revoke(ctx context.Context, userID string, id string) (domain.APIKey, error) {
+	// Find by id, key of other user is not found
+	apiKey, err := u.repository.FindByID(ctx, id)
+	if err != nil {
+		return apiKey, err
+	}
+
+	if apiKey.ID == "" || apiKey.UserID != userID {
+		return domain.APIKey{}, fmt.Errorf("api key with id %s not found", id)
+	}
+
+	if apiKey.RevokedAt != nil {
+		return apiKey, nil
+	}
+
+	// Revoke
+	before := apiKey
+	apiKey, err = u.repository.Revoke(ctx, apiKey)
+	if err != nil {
+		return apiKey, err
+	}
+
+	u.audit.Record(ctx, domain.AuditActionRevoke, domain.AuditEntityAPIKey, apiKey.ID, before, apiKey)
+
+	return apiKey, nil
+}
+
+func (u *apiKeyUsecase) Authenticate(ctx context.Context, key string) (domain.User, domain.APIKey, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return domain.User{}, domain.APIKey{}, errors.New("invalid api key")
+	}
+
+	// Find by hash
+	apiKey, err := u.repository.FindByHash(ctx, hashToken(key))
+	if err != nil {
+		return domain.User{}, apiKey, err
+	}
+
+	if apiKey.ID == "" {
+		return domain.User{}, apiKey, errors.New("invalid api key")
+	}
+
+	if apiKey.RevokedAt != nil {
+		return domain.User{}, apiKey, errors.New("api key has been revoked")
+	}
+
+	now := time.Now()
+	if now.After(apiKey.ExpiresAt) {
+		return domain.User{}, apiKey, errors.New("api key has expired")
+	}
+
+	// Find owner of key
+	user, err := u.userRepository.FindByID(ctx, apiKey.UserID)
+	if err != nil {
+		return user, apiKey, err
+	}
+
+	if user.ID == "" {
+		return user, apiKey, errors.New("invalid api key")
+	}
+
+	if user.DeactivatedAt != nil {
+		return domain.User{}, apiKey, errors.New("user is deactivated")
+	}
+
+	// Track last used
+	err = u.repository.UpdateLastUsed(ctx, apiKey.ID, now)
+	if err != nil {
+		return user, apiKey, err
+	}
+	apiKey.LastUsedAt = &now
+
+	return user, apiKey, nil
+}
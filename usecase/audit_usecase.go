@@ -0,0 +1,153 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"reflect"
+
+	"github.com/letenk/pokedex/models/domain"
+	"github.com/letenk/pokedex/models/web"
+	"github.com/letenk/pokedex/repository"
+)
+
+type AuditUsecase interface {
+	Record(ctx context.Context, action string, entity string, entityID string, before interface{}, after interface{})
+	FindAll(ctx context.Context, reqQuery web.AuditQueryRequest) ([]domain.AuditLog, web.Pagination, error)
+}
+
+type auditUsecase struct {
+	repository repository.AuditRepository
+}
+
+func NewUsecaseAudit(repository repository.AuditRepository) *auditUsecase {
+	return &auditUsecase{repository}
+}
+
+type auditActorKey struct{}
+
+type auditRequestIDKey struct{}
+
+// ContextWithAuditActor set user who does the write, the user is saved as actor of audit log
+func ContextWithAuditActor(ctx context.Context, user domain.User) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, user)
+}
+
+// ContextWithRequestID set id of request, the id is saved in audit log for trace the request
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, auditRequestIDKey{}, requestID)
+}
+
+// Record save audit log of the write, failed record is only logged so the write is not reverted.
+// Before is nil on create and after is nil on delete.
+func (u *auditUsecase) Record(ctx context.Context, action string, entity string, entityID string, before interface{}, after interface{}) {
+	auditLog := domain.AuditLog{
+		Action:   action,
+		Entity:   entity,
+		EntityID: entityID,
+		Changes:  auditDiff(before, after),
+	}
+
+	if actor, ok := ctx.Value(auditActorKey{}).(domain.User); ok {
+		auditLog.ActorID = actor.ID
+		auditLog.ActorUsername = actor.Username
+	}
+	if requestID, ok := ctx.Value(auditRequestIDKey{}).(string); ok {
+		auditLog.RequestID = requestID
+	}
+
+	_, err := u.repository.Create(ctx, auditLog)
+	if err != nil {
+		log.Println("cannot record audit log:", err)
+	}
+}
+
+func (u *auditUsecase) FindAll(ctx context.Context, reqQuery web.AuditQueryRequest) ([]domain.AuditLog, web.Pagination, error) {
+	// Find all
+	auditLogs, pagination, err := u.repository.FindAll(ctx, reqQuery)
+	if err != nil {
+		return auditLogs, pagination, err
+	}
+
+	return auditLogs, pagination, nil
+}
+
+// Fields which are never saved in audit log, secret or changed on every write
+var auditIgnoredFields = map[string]bool{
+	"Password":    true,
+	"MFASecret":   true,
+	"MFALastStep": true,
+	"KeyHash":     true,
+	"CreatedAt":   true,
+	"UpdatedAt":   true,
+	"LastUsedAt":  true,
+	"Catched":     true,
+	"Version":     true,
+	"DeletedAt":   true,
+}
+
+// auditDiff return fields which are different between before and after
+func auditDiff(before interface{}, after interface{}) domain.AuditChanges {
+	beforeFields := auditSnapshot(before)
+	afterFields := auditSnapshot(after)
+
+	changes := domain.AuditChanges{}
+	for name, value := range beforeFields {
+		if afterValue, ok := afterFields[name]; !ok || !reflect.DeepEqual(value, afterValue) {
+			changes[name] = domain.AuditChange{Before: value, After: afterFields[name]}
+		}
+	}
+	for name, value := range afterFields {
+		if _, ok := beforeFields[name]; !ok {
+			changes[name] = domain.AuditChange{After: value}
+		}
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+	return changes
+}
+
+// auditSnapshot return fields of entity by name, relations and empty values are skipped
+func auditSnapshot(entity interface{}) map[string]interface{} {
+	if entity == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(entity)
+	if err != nil {
+		return nil
+	}
+
+	var fields map[string]interface{}
+	if json.Unmarshal(data, &fields) != nil {
+		return nil
+	}
+
+	for name, value := range fields {
+		if auditIgnoredFields[name] || isAuditSkipped(value) {
+			delete(fields, name)
+		}
+	}
+
+	return fields
+}
+
+// isAuditSkipped check value is empty, other entity or list of entities
+func isAuditSkipped(value interface{}) bool {
+	switch value := value.(type) {
+	case nil, map[string]interface{}:
+		return true
+	case []interface{}:
+		if len(value) == 0 {
+			return true
+		}
+		for _, item := range value {
+			if _, ok := item.(map[string]interface{}); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jellydator/ttlcache/v2"
+	"github.com/letenk/pokedex/util"
+)
+
+// Max of keys in memory cache when config CACHE_SIZE_LIMIT is not set,
+// key which expires first is removed when the cache is full
+const defaultMemoryCacheSizeLimit = 10000
+
+// ErrCacheMiss is returned by Get when key is not cached or expired
+var ErrCacheMiss = errors.New("cache: key not found")
+
+// Cache is the storage of cached responses, value is encoded by the caller
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+	DeletePrefix(ctx context.Context, prefix string) error
+}
+
+// NewCache create cache selected by config CACHE_DRIVER, default is memory
+func NewCache(config util.Config) (Cache, error) {
+	switch config.CACHE_DRIVER {
+	case "", "memory":
+		return NewMemoryCache(config.CACHE_SIZE_LIMIT), nil
+	case "redis":
+		return NewRedisCache(config.REDIS_ADDR, config.REDIS_PASSWORD, config.REDIS_DB, config.REDIS_KEY_PREFIX), nil
+	default:
+		return nil, fmt.Errorf("unknown cache driver %s", config.CACHE_DRIVER)
+	}
+}
+
+// memoryCache keep values in the process, every instance has own copy
+type memoryCache struct {
+	cache *ttlcache.Cache
+}
+
+// NewMemoryCache create memory cache with max of sizeLimit keys, zero is default limit
+func NewMemoryCache(sizeLimit int) *memoryCache {
+	if sizeLimit <= 0 {
+		sizeLimit = defaultMemoryCacheSizeLimit
+	}
+
+	cache := ttlcache.NewCache()
+	// Expire on time to live of set like redis, read doesn't extend it
+	cache.SkipTTLExtensionOnHit(true)
+	cache.SetCacheSizeLimit(sizeLimit)
+	return &memoryCache{cache}
+}
+
+func (c *memoryCache) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := c.cache.Get(key)
+	if err == ttlcache.ErrNotFound {
+		return nil, ErrCacheMiss
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return value.([]byte), nil
+}
+
+func (c *memoryCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	// Copy, so value can be reused by the caller
+	return c.cache.SetWithTTL(key, append([]byte(nil), value...), ttl)
+}
+
+func (c *memoryCache) Delete(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		err := c.cache.Remove(key)
+		if err != nil && err != ttlcache.ErrNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *memoryCache) DeletePrefix(ctx context.Context, prefix string) error {
+	var keys []string
+	for _, key := range c.cache.GetKeys() {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return c.Delete(ctx, keys...)
+}
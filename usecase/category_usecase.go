@@ -2,21 +2,30 @@ package usecase
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/letenk/pokedex/models/domain"
+	"github.com/letenk/pokedex/models/web"
 	"github.com/letenk/pokedex/repository"
 )
 
 type CategoryUsecase interface {
 	FindAll(ctx context.Context) ([]domain.Category, error)
+	FindByID(ctx context.Context, ID string) (domain.Category, error)
+	Create(ctx context.Context, req web.CategoryRequest) (domain.Category, error)
+	Update(ctx context.Context, ID string, req web.CategoryRequest) (domain.Category, error)
+	Delete(ctx context.Context, ID string, reassignTo string) (bool, error)
 }
 
 type categoryUsecase struct {
 	repository repository.CategoryRepository
+	audit      AuditUsecase
 }
 
-func NewUsecaseCategory(repository repository.CategoryRepository) *categoryUsecase {
-	return &categoryUsecase{repository}
+func NewUsecaseCategory(repository repository.CategoryRepository, audit AuditUsecase) *categoryUsecase {
+	return &categoryUsecase{repository, audit}
 }
 
 func (u *categoryUsecase) FindAll(ctx context.Context) ([]domain.Category, error) {
@@ -29,3 +38,99 @@ func (u *categoryUsecase) FindAll(ctx context.Context) ([]domain.Category, error
 
 	return categories, nil
 }
+
+func (u *categoryUsecase) FindByID(ctx context.Context, ID string) (domain.Category, error) {
+	// Find by id
+	category, err := u.repository.FindByID(ctx, ID)
+	if err != nil {
+		return category, err
+	}
+
+	return category, nil
+}
+
+func (u *categoryUsecase) Create(ctx context.Context, req web.CategoryRequest) (domain.Category, error) {
+	category := domain.Category{
+		Name: strings.TrimSpace(req.Name),
+	}
+
+	if category.Name == "" {
+		return category, errors.New("name can not be empty")
+	}
+
+	// Create
+	category, err := u.repository.Create(ctx, category)
+	if err != nil {
+		return category, err
+	}
+
+	u.audit.Record(ctx, domain.AuditActionCreate, domain.AuditEntityCategory, category.ID, nil, category)
+
+	return category, nil
+}
+
+func (u *categoryUsecase) Update(ctx context.Context, ID string, req web.CategoryRequest) (domain.Category, error) {
+	// Find by id
+	category, err := u.repository.FindByID(ctx, ID)
+	if err != nil {
+		return category, err
+	}
+	before := category
+
+	category.Name = strings.TrimSpace(req.Name)
+	if category.Name == "" {
+		return category, errors.New("name can not be empty")
+	}
+
+	// Rename
+	category, err = u.repository.Update(ctx, category)
+	if err != nil {
+		return category, err
+	}
+
+	u.audit.Record(ctx, domain.AuditActionUpdate, domain.AuditEntityCategory, category.ID, before, category)
+
+	return category, nil
+}
+
+func (u *categoryUsecase) Delete(ctx context.Context, ID string, reassignTo string) (bool, error) {
+	// Find by id
+	category, err := u.repository.FindByID(ctx, ID)
+	if err != nil {
+		return false, err
+	}
+
+	if reassignTo != "" {
+		if reassignTo == category.ID {
+			return false, errors.New("can not reassign monsters to the deleted category")
+		}
+
+		// Target of reassign must exist
+		_, err := u.repository.FindByID(ctx, reassignTo)
+		if err != nil {
+			return false, err
+		}
+	} else {
+		// Refuse delete category which is still used
+		count, err := u.repository.CountMonsters(ctx, category.ID)
+		if err != nil {
+			return false, err
+		}
+
+		if count != 0 {
+			return false, fmt.Errorf("category is still used by %d monsters, please reassign the monsters to other category", count)
+		}
+	}
+
+	// Delete
+	ok, err := u.repository.Delete(ctx, category, reassignTo)
+	if err != nil {
+		return false, err
+	}
+
+	if ok {
+		u.audit.Record(ctx, domain.AuditActionDelete, domain.AuditEntityCategory, category.ID, category, nil)
+	}
+
+	return ok, nil
+}
@@ -0,0 +1,148 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/letenk/pokedex/models/domain"
+	"github.com/letenk/pokedex/models/web"
+	"github.com/letenk/pokedex/repository"
+)
+
+type EvolutionUsecase interface {
+	Create(ctx context.Context, req web.EvolutionCreateRequest) (domain.Evolution, error)
+	Update(ctx context.Context, ID string, req web.EvolutionTriggerRequest) (domain.Evolution, error)
+	Delete(ctx context.Context, ID string) (bool, error)
+}
+
+type evolutionUsecase struct {
+	repository        repository.EvolutionRepository
+	monsterRepository repository.MonsterRepository
+	audit             AuditUsecase
+}
+
+func NewUsecaseEvolution(repository repository.EvolutionRepository, monsterRepository repository.MonsterRepository, audit AuditUsecase) *evolutionUsecase {
+	return &evolutionUsecase{repository, monsterRepository, audit}
+}
+
+func (u *evolutionUsecase) Create(ctx context.Context, req web.EvolutionCreateRequest) (domain.Evolution, error) {
+	if req.FromMonsterID == req.ToMonsterID {
+		return domain.Evolution{}, errors.New("monster can not evolve into itself")
+	}
+
+	// Both of monster must exist
+	fromMonster, err := u.monsterRepository.FindByID(ctx, req.FromMonsterID)
+	if err != nil {
+		return domain.Evolution{}, err
+	}
+
+	toMonster, err := u.monsterRepository.FindByID(ctx, req.ToMonsterID)
+	if err != nil {
+		return domain.Evolution{}, err
+	}
+
+	evolution := domain.Evolution{
+		FromMonsterID: fromMonster.ID,
+		ToMonsterID:   toMonster.ID,
+	}
+
+	err = setEvolutionTrigger(&evolution, req.EvolutionTriggerRequest)
+	if err != nil {
+		return evolution, err
+	}
+
+	// Create, the new evolution must not create cycle in the chain
+	evolution, err = u.repository.Create(ctx, evolution)
+	if errors.Is(err, domain.ErrEvolutionCycle) {
+		return evolution, fmt.Errorf("evolution from %s to %s creates a cycle", fromMonster.Name, toMonster.Name)
+	}
+	if err != nil {
+		return evolution, err
+	}
+
+	u.audit.Record(ctx, domain.AuditActionCreate, domain.AuditEntityEvolution, evolution.ID, nil, evolution)
+
+	evolution.FromMonster = fromMonster
+	evolution.ToMonster = toMonster
+	return evolution, nil
+}
+
+func (u *evolutionUsecase) Update(ctx context.Context, ID string, req web.EvolutionTriggerRequest) (domain.Evolution, error) {
+	// Find by id
+	evolution, err := u.repository.FindByID(ctx, ID)
+	if err != nil {
+		return evolution, err
+	}
+	before := evolution
+
+	err = setEvolutionTrigger(&evolution, req)
+	if err != nil {
+		return evolution, err
+	}
+
+	// Update
+	evolutionUpdated, err := u.repository.Update(ctx, evolution)
+	if err != nil {
+		return evolutionUpdated, err
+	}
+
+	u.audit.Record(ctx, domain.AuditActionUpdate, domain.AuditEntityEvolution, evolution.ID, before, evolutionUpdated)
+
+	return evolutionUpdated, nil
+}
+
+func (u *evolutionUsecase) Delete(ctx context.Context, ID string) (bool, error) {
+	// Find by id
+	evolution, err := u.repository.FindByID(ctx, ID)
+	if err != nil {
+		return false, err
+	}
+
+	// Delete
+	ok, err := u.repository.Delete(ctx, evolution)
+	if err != nil {
+		return false, err
+	}
+
+	u.audit.Record(ctx, domain.AuditActionDelete, domain.AuditEntityEvolution, evolution.ID, evolution, nil)
+
+	return ok, nil
+}
+
+// setEvolutionTrigger validate trigger and passing it into evolution
+func setEvolutionTrigger(evolution *domain.Evolution, req web.EvolutionTriggerRequest) error {
+	item := strings.TrimSpace(req.Item)
+	condition := strings.TrimSpace(req.Condition)
+
+	switch req.Trigger {
+	case "level":
+		if req.Level == nil {
+			return errors.New("level is required for trigger level")
+		}
+	case "item":
+		if item == "" {
+			return errors.New("item is required for trigger item")
+		}
+	case "condition":
+		if condition == "" {
+			return errors.New("condition is required for trigger condition")
+		}
+	default:
+		return errors.New("trigger must be level, item or condition")
+	}
+
+	evolution.Trigger = req.Trigger
+	evolution.Level = req.Level
+	evolution.Item = nil
+	if item != "" {
+		evolution.Item = &item
+	}
+	evolution.Condition = nil
+	if condition != "" {
+		evolution.Condition = &condition
+	}
+
+	return nil
+}
@@ -0,0 +1,167 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/letenk/pokedex/util"
+)
+
+// ImageStore is the storage backend for monster images
+type ImageStore interface {
+	Upload(ctx context.Context, file multipart.File, fileName string) (string, error)
+	Delete(ctx context.Context, fileName string) error
+}
+
+// NewImageStore create image store selected by config IMAGE_STORAGE, default is s3
+func NewImageStore(config util.Config) (ImageStore, error) {
+	switch config.IMAGE_STORAGE {
+	case "", "s3":
+		return NewS3ImageStore(config), nil
+	case "local":
+		return NewLocalImageStore(config.IMAGE_LOCAL_DIR, config.IMAGE_BASE_URL), nil
+	default:
+		return nil, fmt.Errorf("unknown image storage %s", config.IMAGE_STORAGE)
+	}
+}
+
+type s3ImageStore struct {
+	bucketName string
+	session    *session.Session
+}
+
+func NewS3ImageStore(config util.Config) *s3ImageStore {
+	// Config
+	s3Config := &aws.Config{
+		Region:      aws.String(config.AWS_REGION), // set region aws
+		Credentials: credentials.NewStaticCredentials(config.AWS_KEY_ID, config.AWS_SECRET_KEY, ""),
+	}
+
+	// Create new instance session
+	sess := session.Must(session.NewSession(s3Config))
+
+	return &s3ImageStore{config.AWS_BUCKET_NAME, sess}
+}
+
+func (s *s3ImageStore) Upload(ctx context.Context, file multipart.File, fileName string) (string, error) {
+	// Create new uploadert with session
+	uploader := s3manager.NewUploader(s.session)
+
+	// Create context
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// Create object input
+	input := &s3manager.UploadInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(fileName),
+		// ACL:         aws.String("public-read"),
+		Body:        file,
+		ContentType: aws.String("image/jpg"),
+	}
+
+	// Upload to aws with context
+	res, err := uploader.UploadWithContext(ctx, input)
+	if err != nil {
+		return "", err
+	}
+
+	return res.Location, nil
+}
+
+func (s *s3ImageStore) Delete(ctx context.Context, fileName string) error {
+	// Creates a new instance of the S3 client with a session.
+	svc := s3.New(s.session)
+
+	// Delete Object
+	_, err := svc.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(fileName),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	err = svc.WaitUntilObjectNotExistsWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucketName),
+		Key:    aws.String(fileName),
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+type localImageStore struct {
+	dir     string
+	baseURL string
+}
+
+// NewLocalImageStore create image store which save images into dir, and served with baseURL
+func NewLocalImageStore(dir string, baseURL string) *localImageStore {
+	return &localImageStore{dir, strings.TrimSuffix(baseURL, "/")}
+}
+
+// path return location of file inside dir, base name only for avoid path traversal
+func (s *localImageStore) path(fileName string) (string, error) {
+	name := filepath.Base(fileName)
+	if name == "." || name == string(filepath.Separator) {
+		return "", errors.New("invalid file name")
+	}
+	return filepath.Join(s.dir, name), nil
+}
+
+func (s *localImageStore) Upload(ctx context.Context, file multipart.File, fileName string) (string, error) {
+	path, err := s.path(fileName)
+	if err != nil {
+		return "", err
+	}
+
+	err = os.MkdirAll(s.dir, 0755)
+	if err != nil {
+		return "", err
+	}
+
+	dst, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer dst.Close()
+
+	_, err = io.Copy(dst, file)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s/%s", s.baseURL, url.PathEscape(filepath.Base(path))), nil
+}
+
+func (s *localImageStore) Delete(ctx context.Context, fileName string) error {
+	path, err := s.path(fileName)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/letenk/pokedex/models/domain"
+)
+
+const (
+	defaultLoginMaxAttempts      = 5
+	defaultLoginMaxAttemptsPerIP = 20
+	defaultLoginLockoutDuration  = 15 * time.Minute
+	// Wait after the first failure which is not free, doubled on every next failure
+	loginBackoffBase = time.Second
+)
+
+// LoginLockedError is returned by login when the username or ip has too many failed attempts
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return fmt.Sprintf("too many failed login attempts, try again in %d seconds", e.RetryAfterSeconds())
+}
+
+// RetryAfterSeconds round up retry after, for header `Retry-After`
+func (e *LoginLockedError) RetryAfterSeconds() int {
+	return int(math.Ceil(e.RetryAfter.Seconds()))
+}
+
+// loginAttemptLimits return max failures of every key tracked for the login, ip is empty when unknown
+func (s *userUsecase) loginAttemptLimits(username string, ip string) map[string]int {
+	maxAttemptsPerIP := s.config.LOGIN_MAX_ATTEMPTS_PER_IP
+	if maxAttemptsPerIP == 0 {
+		maxAttemptsPerIP = defaultLoginMaxAttemptsPerIP
+	}
+
+	limits := map[string]int{usernameAttemptKey(username): s.loginMaxAttempts()}
+	if ip != "" {
+		limits[ipAttemptKey(ip)] = maxAttemptsPerIP
+	}
+
+	return limits
+}
+
+// loginMaxAttempts return max failures per username
+func (s *userUsecase) loginMaxAttempts() int {
+	if s.config.LOGIN_MAX_ATTEMPTS == 0 {
+		return defaultLoginMaxAttempts
+	}
+	return s.config.LOGIN_MAX_ATTEMPTS
+}
+
+func (s *userUsecase) loginLockoutDuration() time.Duration {
+	if s.config.LOGIN_LOCKOUT_DURATION == 0 {
+		return defaultLoginLockoutDuration
+	}
+	return s.config.LOGIN_LOCKOUT_DURATION
+}
+
+// reserveLoginAttempt count the attempt as failure of all the keys before password is compared, so concurrent
+// guesses can't pass the limit. LoginLockedError is returned when one of the keys must wait before next login.
+func (s *userUsecase) reserveLoginAttempt(ctx context.Context, limits map[string]int) error {
+	keys := make([]string, 0, len(limits))
+	for key := range limits {
+		keys = append(keys, key)
+	}
+
+	return s.loginAttemptRepository.Reserve(ctx, keys, s.loginLockoutDuration(), func(attempts []domain.LoginAttempt) error {
+		now := time.Now()
+		var retryAt time.Time
+		for _, attempt := range attempts {
+			allowedAt := loginAllowedAt(attempt, limits[attempt.Key], s.loginLockoutDuration())
+			if allowedAt.After(retryAt) {
+				retryAt = allowedAt
+			}
+		}
+
+		if retryAt.After(now) {
+			return &LoginLockedError{RetryAfter: retryAt.Sub(now)}
+		}
+
+		return nil
+	})
+}
+
+// releaseLoginAttempt remove the failure counted by reserveLoginAttempt when the attempt is not a failure,
+// error is only logged so the result of login is not changed
+func (s *userUsecase) releaseLoginAttempt(ctx context.Context, limits map[string]int) {
+	for key := range limits {
+		err := s.loginAttemptRepository.Release(ctx, key)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// loginAllowedAt return time of next login allowed, attempt is before the current one is counted. Half of max failures is free,
+// then wait is doubled on every failure, and locked for lockout duration after max failures.
+func loginAllowedAt(attempt domain.LoginAttempt, maxAttempts int, lockout time.Duration) time.Time {
+	if attempt.Failures >= maxAttempts {
+		return attempt.LastFailedAt.Add(lockout)
+	}
+
+	free := maxAttempts / 2
+	if attempt.Failures <= free {
+		return attempt.LastFailedAt
+	}
+
+	wait := lockout
+	if exponent := attempt.Failures - free - 1; exponent < 32 {
+		wait = loginBackoffBase << exponent
+	}
+	if wait > lockout {
+		wait = lockout
+	}
+
+	return attempt.LastFailedAt.Add(wait)
+}
+
+// usernameAttemptKey is key of failed login for username, username is not case sensitive
+func usernameAttemptKey(username string) string {
+	return "username:" + strings.ToLower(username)
+}
+
+// ipAttemptKey is never cleared by success login or unlock, so one valid account can't reset
+// the counter of an ip guessing others, it expires with the lockout
+func ipAttemptKey(ip string) string {
+	return "ip:" + ip
+}
@@ -0,0 +1,372 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/letenk/pokedex/models/domain"
+	"github.com/letenk/pokedex/models/web"
+	"github.com/letenk/pokedex/util"
+)
+
+const (
+	// Purpose of token for exchange with code of second factor, the token can not access other endpoints
+	mfaTokenPurpose  = "mfa"
+	mfaTokenDuration = 5 * time.Minute
+	defaultMFAIssuer = "Pokedex"
+	// Total recovery codes generated when MFA is enabled
+	recoveryCodeCount = 10
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// EnrollMFA generate new secret of TOTP, MFA is enabled after the first code is verified
+func (s *userUsecase) EnrollMFA(ctx context.Context, id string, req web.MFAEnrollRequest) (web.MFAEnrollResponse, error) {
+	// Find by id
+	user, err := s.FindOneByID(ctx, id)
+	if err != nil {
+		return web.MFAEnrollResponse{}, err
+	}
+
+	// Password is asked again, token could be stolen
+	err = checkPassword(user, req.Password)
+	if err != nil {
+		return web.MFAEnrollResponse{}, errors.New("password incorrect")
+	}
+
+	return s.enrollMFA(ctx, user)
+}
+
+// enrollMFA generate new secret for user, the user has been authenticated by password
+func (s *userUsecase) enrollMFA(ctx context.Context, user domain.User) (web.MFAEnrollResponse, error) {
+	if user.MFAEnabledAt != nil {
+		return web.MFAEnrollResponse{}, errors.New("mfa is already enabled")
+	}
+
+	// Generate secret
+	var err error
+	user.MFASecret, err = util.GenerateTOTPSecret()
+	if err != nil {
+		return web.MFAEnrollResponse{}, err
+	}
+
+	_, err = s.repository.Update(ctx, user)
+	if err != nil {
+		return web.MFAEnrollResponse{}, err
+	}
+
+	issuer := s.config.MFA_ISSUER
+	if issuer == "" {
+		issuer = defaultMFAIssuer
+	}
+
+	enroll := web.MFAEnrollResponse{
+		Secret: user.MFASecret,
+		URI:    util.TOTPURI(issuer, user.Username, user.MFASecret),
+	}
+	return enroll, nil
+}
+
+// ConfirmMFA enable MFA with the first code, return recovery codes which only shown once
+func (s *userUsecase) ConfirmMFA(ctx context.Context, id string, req web.MFACodeRequest) ([]string, error) {
+	// Find by id
+	user, err := s.FindOneByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Failed code is counted like failed login, before the code is verified
+	limits := map[string]int{mfaAttemptKey(user.ID): s.loginMaxAttempts()}
+	err = s.reserveLoginAttempt(ctx, limits)
+	if err != nil {
+		return nil, err
+	}
+
+	recoveryCodes, err := s.confirmMFA(ctx, user, req.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.loginAttemptRepository.Delete(ctx, mfaAttemptKey(user.ID))
+	if err != nil {
+		log.Println(err)
+	}
+
+	return recoveryCodes, nil
+}
+
+// DisableMFA remove secret and recovery codes, not allowed when MFA is required for role of user
+func (s *userUsecase) DisableMFA(ctx context.Context, id string, req web.MFADisableRequest) (bool, error) {
+	// Find by id
+	user, err := s.FindOneByID(ctx, id)
+	if err != nil {
+		return false, err
+	}
+
+	if s.mfaRequired(user) {
+		return false, fmt.Errorf("mfa is required for role %s", user.Role)
+	}
+
+	// Password is asked again, token could be stolen
+	err = checkPassword(user, req.Password)
+	if err != nil {
+		return false, errors.New("password incorrect")
+	}
+
+	user.MFASecret = ""
+	user.MFAEnabledAt = nil
+	user.MFALastStep = 0
+	_, err = s.repository.Update(ctx, user)
+	if err != nil {
+		return false, err
+	}
+
+	err = s.mfaRepository.DeleteRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		return false, err
+	}
+
+	s.audit.Record(ctx, domain.AuditActionMFADisable, domain.AuditEntityUser, user.ID, nil, nil)
+
+	return true, nil
+}
+
+// EnrollMFAWithToken enroll MFA with pending token, for user which is required MFA but not enabled yet
+func (s *userUsecase) EnrollMFAWithToken(ctx context.Context, req web.MFAEnrollLoginRequest) (web.MFAEnrollResponse, error) {
+	claim, err := s.parseMFAToken(ctx, req.MFAToken)
+	if err != nil {
+		return web.MFAEnrollResponse{}, err
+	}
+
+	// Pending token is issued after password is verified
+	user, err := s.FindOneByID(ctx, claim.UserID)
+	if err != nil {
+		return web.MFAEnrollResponse{}, err
+	}
+
+	return s.enrollMFA(ctx, user)
+}
+
+// LoginMFA exchange pending token and code of TOTP or recovery code with access token and refresh token.
+// When the user is enrolling, the code enable MFA and the recovery codes are returned.
+func (s *userUsecase) LoginMFA(ctx context.Context, req web.MFALoginRequest) (domain.AuthToken, []string, error) {
+	claim, err := s.parseMFAToken(ctx, req.MFAToken)
+	if err != nil {
+		return domain.AuthToken{}, nil, err
+	}
+
+	user, err := s.FindOneByID(ctx, claim.UserID)
+	if err != nil {
+		return domain.AuthToken{}, nil, err
+	}
+
+	if user.DeactivatedAt != nil {
+		return domain.AuthToken{}, nil, errors.New("user is deactivated")
+	}
+
+	// Failed code is counted like failed login, before the code is verified
+	limits := map[string]int{mfaAttemptKey(user.ID): s.loginMaxAttempts()}
+	err = s.reserveLoginAttempt(ctx, limits)
+	if err != nil {
+		return domain.AuthToken{}, nil, err
+	}
+
+	var recoveryCodes []string
+	if user.MFAEnabledAt == nil {
+		recoveryCodes, err = s.confirmMFA(ctx, user, req.Code)
+	} else {
+		err = s.verifyMFA(ctx, user, req.Code)
+	}
+	if err != nil {
+		s.audit.Record(ContextWithAuditActor(ctx, user), domain.AuditActionLoginFailed, domain.AuditEntityUser, user.ID, nil, nil)
+		return domain.AuthToken{}, nil, err
+	}
+
+	err = s.loginAttemptRepository.Delete(ctx, mfaAttemptKey(user.ID))
+	if err != nil {
+		log.Println(err)
+	}
+
+	// Pending token can only be exchanged once
+	err = s.tokenRepository.RevokeAccessToken(ctx, domain.RevokedToken{
+		JTI:       claim.Id,
+		ExpiresAt: time.Unix(claim.ExpiresAt, 0),
+	})
+	if err != nil {
+		return domain.AuthToken{}, nil, err
+	}
+
+	s.audit.Record(ContextWithAuditActor(ctx, user), domain.AuditActionLogin, domain.AuditEntityUser, user.ID, nil, nil)
+
+	authToken, err := s.generateAuthToken(ctx, user, "")
+	if err != nil {
+		return authToken, nil, err
+	}
+
+	return authToken, recoveryCodes, nil
+}
+
+// mfaRequired check role of user must use MFA by policy MFA_REQUIRED_ROLES
+func (s *userUsecase) mfaRequired(user domain.User) bool {
+	for _, role := range s.config.MFA_REQUIRED_ROLES {
+		if strings.TrimSpace(role) == user.Role {
+			return true
+		}
+	}
+	return false
+}
+
+// generateMFAToken create pending token, returned by login instead of access token
+func (s *userUsecase) generateMFAToken(user domain.User) (domain.AuthToken, error) {
+	now := time.Now()
+	authToken := domain.AuthToken{
+		MFATokenExpiresAt:     now.Add(mfaTokenDuration),
+		MFAEnrollmentRequired: user.MFAEnabledAt == nil,
+	}
+
+	jti, err := randomToken(16)
+	if err != nil {
+		return authToken, err
+	}
+
+	claim := Claim{
+		UserID:  user.ID,
+		Purpose: mfaTokenPurpose,
+		StandardClaims: jwt.StandardClaims{
+			Id:        jti,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: authToken.MFATokenExpiresAt.Unix(),
+		},
+	}
+
+	authToken.MFAToken, err = s.keySet.Sign(claim)
+	if err != nil {
+		return authToken, err
+	}
+
+	return authToken, nil
+}
+
+// parseMFAToken verify pending token, the token is rejected after exchanged
+func (s *userUsecase) parseMFAToken(ctx context.Context, tokenString string) (Claim, error) {
+	var claim Claim
+	token, err := s.keySet.Parse(tokenString, &claim)
+	if err != nil {
+		return claim, errors.New("invalid mfa token")
+	}
+
+	if !token.Valid || claim.Purpose != mfaTokenPurpose || claim.UserID == "" || claim.Id == "" {
+		return claim, errors.New("invalid mfa token")
+	}
+
+	revoked, err := s.tokenRepository.IsAccessTokenRevoked(ctx, claim.Id)
+	if err != nil {
+		return claim, err
+	}
+
+	if revoked {
+		return claim, errors.New("invalid mfa token")
+	}
+
+	return claim, nil
+}
+
+// confirmMFA verify the first code of enrolled secret, then enable MFA and generate recovery codes
+func (s *userUsecase) confirmMFA(ctx context.Context, user domain.User, code string) ([]string, error) {
+	if user.MFAEnabledAt != nil {
+		return nil, errors.New("mfa is already enabled")
+	}
+
+	if user.MFASecret == "" {
+		return nil, errors.New("mfa is not enrolled")
+	}
+
+	err := s.verifyTOTP(ctx, &user, code)
+	if err != nil {
+		return nil, err
+	}
+
+	// Generate recovery codes, only the hash is saved
+	var codes, codeHashes []string
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 5)
+		_, err := rand.Read(b)
+		if err != nil {
+			return nil, err
+		}
+
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))
+		codes = append(codes, code[:4]+"-"+code[4:])
+		codeHashes = append(codeHashes, hashToken(code))
+	}
+
+	err = s.mfaRepository.ReplaceRecoveryCodes(ctx, user.ID, codeHashes)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user.MFAEnabledAt = &now
+	_, err = s.repository.Update(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ContextWithAuditActor(ctx, user), domain.AuditActionMFAEnable, domain.AuditEntityUser, user.ID, nil, nil)
+
+	return codes, nil
+}
+
+// verifyMFA accept code of TOTP or unused recovery code
+func (s *userUsecase) verifyMFA(ctx context.Context, user domain.User, code string) error {
+	code = strings.TrimSpace(code)
+
+	// Code of TOTP is only digits
+	if len(code) == 6 && strings.Trim(code, "0123456789") == "" {
+		return s.verifyTOTP(ctx, &user, code)
+	}
+
+	normalized := strings.ToLower(strings.ReplaceAll(code, "-", ""))
+	ok, err := s.mfaRepository.UseRecoveryCode(ctx, user.ID, hashToken(normalized))
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return errors.New("invalid code")
+	}
+
+	return nil
+}
+
+// verifyTOTP check code and save the step, so the same code can not be used again
+func (s *userUsecase) verifyTOTP(ctx context.Context, user *domain.User, code string) error {
+	step, ok := util.ValidateTOTP(user.MFASecret, strings.TrimSpace(code), time.Now())
+	if !ok {
+		return errors.New("invalid code")
+	}
+
+	ok, err := s.mfaRepository.UseStep(ctx, user.ID, step)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return errors.New("code has been used")
+	}
+	user.MFALastStep = step
+
+	return nil
+}
+
+// mfaAttemptKey is key of failed code of second factor
+func mfaAttemptKey(userID string) string {
+	return "mfa:" + userID
+}
@@ -0,0 +1,394 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime/multipart"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/letenk/pokedex/models/domain"
+	"github.com/letenk/pokedex/models/web"
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// Time to live of cached monsters
+	monsterCacheTTL = time.Hour
+	// Load is shared by concurrent callers, so it is not canceled with the first caller and has own timeout
+	monsterCacheLoadTimeout = 15 * time.Second
+	// Key of the last invalidation by any instance, it is not started with monsterCachePrefix so invalidation keeps it
+	monsterInvalidatedCacheKey = "invalidated_monster"
+)
+
+// Prefix of cache keys, every key of monster is started with monsterCachePrefix,
+// so rename of category or type can remove all of them
+const (
+	monsterCachePrefix       = "monster"
+	monsterListCachePrefix   = "monsters_list_"
+	monsterDetailCachePrefix = "monster_id_"
+)
+
+// cachedMonsterUsecase is read-through cache of MonsterUsecase.
+// Cache is removed before write is returned, and concurrent reads of the same key only load once.
+//
+// Value loaded before a write of this instance is never cached. Write of other instance with the same
+// shared cache is seen by key monsterInvalidatedCacheKey, which is checked again after the value is cached.
+// Between the set and the check, other reader can still get the older value, and when removing it fails
+// the older value stays until it expires.
+type cachedMonsterUsecase struct {
+	next  MonsterUsecase
+	cache Cache
+	group singleflight.Group
+
+	// Generation is increased on every write, value loaded before the write is not cached
+	mu         sync.Mutex
+	generation uint64
+}
+
+// MonsterCacheInvalidator remove cached monsters on write of other entity which is in response of monster,
+// e.g. category, type and evolution
+type MonsterCacheInvalidator interface {
+	InvalidateMonsters(ctx context.Context)
+}
+
+// monsterListCache is value of cached list
+type monsterListCache struct {
+	Monsters   []domain.Monster
+	Pagination web.Pagination
+}
+
+func NewCachedMonsterUsecase(next MonsterUsecase, cache Cache) *cachedMonsterUsecase {
+	return &cachedMonsterUsecase{next: next, cache: cache}
+}
+
+func (u *cachedMonsterUsecase) FindAll(ctx context.Context, reqQuery web.MonsterQueryRequest) ([]domain.Monster, web.Pagination, error) {
+	// Filter of catched by user login has own result for every user, it is not cached
+	if reqQuery.UserID != "" && reqQuery.Catched != "" {
+		return u.next.FindAll(ctx, reqQuery)
+	}
+
+	// List is cached once for every user, catched of user login is set after the read
+	userID := reqQuery.UserID
+	reqQuery.UserID = ""
+
+	var list monsterListCache
+	err := u.readThrough(ctx, monsterListCacheKey(reqQuery), &list, func(ctx context.Context) (interface{}, error) {
+		monsters, pagination, err := u.next.FindAll(ctx, reqQuery)
+		return monsterListCache{monsters, pagination}, err
+	})
+	if err != nil {
+		return nil, web.Pagination{}, err
+	}
+
+	err = u.next.MarkCatched(ctx, userID, list.Monsters)
+	if err != nil {
+		return nil, web.Pagination{}, err
+	}
+
+	return list.Monsters, list.Pagination, nil
+}
+
+func (u *cachedMonsterUsecase) FindByID(ctx context.Context, ID string, userID string) (domain.Monster, error) {
+	// Detail is cached once for every user, catched of user login is set after the read
+	var monster domain.Monster
+	err := u.readThrough(ctx, monsterDetailCacheKey(ID), &monster, func(ctx context.Context) (interface{}, error) {
+		return u.next.FindByID(ctx, ID, "")
+	})
+	if err != nil {
+		return domain.Monster{}, err
+	}
+
+	monsters := []domain.Monster{monster}
+	err = u.next.MarkCatched(ctx, userID, monsters)
+	if err != nil {
+		return domain.Monster{}, err
+	}
+
+	return monsters[0], nil
+}
+
+func (u *cachedMonsterUsecase) Create(ctx context.Context, req web.MonsterCreateRequest, file multipart.File, fileName string) (domain.Monster, error) {
+	monster, err := u.next.Create(ctx, req, file, fileName)
+	if err != nil {
+		return monster, err
+	}
+
+	// New monster is only in the lists
+	u.invalidate(ctx, monsterListCachePrefix)
+	return monster, nil
+}
+
+func (u *cachedMonsterUsecase) Update(ctx context.Context, ID string, reqUpdate web.MonsterUpdateRequest, file multipart.File, fileName string) (domain.Monster, error) {
+	monster, err := u.next.Update(ctx, ID, reqUpdate, file, fileName)
+	if errors.Is(err, domain.ErrMonsterVersionConflict) {
+		// Cached monster is older than database
+		u.invalidate(ctx, monsterCachePrefix)
+	}
+	if err != nil {
+		return monster, err
+	}
+
+	// Name of monster is also in evolution chain of other monsters
+	u.invalidate(ctx, monsterCachePrefix)
+	return monster, nil
+}
+
+func (u *cachedMonsterUsecase) UpdateMarkMonsterCaptured(ctx context.Context, ID string, userID string, reqUpdate web.MonsterUpdateRequestMonsterCapture) (bool, error) {
+	ok, err := u.next.UpdateMarkMonsterCaptured(ctx, ID, userID, reqUpdate)
+	if errors.Is(err, domain.ErrMonsterVersionConflict) {
+		// Cached monster is older than database
+		u.invalidate(ctx, monsterCachePrefix)
+	}
+	if err != nil {
+		return ok, err
+	}
+
+	// Cached monsters don't have catched of user, nothing to remove
+	return ok, nil
+}
+
+func (u *cachedMonsterUsecase) Delete(ctx context.Context, ID string) (bool, error) {
+	ok, err := u.next.Delete(ctx, ID)
+	if err != nil {
+		return ok, err
+	}
+
+	// Monster is also removed from evolution chain of other monsters
+	u.invalidate(ctx, monsterCachePrefix)
+	return ok, nil
+}
+
+// InvalidateMonsters remove every cached list and detail of monster
+func (u *cachedMonsterUsecase) InvalidateMonsters(ctx context.Context) {
+	u.invalidate(ctx, monsterCachePrefix)
+}
+
+func (u *cachedMonsterUsecase) FindTrash(ctx context.Context, reqQuery web.MonsterTrashQueryRequest) ([]domain.Monster, web.Pagination, error) {
+	// Trash is only read by admin, not cached
+	return u.next.FindTrash(ctx, reqQuery)
+}
+
+func (u *cachedMonsterUsecase) Restore(ctx context.Context, ID string) (domain.Monster, error) {
+	monster, err := u.next.Restore(ctx, ID)
+	if err != nil {
+		return monster, err
+	}
+
+	// Monster is back in the lists and evolution chain of other monsters
+	u.invalidate(ctx, monsterCachePrefix)
+	return monster, nil
+}
+
+func (u *cachedMonsterUsecase) MarkCatched(ctx context.Context, userID string, monsters []domain.Monster) error {
+	return u.next.MarkCatched(ctx, userID, monsters)
+}
+
+func (u *cachedMonsterUsecase) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int, error) {
+	// Monsters in trash are never cached
+	return u.next.PurgeTrash(ctx, deletedBefore)
+}
+
+// readThrough decode cached value of key into dest, or load and cache the value when not cached
+func (u *cachedMonsterUsecase) readThrough(ctx context.Context, key string, dest interface{}, load func(ctx context.Context) (interface{}, error)) error {
+	value, err := u.cache.Get(ctx, key)
+	if err == nil && json.Unmarshal(value, dest) == nil {
+		return nil
+	}
+	if err != nil && err != ErrCacheMiss {
+		log.Println("cannot read cache:", err)
+	}
+
+	// Read which starts after a write must not wait for load which starts before the write
+	generation := u.currentGeneration()
+	flightKey := fmt.Sprintf("%s_%d", key, generation)
+
+	resultChan := u.group.DoChan(flightKey, func() (interface{}, error) {
+		// Detached from the caller, so canceled request doesn't fail the others waiting for the load
+		loadCtx, cancel := context.WithTimeout(context.Background(), monsterCacheLoadTimeout)
+		defer cancel()
+		return u.load(loadCtx, key, generation, load)
+	})
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case result := <-resultChan:
+		if result.Err != nil {
+			return result.Err
+		}
+		// Every caller decode own copy
+		return json.Unmarshal(result.Val.([]byte), dest)
+	}
+}
+
+// load value and cache it, unless a write of any instance happens during the load
+func (u *cachedMonsterUsecase) load(ctx context.Context, key string, generation uint64, load func(ctx context.Context) (interface{}, error)) ([]byte, error) {
+	invalidated := u.lastInvalidation(ctx)
+
+	loaded, err := load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(loaded)
+	if err != nil {
+		return nil, err
+	}
+
+	if generation != u.currentGeneration() {
+		return data, nil
+	}
+
+	err = u.cache.Set(ctx, key, data, monsterCacheTTL)
+	if err != nil {
+		log.Println("cannot write cache:", err)
+		return data, nil
+	}
+
+	// Other instance has invalidated during the load, the value can be older than its write
+	if u.lastInvalidation(ctx) != invalidated {
+		err = u.cache.Delete(ctx, key)
+		if err != nil {
+			log.Println("cannot remove cache:", err)
+		}
+	}
+
+	return data, nil
+}
+
+// invalidate remove cache of keys and keys started with prefixes, before the write is returned
+func (u *cachedMonsterUsecase) invalidate(ctx context.Context, prefixes ...string) {
+	u.mu.Lock()
+	u.generation++
+	u.mu.Unlock()
+
+	// Mark before remove, so load of other instance which sets after the remove sees the mark
+	mark := strconv.FormatInt(time.Now().UnixNano(), 10)
+	err := u.cache.Set(ctx, monsterInvalidatedCacheKey, []byte(mark), monsterCacheTTL)
+	if err != nil {
+		log.Println("cannot write cache:", err)
+	}
+
+	for _, prefix := range prefixes {
+		err := u.cache.DeletePrefix(ctx, prefix)
+		if err != nil {
+			log.Println("cannot remove cache:", err)
+		}
+	}
+}
+
+// lastInvalidation return mark of the last invalidation, empty when not marked or expired
+func (u *cachedMonsterUsecase) lastInvalidation(ctx context.Context) string {
+	value, err := u.cache.Get(ctx, monsterInvalidatedCacheKey)
+	if err != nil && err != ErrCacheMiss {
+		log.Println("cannot read cache:", err)
+	}
+	return string(value)
+}
+
+func (u *cachedMonsterUsecase) currentGeneration() uint64 {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.generation
+}
+
+// monsterListCacheQuery is the fields of query which change the list, normalised so equal filters have one key
+type monsterListCacheQuery struct {
+	Name         string
+	Types        []string
+	TypesMode    string
+	ExcludeTypes []string
+	Catched      string
+	CategoryID   string
+	HpMin        *float64
+	HpMax        *float64
+	AttackMin    *float64
+	AttackMax    *float64
+	DefendsMin   *float64
+	DefendsMax   *float64
+	SpeedMin     *float64
+	SpeedMax     *float64
+	WeightMin    *float64
+	WeightMax    *float64
+	LengthMin    *float64
+	LengthMax    *float64
+	Sort         string
+	Order        string
+	Page         int
+	Limit        int
+	Cursor       string
+}
+
+// monsterListCacheKey is derived from normalised query, so each filter and page has own cache
+func monsterListCacheKey(reqQuery web.MonsterQueryRequest) string {
+	query := monsterListCacheQuery{
+		// Name is matched case insensitive
+		Name:         strings.ToLower(reqQuery.Name),
+		Types:        cacheIDs(reqQuery.Types),
+		TypesMode:    reqQuery.TypesMode,
+		ExcludeTypes: cacheIDs(reqQuery.ExcludeTypes),
+		Catched:      reqQuery.Catched,
+		CategoryID:   strings.ToLower(reqQuery.CategoryID),
+		HpMin:        reqQuery.HpMin,
+		HpMax:        reqQuery.HpMax,
+		AttackMin:    reqQuery.AttackMin,
+		AttackMax:    reqQuery.AttackMax,
+		DefendsMin:   reqQuery.DefendsMin,
+		DefendsMax:   reqQuery.DefendsMax,
+		SpeedMin:     reqQuery.SpeedMin,
+		SpeedMax:     reqQuery.SpeedMax,
+		WeightMin:    reqQuery.WeightMin,
+		WeightMax:    reqQuery.WeightMax,
+		LengthMin:    reqQuery.LengthMin,
+		LengthMax:    reqQuery.LengthMax,
+		Sort:         reqQuery.Sort,
+		Order:        reqQuery.Order,
+		Page:         reqQuery.Page,
+		Limit:        reqQuery.Limit,
+		Cursor:       reqQuery.Cursor,
+	}
+
+	// Mode is only used with types, default is any
+	if len(query.Types) == 0 || query.TypesMode == "any" {
+		query.TypesMode = ""
+	}
+	if catched, err := strconv.ParseBool(query.Catched); err == nil {
+		query.Catched = strconv.FormatBool(catched)
+	}
+	// First page is default when cursor is not used
+	if query.Page == 1 && query.Cursor == "" {
+		query.Page = 0
+	}
+
+	data, _ := json.Marshal(query)
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf("%s%x", monsterListCachePrefix, sum[:16])
+}
+
+// cacheIDs return sorted ids without duplicate, filter of types is a set
+func cacheIDs(IDs []string) []string {
+	seen := make(map[string]bool, len(IDs))
+	result := make([]string, 0, len(IDs))
+	for _, ID := range IDs {
+		ID = strings.ToLower(ID)
+		if !seen[ID] {
+			seen[ID] = true
+			result = append(result, ID)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// monsterDetailCacheKey is key of detail, same for every user
+func monsterDetailCacheKey(ID string) string {
+	return monsterDetailCachePrefix + ID
+}
@@ -0,0 +1,45 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/letenk/pokedex/util"
+)
+
+const (
+	// Default of how long deleted monster stays in trash and how often trash is purged
+	defaultMonsterTrashRetention = 30 * 24 * time.Hour
+	defaultMonsterPurgeInterval  = time.Hour
+)
+
+// StartMonsterTrashPurge purge monsters which are in trash longer than retention on every interval, until ctx is done
+func StartMonsterTrashPurge(ctx context.Context, usecase MonsterUsecase, config util.Config) {
+	retention := config.MONSTER_TRASH_RETENTION
+	if retention == 0 {
+		retention = defaultMonsterTrashRetention
+	}
+	interval := config.MONSTER_PURGE_INTERVAL
+	if interval == 0 {
+		interval = defaultMonsterPurgeInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := usecase.PurgeTrash(ctx, time.Now().Add(-retention))
+		if err != nil {
+			log.Println("cannot purge monsters in trash:", err)
+		} else if purged != 0 {
+			log.Printf("purged %d monsters from trash", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
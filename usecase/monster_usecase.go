@@ -6,117 +6,38 @@ import (
 	"fmt"
 	"log"
 	"mime/multipart"
+	"sort"
 	"strconv"
 	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3"
-	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/letenk/pokedex/models/domain"
 	"github.com/letenk/pokedex/models/web"
 	"github.com/letenk/pokedex/repository"
-	"github.com/letenk/pokedex/util"
 )
 
 type MonsterUsecase interface {
-	FindAll(ctx context.Context, reqQuery web.MonsterQueryRequest) ([]domain.Monster, error)
-	FindByID(ctx context.Context, ID string) (domain.Monster, error)
+	FindAll(ctx context.Context, reqQuery web.MonsterQueryRequest) ([]domain.Monster, web.Pagination, error)
+	FindByID(ctx context.Context, ID string, userID string) (domain.Monster, error)
 	Create(ctx context.Context, monster web.MonsterCreateRequest, file multipart.File, fileName string) (domain.Monster, error)
 	Update(ctx context.Context, ID string, reqUpdate web.MonsterUpdateRequest, file multipart.File, fileName string) (domain.Monster, error)
-	UpdateMarkMonsterCaptured(ctx context.Context, ID string, reqUpdate web.MonsterUpdateRequestMonsterCapture) (bool, error)
+	UpdateMarkMonsterCaptured(ctx context.Context, ID string, userID string, reqUpdate web.MonsterUpdateRequestMonsterCapture) (bool, error)
 	Delete(ctx context.Context, ID string) (bool, error)
+	FindTrash(ctx context.Context, reqQuery web.MonsterTrashQueryRequest) ([]domain.Monster, web.Pagination, error)
+	Restore(ctx context.Context, ID string) (domain.Monster, error)
+	PurgeTrash(ctx context.Context, deletedBefore time.Time) (int, error)
+	MarkCatched(ctx context.Context, userID string, monsters []domain.Monster) error
 }
 
 type monsterUsecase struct {
-	repository repository.MonsterRepository
+	repository          repository.MonsterRepository
+	captureRepository   repository.CaptureRepository
+	evolutionRepository repository.EvolutionRepository
+	imageStore          ImageStore
+	audit               AuditUsecase
 }
 
-func NewUsecaseMonster(repository repository.MonsterRepository) *monsterUsecase {
-	return &monsterUsecase{repository}
-}
-
-func UploadToAwsS3(ctx context.Context, file multipart.File, fileName string) (string, error) {
-	// Load Config
-	config, err := util.LoadConfig(".")
-	if err != nil {
-		log.Fatal("cannot load config:", err)
-	}
-	// Config
-	s3Config := &aws.Config{
-		Region:      aws.String(config.AWS_REGION), // set region aws
-		Credentials: credentials.NewStaticCredentials(config.AWS_KEY_ID, config.AWS_SECRET_KEY, ""),
-	}
-
-	// Create new instance session
-	sess := session.New(s3Config)
-
-	// Bucket name
-	bucketName := config.AWS_BUCKET_NAME
-	// Create new uploadert with session
-	uploader := s3manager.NewUploader(sess)
-
-	// Create context
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
-
-	// Create object input
-	input := &s3manager.UploadInput{
-		Bucket: aws.String(bucketName),
-		Key:    aws.String(fileName),
-		// ACL:         aws.String("public-read"),
-		Body:        file,
-		ContentType: aws.String("image/jpg"),
-	}
-
-	// Upload to aws with context
-	res, err := uploader.UploadWithContext(ctx, input)
-	if err != nil {
-		return "", err
-	}
-
-	return res.Location, nil
-}
-
-func DeleteItemFromAwsS3(ctx context.Context, keyName string) error {
-	// Load Config
-	config, err := util.LoadConfig(".")
-	if err != nil {
-		log.Fatal("cannot load config:", err)
-	}
-	// Config
-	s3Config := &aws.Config{
-		Region:      aws.String(config.AWS_REGION), // set region aws
-		Credentials: credentials.NewStaticCredentials(config.AWS_KEY_ID, config.AWS_SECRET_KEY, ""),
-	}
-
-	// Create new instance session
-	sess := session.New(s3Config)
-
-	// Creates a new instance of the S3 client with a session.
-	svc := s3.New(sess)
-
-	// Delete Object
-	_, err = svc.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
-		Bucket: aws.String(config.AWS_BUCKET_NAME),
-		Key:    aws.String(keyName),
-	})
-
-	if err != nil {
-		return err
-	}
-
-	err = svc.WaitUntilObjectNotExists(&s3.HeadObjectInput{
-		Bucket: aws.String(config.AWS_BUCKET_NAME),
-		Key:    aws.String(keyName),
-	})
-
-	if err != nil {
-		return err
-	}
-
-	return nil
+func NewUsecaseMonster(repository repository.MonsterRepository, captureRepository repository.CaptureRepository, evolutionRepository repository.EvolutionRepository, imageStore ImageStore, audit AuditUsecase) *monsterUsecase {
+	return &monsterUsecase{repository, captureRepository, evolutionRepository, imageStore, audit}
 }
 
 func (u *monsterUsecase) Create(ctx context.Context, req web.MonsterCreateRequest, file multipart.File, fileName string) (domain.Monster, error) {
@@ -142,42 +63,92 @@ func (u *monsterUsecase) Create(ctx context.Context, req web.MonsterCreateReques
 		return monster, err
 	}
 
-	// Upload to aws S3
-	imageLocationS3, err := UploadToAwsS3(ctx, file, fileName)
+	// Upload to image store
+	imageLocation, err := u.imageStore.Upload(ctx, file, fileName)
 	if err != nil {
 		return monster, err
 	}
 
-	// Passing image location in aws to object monster
-	monster.ImageURL = imageLocationS3
+	// Passing image location in image store to object monster
+	monster.ImageURL = imageLocation
 
 	// Update image
-	_, err = u.repository.Update(ctx, monster)
+	monsterUpdated, err := u.repository.Update(ctx, monster)
 	if err != nil {
 		return monster, err
 	}
+	monster.Version = monsterUpdated.Version
+
+	u.audit.Record(ctx, domain.AuditActionCreate, domain.AuditEntityMonster, monster.ID, nil, auditMonster(monster))
 
 	return monster, nil
 }
 
-func (u *monsterUsecase) FindAll(ctx context.Context, reqQuery web.MonsterQueryRequest) ([]domain.Monster, error) {
+func (u *monsterUsecase) FindAll(ctx context.Context, reqQuery web.MonsterQueryRequest) ([]domain.Monster, web.Pagination, error) {
 	// Find all
-	monsters, err := u.repository.FindAll(ctx, reqQuery)
+	monsters, pagination, err := u.repository.FindAll(ctx, reqQuery)
+	if err != nil {
+		return monsters, pagination, err
+	}
+
+	// Mark monsters captured by user login
+	err = u.MarkCatched(ctx, reqQuery.UserID, monsters)
+	if err != nil {
+		return monsters, pagination, err
+	}
+
+	return monsters, pagination, nil
+}
+
+// MarkCatched set catched of monsters captured by user, guest never captured any monster
+func (u *monsterUsecase) MarkCatched(ctx context.Context, userID string, monsters []domain.Monster) error {
+	if userID == "" || len(monsters) == 0 {
+		return nil
+	}
+
+	monsterIDs := make([]string, 0, len(monsters))
+	for _, monster := range monsters {
+		monsterIDs = append(monsterIDs, monster.ID)
+	}
+
+	captures, err := u.captureRepository.FindByUserID(ctx, userID, monsterIDs)
 	if err != nil {
-		return monsters, err
+		return err
+	}
+
+	captured := make(map[string]bool, len(captures))
+	for _, capture := range captures {
+		captured[capture.MonsterID] = true
+	}
+
+	for i := range monsters {
+		monsters[i].Catched = captured[monsters[i].ID]
 	}
 
-	return monsters, nil
+	return nil
 }
 
-func (u *monsterUsecase) FindByID(ctx context.Context, ID string) (domain.Monster, error) {
+func (u *monsterUsecase) FindByID(ctx context.Context, ID string, userID string) (domain.Monster, error) {
 	// Find by id
 	monster, err := u.repository.FindByID(ctx, ID)
 	if err != nil {
 		return monster, err
 	}
 
-	return monster, nil
+	// Full evolution chain
+	monster.Evolutions, err = u.findEvolutions(ctx, monster.ID)
+	if err != nil {
+		return monster, err
+	}
+
+	// Mark monster captured by user login
+	monsters := []domain.Monster{monster}
+	err = u.MarkCatched(ctx, userID, monsters)
+	if err != nil {
+		return monster, err
+	}
+
+	return monsters[0], nil
 }
 
 func (u *monsterUsecase) Update(ctx context.Context, ID string, reqUpdate web.MonsterUpdateRequest, file multipart.File, fileName string) (domain.Monster, error) {
@@ -187,6 +158,13 @@ func (u *monsterUsecase) Update(ctx context.Context, ID string, reqUpdate web.Mo
 	if err != nil {
 		return currentMonster, err
 	}
+	before := auditMonster(currentMonster)
+
+	// Monster is changed after the version known by client
+	if reqUpdate.Version != nil && *reqUpdate.Version != currentMonster.Version {
+		return currentMonster, domain.ErrMonsterVersionConflict
+	}
+
 	// Parse reqUpdate form when not empty
 	if reqUpdate.Name != "" {
 		currentMonster.Name = reqUpdate.Name
@@ -239,14 +217,6 @@ func (u *monsterUsecase) Update(ctx context.Context, ID string, reqUpdate web.Mo
 		}
 		currentMonster.Speed = uint16(intSpeed)
 	}
-	if reqUpdate.Catched != "" {
-		catchedBool, err := strconv.ParseBool(reqUpdate.Catched)
-		if err != nil {
-			return currentMonster, err
-		}
-		currentMonster.Catched = catchedBool
-	}
-
 	if len(reqUpdate.TypeID) != 0 {
 		currentMonster.TypeID = reqUpdate.TypeID
 	}
@@ -262,64 +232,94 @@ func (u *monsterUsecase) Update(ctx context.Context, ID string, reqUpdate web.Mo
 		Attack:      currentMonster.Attack,
 		Defends:     currentMonster.Defends,
 		Speed:       currentMonster.Speed,
-		Catched:     currentMonster.Catched,
 		ImageName:   currentMonster.ImageName,
 		ImageURL:    currentMonster.ImageURL,
 		TypeID:      reqUpdate.TypeID,
-	}
-
-	// Update monster image
-	monsterUpdated, err := u.repository.Update(ctx, dataUpdate)
-	if err != nil {
-		return currentMonster, err
+		Version:     currentMonster.Version,
 	}
 
 	if fileName != "" {
-		ctxToAws, cancel := context.WithTimeout(ctx, 5*time.Second)
+		ctxToStore, cancel := context.WithTimeout(ctx, 5*time.Second)
 		defer cancel()
 
-		// Remove item from aws
-		err := DeleteItemFromAwsS3(ctxToAws, monsterUpdated.ImageName)
-
+		// Upload new image before update, so failed upload keeps the monster and its old image
+		newImageLocation, err := u.imageStore.Upload(ctxToStore, file, fileName)
 		if err != nil {
 			return currentMonster, err
 		}
 
-		// Upload new image to aws S3
-		newImageLocationS3, err := UploadToAwsS3(ctxToAws, file, fileName)
-		if err != nil {
-			return currentMonster, err
+		dataUpdate.ImageName = fileName
+		dataUpdate.ImageURL = newImageLocation
+	}
+
+	// Update monster and image in one version
+	monsterUpdated, err := u.repository.Update(ctx, dataUpdate)
+	if err != nil {
+		// New image is not used by any monster
+		if fileName != "" && fileName != currentMonster.ImageName {
+			u.deleteImage(ctx, fileName)
 		}
+		return currentMonster, err
+	}
 
-		// Update current imageName to new image and new url image
-		monsterUpdated.ImageName = fileName
-		monsterUpdated.ImageURL = newImageLocationS3
+	// Old image is only removed after the monster use the new image
+	if fileName != "" && fileName != currentMonster.ImageName {
+		u.deleteImage(ctx, currentMonster.ImageName)
+	}
 
-		// Update monster image
-		_, err = u.repository.Update(ctx, monsterUpdated)
-		if err != nil {
-			return currentMonster, err
-		}
+	// Types are kept when not requested
+	after := auditMonster(monsterUpdated)
+	if len(after.TypeID) == 0 {
+		after.TypeID = before.TypeID
+	}
+	u.audit.Record(ctx, domain.AuditActionUpdate, domain.AuditEntityMonster, monsterUpdated.ID, before, after)
+
+	// Full evolution chain
+	monsterUpdated.Evolutions, err = u.findEvolutions(ctx, monsterUpdated.ID)
+	if err != nil {
+		return currentMonster, err
 	}
 
 	return monsterUpdated, nil
 }
 
-func (u *monsterUsecase) UpdateMarkMonsterCaptured(ctx context.Context, ID string, reqUpdate web.MonsterUpdateRequestMonsterCapture) (bool, error) {
+func (u *monsterUsecase) UpdateMarkMonsterCaptured(ctx context.Context, ID string, userID string, reqUpdate web.MonsterUpdateRequestMonsterCapture) (bool, error) {
 	// Find by id
 	currentMonster, err := u.repository.FindByID(ctx, ID)
 	if err != nil {
 		return false, err
 	}
 
-	currentMonster.Catched = reqUpdate.Catched
+	// Monster is changed after the version known by client
+	if reqUpdate.Version != nil && *reqUpdate.Version != currentMonster.Version {
+		return false, domain.ErrMonsterVersionConflict
+	}
+
+	capture := domain.UserMonster{
+		UserID:    userID,
+		MonsterID: currentMonster.ID,
+	}
 
-	// Update monster
-	_, err = u.repository.Update(ctx, currentMonster)
+	// Release monster when catched is false
+	if !reqUpdate.Catched {
+		_, err = u.captureRepository.Delete(ctx, capture)
+		if err != nil {
+			return false, err
+		}
+
+		u.audit.Record(ctx, domain.AuditActionRelease, domain.AuditEntityMonster, currentMonster.ID, nil, nil)
+
+		return true, nil
+	}
+
+	// Capture monster
+	_, err = u.captureRepository.Create(ctx, capture)
 	if err != nil {
 		return false, err
 	}
 
+	u.audit.Record(ctx, domain.AuditActionCapture, domain.AuditEntityMonster, currentMonster.ID, nil, nil)
+
 	return true, nil
 }
 
@@ -339,24 +339,143 @@ func (u *monsterUsecase) Delete(ctx context.Context, ID string) (bool, error) {
 		return false, err
 	}
 
-	// Delete
+	// Trashed monster is hidden from evolution chain of the other monsters
+	chain, err := u.evolutionRepository.FindChain(ctx, monster.ID)
+	if err != nil {
+		return false, err
+	}
+
+	// Move into trash, image is removed when the monster is purged
 	ok, err := u.repository.Delete(ctx, monster)
 	if err != nil {
 		return false, err
 	}
 
-	// Remove image in aws
+	// Touch the other monsters of chain, so their last modified see the hidden step
+	var chainIDs []string
+	for _, evolution := range chain {
+		for _, chainID := range []string{evolution.FromMonsterID, evolution.ToMonsterID} {
+			if chainID != monster.ID {
+				chainIDs = append(chainIDs, chainID)
+			}
+		}
+	}
+	if len(chainIDs) != 0 {
+		err = u.repository.Touch(ctx, chainIDs)
+		if err != nil {
+			return false, err
+		}
+	}
+
 	if ok {
-		ctxToAws, cancel := context.WithTimeout(ctx, 5*time.Second)
-		defer cancel()
+		u.audit.Record(ctx, domain.AuditActionDelete, domain.AuditEntityMonster, monster.ID, auditMonster(monster), nil)
+	}
 
-		// Remove item from aws
-		err := DeleteItemFromAwsS3(ctxToAws, monster.ImageName)
+	return ok, nil
+}
 
+func (u *monsterUsecase) FindTrash(ctx context.Context, reqQuery web.MonsterTrashQueryRequest) ([]domain.Monster, web.Pagination, error) {
+	// Find monsters in trash
+	monsters, pagination, err := u.repository.FindTrash(ctx, reqQuery)
+	if err != nil {
+		return monsters, pagination, err
+	}
+
+	return monsters, pagination, nil
+}
+
+func (u *monsterUsecase) Restore(ctx context.Context, ID string) (domain.Monster, error) {
+	// Restore from trash
+	monster, err := u.repository.Restore(ctx, ID)
+	if err != nil {
+		return monster, err
+	}
+
+	u.audit.Record(ctx, domain.AuditActionRestore, domain.AuditEntityMonster, monster.ID, nil, auditMonster(monster))
+
+	return monster, nil
+}
+
+// Monsters purged in one batch
+const purgeBatchSize = 100
+
+// PurgeTrash permanently delete monsters which are deleted before deletedBefore and their images, return count of purged monsters
+func (u *monsterUsecase) PurgeTrash(ctx context.Context, deletedBefore time.Time) (int, error) {
+	purged := 0
+	for {
+		monsters, err := u.repository.FindPurgeable(ctx, deletedBefore, purgeBatchSize)
 		if err != nil {
-			return false, err
+			return purged, err
+		}
+
+		for _, monster := range monsters {
+			// Monster restored after it is found is not purged
+			ok, err := u.repository.Purge(ctx, monster, deletedBefore)
+			if err != nil {
+				return purged, err
+			}
+			if !ok {
+				continue
+			}
+			purged++
+
+			u.audit.Record(ctx, domain.AuditActionPurge, domain.AuditEntityMonster, monster.ID, auditMonster(monster), nil)
+
+			// Row is already removed, so failed image is only logged and not retried
+			ctxToStore, cancel := context.WithTimeout(ctx, 5*time.Second)
+			err = u.imageStore.Delete(ctxToStore, monster.ImageName)
+			cancel()
+			if err != nil {
+				log.Printf("cannot remove image %s of purged monster %s: %v", monster.ImageName, monster.ID, err)
+			}
+		}
+
+		if len(monsters) < purgeBatchSize {
+			return purged, nil
 		}
 	}
+}
 
-	return true, nil
+// findEvolutions return evolution chain of monster, evolution of monster in trash is hidden until the monster is restored
+func (u *monsterUsecase) findEvolutions(ctx context.Context, monsterID string) ([]domain.Evolution, error) {
+	chain, err := u.evolutionRepository.FindChain(ctx, monsterID)
+	if err != nil {
+		return nil, err
+	}
+
+	evolutions := make([]domain.Evolution, 0, len(chain))
+	for _, evolution := range chain {
+		if evolution.FromMonster.ID == "" || evolution.ToMonster.ID == "" {
+			continue
+		}
+		evolutions = append(evolutions, evolution)
+	}
+
+	return evolutions, nil
+}
+
+// deleteImage remove image from image store, error is only logged because the monster is already saved
+func (u *monsterUsecase) deleteImage(ctx context.Context, fileName string) {
+	ctxToStore, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err := u.imageStore.Delete(ctxToStore, fileName)
+	if err != nil {
+		log.Printf("cannot remove image %s: %v", fileName, err)
+	}
+}
+
+// auditMonster return monster for audit log, types are saved as sorted ids
+func auditMonster(monster domain.Monster) domain.Monster {
+	typeIDs := make([]string, 0, len(monster.TypeID))
+	typeIDs = append(typeIDs, monster.TypeID...)
+	if len(typeIDs) == 0 {
+		for _, t := range monster.Types {
+			typeIDs = append(typeIDs, t.ID)
+		}
+	}
+	sort.Strings(typeIDs)
+
+	monster.TypeID = typeIDs
+	return monster
 }
@@ -0,0 +1,329 @@
+package usecase
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/letenk/pokedex/models/domain"
+	"github.com/letenk/pokedex/util"
+)
+
+// OIDCProvider talk to identity provider with authorization code flow
+type OIDCProvider interface {
+	AuthCodeURL(ctx context.Context, state string, nonce string, codeChallenge string) (string, error)
+	Exchange(ctx context.Context, code string, codeVerifier string, nonce string) (domain.ExternalIdentity, error)
+}
+
+// oidcDiscovery is part of `/.well-known/openid-configuration` used by the flow
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type oidcProvider struct {
+	config util.Config
+	client *http.Client
+
+	// Discovery and keys of provider are fetched once, keys are fetched again on unknown kid
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	keys      map[string]interface{}
+}
+
+func NewOIDCProvider(config util.Config) *oidcProvider {
+	return &oidcProvider{
+		config: config,
+		client: &http.Client{Timeout: 15 * time.Second},
+		keys:   map[string]interface{}{},
+	}
+}
+
+func (p *oidcProvider) AuthCodeURL(ctx context.Context, state string, nonce string, codeChallenge string) (string, error) {
+	discovery, err := p.getDiscovery(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	scopes := p.config.OIDC_SCOPES
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "profile", "email"}
+	}
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", p.config.OIDC_CLIENT_ID)
+	query.Set("redirect_uri", p.config.OIDC_REDIRECT_URL)
+	query.Set("scope", strings.Join(scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", codeChallenge)
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange code with id token, then verify signature, issuer, audience, expiry and nonce of id token
+func (p *oidcProvider) Exchange(ctx context.Context, code string, codeVerifier string, nonce string) (domain.ExternalIdentity, error) {
+	discovery, err := p.getDiscovery(ctx)
+	if err != nil {
+		return domain.ExternalIdentity{}, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.OIDC_REDIRECT_URL)
+	form.Set("code_verifier", codeVerifier)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return domain.ExternalIdentity{}, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	request.SetBasicAuth(url.QueryEscape(p.config.OIDC_CLIENT_ID), url.QueryEscape(p.config.OIDC_CLIENT_SECRET))
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return domain.ExternalIdentity{}, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return domain.ExternalIdentity{}, fmt.Errorf("token endpoint return status %d", response.StatusCode)
+	}
+
+	var tokenResponse struct {
+		IDToken string `json:"id_token"`
+	}
+	err = json.NewDecoder(response.Body).Decode(&tokenResponse)
+	if err != nil {
+		return domain.ExternalIdentity{}, err
+	}
+
+	if tokenResponse.IDToken == "" {
+		return domain.ExternalIdentity{}, errors.New("token endpoint doesn't return id token")
+	}
+
+	return p.verifyIDToken(ctx, discovery, tokenResponse.IDToken, nonce)
+}
+
+func (p *oidcProvider) verifyIDToken(ctx context.Context, discovery *oidcDiscovery, idToken string, nonce string) (domain.ExternalIdentity, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		switch token.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+		default:
+			return nil, errors.New("unexpected signing method of id token")
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		return p.getKey(ctx, discovery, kid)
+	})
+	if err != nil {
+		return domain.ExternalIdentity{}, fmt.Errorf("invalid id token: %w", err)
+	}
+
+	if issuer, _ := claims["iss"].(string); issuer != discovery.Issuer {
+		return domain.ExternalIdentity{}, errors.New("invalid id token: issuer is not matched")
+	}
+
+	if !containsAudience(claims["aud"], p.config.OIDC_CLIENT_ID) {
+		return domain.ExternalIdentity{}, errors.New("invalid id token: audience is not matched")
+	}
+
+	if _, ok := claims["exp"]; !ok {
+		return domain.ExternalIdentity{}, errors.New("invalid id token: expiry is missing")
+	}
+
+	if claimNonce, _ := claims["nonce"].(string); claimNonce == "" || claimNonce != nonce {
+		return domain.ExternalIdentity{}, errors.New("invalid id token: nonce is not matched")
+	}
+
+	if p.config.OIDC_MFA_MODE == oidcMFAModeProvider && !oidcMFAAsserted(claims, p.config.OIDC_MFA_ACR_VALUES) {
+		return domain.ExternalIdentity{}, errors.New("invalid id token: second factor is not verified by provider")
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return domain.ExternalIdentity{}, errors.New("invalid id token: subject is missing")
+	}
+
+	identity := domain.ExternalIdentity{
+		Issuer:  discovery.Issuer,
+		Subject: subject,
+	}
+	identity.Username, _ = claims["preferred_username"].(string)
+	identity.Fullname, _ = claims["name"].(string)
+	identity.Email, _ = claims["email"].(string)
+
+	groupsClaim := p.config.OIDC_GROUPS_CLAIM
+	if groupsClaim == "" {
+		groupsClaim = "groups"
+	}
+	if groups, ok := claims[groupsClaim].([]interface{}); ok {
+		identity.Groups = []string{}
+		for _, group := range groups {
+			if name, ok := group.(string); ok {
+				identity.Groups = append(identity.Groups, name)
+			}
+		}
+	}
+
+	return identity, nil
+}
+
+// oidcMFAAsserted check claim `amr` contains mfa (RFC 8176), or claim `acr` is one of acr values
+func oidcMFAAsserted(claims jwt.MapClaims, acrValues []string) bool {
+	if methods, ok := claims["amr"].([]interface{}); ok {
+		for _, method := range methods {
+			if method == "mfa" {
+				return true
+			}
+		}
+	}
+
+	acr, _ := claims["acr"].(string)
+	for _, value := range acrValues {
+		if acr != "" && acr == strings.TrimSpace(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsAudience check claim `aud` which can be string or array
+func containsAudience(aud interface{}, clientID string) bool {
+	switch value := aud.(type) {
+	case string:
+		return value == clientID
+	case []interface{}:
+		for _, item := range value {
+			if item == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *oidcProvider) getDiscovery(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	if p.config.OIDC_ISSUER == "" {
+		return nil, errors.New("oidc is not configured")
+	}
+
+	var discovery oidcDiscovery
+	err := p.getJSON(ctx, strings.TrimSuffix(p.config.OIDC_ISSUER, "/")+"/.well-known/openid-configuration", &discovery)
+	if err != nil {
+		return nil, err
+	}
+
+	if discovery.Issuer != p.config.OIDC_ISSUER {
+		return nil, fmt.Errorf("issuer of discovery %s is not matched", discovery.Issuer)
+	}
+
+	p.discovery = &discovery
+	return p.discovery, nil
+}
+
+// getKey return public key of provider by kid, the keys are fetched again when kid is unknown because of rotation
+func (p *oidcProvider) getKey(ctx context.Context, discovery *oidcDiscovery, kid string) (interface{}, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+
+	var jwks struct {
+		Keys []struct {
+			KeyType string `json:"kty"`
+			KeyID   string `json:"kid"`
+			N       string `json:"n"`
+			E       string `json:"e"`
+			Curve   string `json:"crv"`
+			X       string `json:"x"`
+			Y       string `json:"y"`
+		} `json:"keys"`
+	}
+	err := p.getJSON(ctx, discovery.JWKSURI, &jwks)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[string]interface{}{}
+	for _, jwk := range jwks.Keys {
+		switch jwk.KeyType {
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+			e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+			if errN != nil || errE != nil {
+				continue
+			}
+			keys[jwk.KeyID] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			if jwk.Curve != "P-256" {
+				continue
+			}
+			x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+			y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+			if errX != nil || errY != nil {
+				continue
+			}
+			keys[jwk.KeyID] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+	p.keys = keys
+
+	key, ok := p.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("key %s of id token not found", kid)
+	}
+	return key, nil
+}
+
+func (p *oidcProvider) getJSON(ctx context.Context, target string, v interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/json")
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s return status %d", target, response.StatusCode)
+	}
+
+	return json.NewDecoder(response.Body).Decode(v)
+}
@@ -0,0 +1,266 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/letenk/pokedex/models/domain"
+	"github.com/letenk/pokedex/models/web"
+	"github.com/letenk/pokedex/repository"
+	"github.com/letenk/pokedex/util"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// Purpose of token saved on cookie between redirect to provider and callback
+	oidcStatePurpose  = "oidc_state"
+	oidcStateDuration = 10 * time.Minute
+	// Username is created from claim of provider, and is made unique with random suffix
+	oidcUsernameMinLength = 3
+	oidcUsernameMaxLength = 30
+	oidcProvisionRetry    = 3
+	// Second factor is verified by provider, otherwise pokedex ask TOTP
+	oidcMFAModeProvider = "provider"
+)
+
+type OIDCUsecase interface {
+	Start(ctx context.Context) (string, string, error)
+	Callback(ctx context.Context, req web.OIDCCallbackRequest) (domain.AuthToken, error)
+}
+
+// oidcStateClaim is state of login flow, state is sent to provider and must be returned to callback
+type oidcStateClaim struct {
+	Purpose      string `json:"purpose"`
+	State        string `json:"state"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	jwt.StandardClaims
+}
+
+type oidcUsecase struct {
+	provider           OIDCProvider
+	identityRepository repository.UserIdentityRepository
+	userRepository     repository.UserRepository
+	userUsecase        UserUsecase
+	audit              AuditUsecase
+	keySet             *KeySet
+	config             util.Config
+}
+
+func NewUsecaseOIDC(provider OIDCProvider, identityRepository repository.UserIdentityRepository, userRepository repository.UserRepository, userUsecase UserUsecase, audit AuditUsecase, keySet *KeySet, config util.Config) *oidcUsecase {
+	return &oidcUsecase{provider, identityRepository, userRepository, userUsecase, audit, keySet, config}
+}
+
+// Start return url of provider for login and signed state, the state must be kept by client until callback
+func (u *oidcUsecase) Start(ctx context.Context) (string, string, error) {
+	state, err := randomToken(16)
+	if err != nil {
+		return "", "", err
+	}
+
+	nonce, err := randomToken(16)
+	if err != nil {
+		return "", "", err
+	}
+
+	// PKCE, only the challenge is sent to provider
+	codeVerifier, err := randomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	challenge := sha256.Sum256([]byte(codeVerifier))
+	codeChallenge := base64.RawURLEncoding.EncodeToString(challenge[:])
+
+	authURL, err := u.provider.AuthCodeURL(ctx, state, nonce, codeChallenge)
+	if err != nil {
+		return "", "", err
+	}
+
+	now := time.Now()
+	signedState, err := u.keySet.Sign(oidcStateClaim{
+		Purpose:      oidcStatePurpose,
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		StandardClaims: jwt.StandardClaims{
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(oidcStateDuration).Unix(),
+		},
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	return authURL, signedState, nil
+}
+
+// Callback exchange code of provider, then login with user of the identity
+func (u *oidcUsecase) Callback(ctx context.Context, req web.OIDCCallbackRequest) (domain.AuthToken, error) {
+	if req.Error != "" {
+		return domain.AuthToken{}, fmt.Errorf("login rejected by provider: %s %s", req.Error, req.ErrorDescription)
+	}
+
+	// Verify state
+	var stateClaim oidcStateClaim
+	token, err := u.keySet.Parse(req.SignedState, &stateClaim)
+	if err != nil || !token.Valid || stateClaim.Purpose != oidcStatePurpose {
+		return domain.AuthToken{}, errors.New("invalid oidc state")
+	}
+
+	if req.Code == "" || req.State == "" || req.State != stateClaim.State {
+		return domain.AuthToken{}, errors.New("invalid oidc state")
+	}
+
+	identity, err := u.provider.Exchange(ctx, req.Code, stateClaim.CodeVerifier, stateClaim.Nonce)
+	if err != nil {
+		return domain.AuthToken{}, err
+	}
+
+	user, err := u.findOrProvision(ctx, identity)
+	if err != nil {
+		return domain.AuthToken{}, err
+	}
+
+	// Id token without second factor is rejected by provider mode
+	mfaVerified := u.config.OIDC_MFA_MODE == oidcMFAModeProvider
+	return u.userUsecase.IssueAuthToken(ctx, user, mfaVerified)
+}
+
+// findOrProvision find user of identity, role is synced with groups on every login
+func (u *oidcUsecase) findOrProvision(ctx context.Context, identity domain.ExternalIdentity) (domain.User, error) {
+	userIdentity, err := u.identityRepository.FindBySubject(ctx, identity.Issuer, identity.Subject)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	role, mapped := u.mapRole(identity.Groups)
+
+	// Known identity
+	if userIdentity.ID != "" {
+		user, err := u.userUsecase.FindOneByID(ctx, userIdentity.UserID)
+		if err != nil {
+			return user, err
+		}
+
+		if mapped && user.Role != role {
+			before := user
+			user.Role = role
+			user, err = u.userRepository.Update(ctx, user)
+			if err != nil {
+				return user, err
+			}
+
+			u.audit.Record(ContextWithAuditActor(ctx, user), domain.AuditActionRoleChange, domain.AuditEntityUser, user.ID, before, user)
+		}
+
+		return user, nil
+	}
+
+	if !u.config.OIDC_AUTO_PROVISION {
+		return domain.User{}, errors.New("user is not registered")
+	}
+
+	// Password is random, user of identity can only login with provider
+	password, err := randomToken(32)
+	if err != nil {
+		return domain.User{}, err
+	}
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	username := oidcUsername(identity)
+	fullname := strings.TrimSpace(identity.Fullname)
+	if fullname == "" {
+		fullname = username
+	}
+
+	for i := 0; ; i++ {
+		user, err := u.identityRepository.Provision(ctx, domain.User{
+			Fullname: fullname,
+			Username: username,
+			Password: string(passwordHash),
+			Role:     role,
+		}, domain.UserIdentity{
+			Issuer:  identity.Issuer,
+			Subject: identity.Subject,
+		})
+		if err == nil {
+			u.audit.Record(ContextWithAuditActor(ctx, user), domain.AuditActionRegister, domain.AuditEntityUser, user.ID, nil, user)
+			return user, nil
+		}
+
+		// Identity is provisioned by concurrent login
+		userIdentity, errFind := u.identityRepository.FindBySubject(ctx, identity.Issuer, identity.Subject)
+		if errFind == nil && userIdentity.ID != "" {
+			return u.userUsecase.FindOneByID(ctx, userIdentity.UserID)
+		}
+
+		// Username is used by other user, try with random suffix
+		if i == oidcProvisionRetry {
+			return user, err
+		}
+		if len(username) > oidcUsernameMaxLength-5 {
+			username = username[:oidcUsernameMaxLength-5]
+		}
+		username += util.RandomString(5)
+	}
+}
+
+// mapRole return role of the first mapping in OIDC_GROUP_ROLES matched with groups, false when groups is not sent
+func (u *oidcUsecase) mapRole(groups []string) (string, bool) {
+	defaultRole := u.config.OIDC_DEFAULT_ROLE
+	if defaultRole == "" {
+		defaultRole = "user"
+	}
+
+	if groups == nil || len(u.config.OIDC_GROUP_ROLES) == 0 {
+		return defaultRole, false
+	}
+
+	member := map[string]bool{}
+	for _, group := range groups {
+		member[group] = true
+	}
+
+	for _, mapping := range u.config.OIDC_GROUP_ROLES {
+		group, role, ok := strings.Cut(strings.TrimSpace(mapping), "=")
+		if ok && member[group] {
+			return role, true
+		}
+	}
+
+	return defaultRole, true
+}
+
+// oidcUsername create valid username from claim preferred_username or email
+func oidcUsername(identity domain.ExternalIdentity) string {
+	source := identity.Username
+	if source == "" {
+		source, _, _ = strings.Cut(identity.Email, "@")
+	}
+
+	var sb strings.Builder
+	for _, r := range strings.ToLower(source) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+		}
+	}
+
+	username := sb.String()
+	if len(username) > oidcUsernameMaxLength {
+		username = username[:oidcUsernameMaxLength]
+	}
+	if len(username) < oidcUsernameMinLength {
+		username += util.RandomString(oidcUsernameMinLength + 5 - len(username))
+	}
+
+	return username
+}
@@ -0,0 +1,35 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/letenk/pokedex/repository"
+)
+
+type PermissionUsecase interface {
+	HasPermission(ctx context.Context, role string, permission string) (bool, error)
+}
+
+type permissionUsecase struct {
+	repository repository.PermissionRepository
+}
+
+func NewUsecasePermission(repository repository.PermissionRepository) *permissionUsecase {
+	return &permissionUsecase{repository}
+}
+
+func (u *permissionUsecase) HasPermission(ctx context.Context, role string, permission string) (bool, error) {
+	// Find all permissions of role
+	permissions, err := u.repository.FindByRole(ctx, role)
+	if err != nil {
+		return false, err
+	}
+
+	for _, data := range permissions {
+		if data.Permission == permission {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,100 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// Timeout of command when context doesn't have deadline
+	redisTimeout = 5 * time.Second
+	// Keys of one SCAN, for delete by prefix
+	redisScanCount = 100
+)
+
+// redisCache keep values in redis, so the cache is shared by every instance
+type redisCache struct {
+	client    *redis.Client
+	keyPrefix string
+}
+
+func NewRedisCache(addr string, password string, db int, keyPrefix string) *redisCache {
+	client := redis.NewClient(&redis.Options{
+		Addr:         addr,
+		Password:     password,
+		DB:           db,
+		ReadTimeout:  redisTimeout,
+		WriteTimeout: redisTimeout,
+	})
+	return &redisCache{client, keyPrefix}
+}
+
+func (c *redisCache) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := c.client.Get(ctx, c.keyPrefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrCacheMiss
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+func (c *redisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.client.Set(ctx, c.keyPrefix+key, value, ttl).Err()
+}
+
+func (c *redisCache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	prefixed := make([]string, 0, len(keys))
+	for _, key := range keys {
+		prefixed = append(prefixed, c.keyPrefix+key)
+	}
+
+	return c.client.Del(ctx, prefixed...).Err()
+}
+
+func (c *redisCache) DeletePrefix(ctx context.Context, prefix string) error {
+	pattern := escapeRedisPattern(c.keyPrefix+prefix) + "*"
+
+	var cursor uint64
+	for {
+		keys, next, err := c.client.Scan(ctx, cursor, pattern, redisScanCount).Result()
+		if err != nil {
+			return err
+		}
+
+		if len(keys) != 0 {
+			err = c.client.Del(ctx, keys...).Err()
+			if err != nil {
+				return err
+			}
+		}
+
+		cursor = next
+		if cursor == 0 {
+			return nil
+		}
+	}
+}
+
+// escapeRedisPattern escape special characters of glob-style pattern
+func escapeRedisPattern(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			sb.WriteRune('\\')
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
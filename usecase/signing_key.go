@@ -0,0 +1,215 @@
+package usecase
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/letenk/pokedex/models/web"
+	"github.com/letenk/pokedex/util"
+)
+
+// signingMethodEdDSA sign token with ed25519, it is not provided by jwt-go
+type signingMethodEdDSA struct{}
+
+var SigningMethodEdDSA = &signingMethodEdDSA{}
+
+func init() {
+	jwt.RegisterSigningMethod(SigningMethodEdDSA.Alg(), func() jwt.SigningMethod {
+		return SigningMethodEdDSA
+	})
+}
+
+func (m *signingMethodEdDSA) Alg() string {
+	return "EdDSA"
+}
+
+func (m *signingMethodEdDSA) Verify(signingString, signature string, key interface{}) error {
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return jwt.ErrInvalidKeyType
+	}
+
+	sig, err := jwt.DecodeSegment(signature)
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(publicKey, []byte(signingString), sig) {
+		return errors.New("ed25519: verification error")
+	}
+
+	return nil
+}
+
+func (m *signingMethodEdDSA) Sign(signingString string, key interface{}) (string, error) {
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return "", jwt.ErrInvalidKeyType
+	}
+
+	return jwt.EncodeSegment(ed25519.Sign(privateKey, []byte(signingString))), nil
+}
+
+// signingKey is private key for sign token, identified by kid
+type signingKey struct {
+	kid        string
+	method     jwt.SigningMethod
+	privateKey crypto.PrivateKey
+	publicKey  crypto.PublicKey
+}
+
+// KeySet sign and verify access token.
+// Without JWT_KEYS_DIR token is signed by HS256 with JWT_SECRET_KEY, else every file `<kid>.pem`
+// inside the directory is RSA (RS256) or ed25519 (EdDSA) private key, token is signed by key JWT_ACTIVE_KID
+// and verified by any key, so the old key is kept in the directory until the tokens signed by it have expired.
+type KeySet struct {
+	secret    []byte
+	activeKID string
+	keys      map[string]signingKey
+}
+
+// NewKeySet create key set from config JWT_SECRET_KEY or JWT_KEYS_DIR and JWT_ACTIVE_KID
+func NewKeySet(config util.Config) (*KeySet, error) {
+	keySet := &KeySet{
+		secret: []byte(config.JWT_SECRET_KEY),
+		keys:   map[string]signingKey{},
+	}
+
+	if config.JWT_KEYS_DIR == "" {
+		return keySet, nil
+	}
+
+	// Load all private keys
+	files, err := filepath.Glob(filepath.Join(config.JWT_KEYS_DIR, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		kid := strings.TrimSuffix(filepath.Base(file), ".pem")
+		key, err := loadSigningKey(kid, file)
+		if err != nil {
+			return nil, err
+		}
+		keySet.keys[kid] = key
+	}
+
+	if _, ok := keySet.keys[config.JWT_ACTIVE_KID]; !ok {
+		return nil, fmt.Errorf("active key %s not found in %s", config.JWT_ACTIVE_KID, config.JWT_KEYS_DIR)
+	}
+	keySet.activeKID = config.JWT_ACTIVE_KID
+
+	return keySet, nil
+}
+
+// loadSigningKey parse PEM private key, PKCS8 or PKCS1 for RSA
+func loadSigningKey(kid string, file string) (signingKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return signingKey{}, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return signingKey{}, fmt.Errorf("key %s is not PEM encoded", kid)
+	}
+
+	var privateKey interface{}
+	if block.Type == "RSA PRIVATE KEY" {
+		privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		privateKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return signingKey{}, fmt.Errorf("key %s: %w", kid, err)
+	}
+
+	switch key := privateKey.(type) {
+	case *rsa.PrivateKey:
+		return signingKey{kid, jwt.SigningMethodRS256, key, &key.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return signingKey{kid, SigningMethodEdDSA, key, key.Public()}, nil
+	default:
+		return signingKey{}, fmt.Errorf("key %s must be RSA or ed25519", kid)
+	}
+}
+
+// Sign create signed token, header `kid` is set when signed by asymmetric key
+func (k *KeySet) Sign(claims jwt.Claims) (string, error) {
+	if k.activeKID == "" {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return token.SignedString(k.secret)
+	}
+
+	key := k.keys[k.activeKID]
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.kid
+	return token.SignedString(key.privateKey)
+}
+
+// Parse verify token with key of header `kid`, the algorithm must match the key
+func (k *KeySet) Parse(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if k.activeKID == "" {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, errors.New("invalid token")
+			}
+			return k.secret, nil
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		key, ok := k.keys[kid]
+		if !ok || token.Method.Alg() != key.method.Alg() {
+			return nil, errors.New("invalid token")
+		}
+		return key.publicKey, nil
+	})
+}
+
+// JSONWebKeys return public keys for endpoint jwks, empty when signed by secret
+func (k *KeySet) JSONWebKeys() []web.JSONWebKey {
+	jwks := []web.JSONWebKey{}
+
+	for _, key := range k.keys {
+		jwk := web.JSONWebKey{
+			KeyID:     key.kid,
+			Use:       "sig",
+			Algorithm: key.method.Alg(),
+		}
+
+		switch publicKey := key.publicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		}
+
+		jwks = append(jwks, jwk)
+	}
+
+	// Active key first, then by kid
+	sort.Slice(jwks, func(i, j int) bool {
+		if jwks[i].KeyID == k.activeKID || jwks[j].KeyID == k.activeKID {
+			return jwks[i].KeyID == k.activeKID
+		}
+		return jwks[i].KeyID < jwks[j].KeyID
+	})
+
+	return jwks
+}
@@ -0,0 +1,248 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/letenk/pokedex/models/domain"
+	"github.com/letenk/pokedex/models/web"
+)
+
+const (
+	defaultAccessTokenDuration  = 15 * time.Minute
+	defaultRefreshTokenDuration = 7 * 24 * time.Hour
+)
+
+type Claim struct {
+	UserID string `json:"user_id"`
+	// Empty for access token, token with purpose can only be used for the purpose
+	Purpose string `json:"purpose,omitempty"`
+	jwt.StandardClaims
+}
+
+func (s *userUsecase) RefreshToken(ctx context.Context, refreshToken string) (domain.AuthToken, error) {
+	// Find refresh token by hash
+	currentToken, err := s.tokenRepository.FindRefreshTokenByHash(ctx, hashToken(refreshToken))
+	if err != nil {
+		return domain.AuthToken{}, err
+	}
+
+	if currentToken.ID == "" {
+		return domain.AuthToken{}, errors.New("invalid refresh token")
+	}
+
+	// Refresh token is used again after rotated, revoke all the family because it could be stolen
+	if currentToken.RevokedAt != nil {
+		err := s.tokenRepository.RevokeFamily(ctx, currentToken.FamilyID)
+		if err != nil {
+			return domain.AuthToken{}, err
+		}
+		return domain.AuthToken{}, errors.New("refresh token has been revoked")
+	}
+
+	if time.Now().After(currentToken.ExpiresAt) {
+		return domain.AuthToken{}, errors.New("refresh token has expired")
+	}
+
+	// Rotate, only one of concurrent request can revoke the refresh token
+	ok, err := s.tokenRepository.RevokeRefreshToken(ctx, currentToken.ID)
+	if err != nil {
+		return domain.AuthToken{}, err
+	}
+
+	if !ok {
+		err := s.tokenRepository.RevokeFamily(ctx, currentToken.FamilyID)
+		if err != nil {
+			return domain.AuthToken{}, err
+		}
+		return domain.AuthToken{}, errors.New("refresh token has been revoked")
+	}
+
+	user, err := s.FindOneByID(ctx, currentToken.UserID)
+	if err != nil {
+		return domain.AuthToken{}, err
+	}
+
+	if user.DeactivatedAt != nil {
+		return domain.AuthToken{}, errors.New("user is deactivated")
+	}
+
+	// New token in the same family
+	return s.generateAuthToken(ctx, user, currentToken.FamilyID)
+}
+
+func (s *userUsecase) Logout(ctx context.Context, userID string, refreshToken string, claim Claim) (bool, error) {
+	// Revoke family of refresh token
+	if refreshToken != "" {
+		currentToken, err := s.tokenRepository.FindRefreshTokenByHash(ctx, hashToken(refreshToken))
+		if err != nil {
+			return false, err
+		}
+
+		if currentToken.ID == "" || currentToken.UserID != userID {
+			return false, errors.New("invalid refresh token")
+		}
+
+		err = s.tokenRepository.RevokeFamily(ctx, currentToken.FamilyID)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	// Access token in use is denied until it expires, empty when login with api key
+	if claim.Id != "" {
+		err := s.tokenRepository.RevokeAccessToken(ctx, domain.RevokedToken{
+			JTI:       claim.Id,
+			ExpiresAt: time.Unix(claim.ExpiresAt, 0),
+		})
+		if err != nil {
+			return false, err
+		}
+	}
+
+	s.audit.Record(ctx, domain.AuditActionLogout, domain.AuditEntityUser, userID, nil, nil)
+
+	return true, nil
+}
+
+func (s *userUsecase) Authenticate(ctx context.Context, tokenString string) (domain.User, Claim, error) {
+	// Parse token
+	var claim Claim
+	token, err := s.keySet.Parse(tokenString, &claim)
+
+	// If error
+	if err != nil {
+		return domain.User{}, claim, err
+	}
+
+	// If token invalid, or issued without jti
+	if !token.Valid || claim.UserID == "" || claim.Id == "" || claim.Purpose != "" {
+		return domain.User{}, claim, errors.New("invalid token")
+	}
+
+	// Check denylist
+	revoked, err := s.tokenRepository.IsAccessTokenRevoked(ctx, claim.Id)
+	if err != nil {
+		return domain.User{}, claim, err
+	}
+
+	if revoked {
+		return domain.User{}, claim, errors.New("token has been revoked")
+	}
+
+	// Find user on db
+	user, err := s.FindOneByID(ctx, claim.UserID)
+	if err != nil {
+		return domain.User{}, claim, err
+	}
+
+	// Token of deactivated user is still valid, but the user is rejected
+	if user.DeactivatedAt != nil {
+		return domain.User{}, claim, errors.New("user is deactivated")
+	}
+
+	return user, claim, nil
+}
+
+// IssueAuthToken generate token for user which is authenticated by other method, e.g. OIDC.
+// mfaVerified is true when the method has verified second factor, otherwise pending token is returned like login.
+func (s *userUsecase) IssueAuthToken(ctx context.Context, user domain.User, mfaVerified bool) (domain.AuthToken, error) {
+	if user.DeactivatedAt != nil {
+		return domain.AuthToken{}, errors.New("user is deactivated")
+	}
+
+	// Second factor is needed, the pending token must be exchanged with code
+	if !mfaVerified && (user.MFAEnabledAt != nil || s.mfaRequired(user)) {
+		return s.generateMFAToken(user)
+	}
+
+	s.audit.Record(ContextWithAuditActor(ctx, user), domain.AuditActionLogin, domain.AuditEntityUser, user.ID, nil, nil)
+
+	return s.generateAuthToken(ctx, user, "")
+}
+
+// JSONWebKeys return public keys for verify access token
+func (s *userUsecase) JSONWebKeys() []web.JSONWebKey {
+	return s.keySet.JSONWebKeys()
+}
+
+// generateAuthToken create access token and refresh token, empty familyID start new family
+func (s *userUsecase) generateAuthToken(ctx context.Context, user domain.User, familyID string) (domain.AuthToken, error) {
+	accessTokenDuration := s.config.ACCESS_TOKEN_DURATION
+	if accessTokenDuration == 0 {
+		accessTokenDuration = defaultAccessTokenDuration
+	}
+
+	refreshTokenDuration := s.config.REFRESH_TOKEN_DURATION
+	if refreshTokenDuration == 0 {
+		refreshTokenDuration = defaultRefreshTokenDuration
+	}
+
+	now := time.Now()
+	authToken := domain.AuthToken{
+		AccessTokenExpiresAt:  now.Add(accessTokenDuration),
+		RefreshTokenExpiresAt: now.Add(refreshTokenDuration),
+	}
+
+	// Create claim for payload token, jti is used for revoke the token
+	jti, err := randomToken(16)
+	if err != nil {
+		return authToken, err
+	}
+
+	claim := Claim{
+		UserID: user.ID,
+		StandardClaims: jwt.StandardClaims{
+			Id:        jti,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: authToken.AccessTokenExpiresAt.Unix(),
+		},
+	}
+
+	// Signed token with active key
+	authToken.AccessToken, err = s.keySet.Sign(claim)
+	if err != nil {
+		return authToken, err
+	}
+
+	// Refresh token is random string, only the hash is saved
+	authToken.RefreshToken, err = randomToken(32)
+	if err != nil {
+		return authToken, err
+	}
+
+	_, err = s.tokenRepository.CreateRefreshToken(ctx, domain.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(authToken.RefreshToken),
+		ExpiresAt: authToken.RefreshTokenExpiresAt,
+	})
+	if err != nil {
+		return authToken, err
+	}
+
+	return authToken, nil
+}
+
+// randomToken generate random string from n bytes
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken hash token with sha256, token is already random so no need salt
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,200 @@
+package usecase
+
+import (
+	"context"
+	"sort"
+
+	"github.com/letenk/pokedex/models/domain"
+	"github.com/letenk/pokedex/models/web"
+	"github.com/letenk/pokedex/repository"
+)
+
+type TypeEffectivenessUsecase interface {
+	Matchups(ctx context.Context, typeID string) (domain.Type, []domain.TypeEffectiveness, error)
+	Save(ctx context.Context, attackerTypeID string, defenderTypeID string, req web.TypeEffectivenessRequest) (domain.TypeEffectiveness, error)
+	Delete(ctx context.Context, attackerTypeID string, defenderTypeID string) (bool, error)
+	Weaknesses(ctx context.Context, monsterID string) (domain.Monster, domain.MonsterWeaknesses, error)
+}
+
+type typeEffectivenessUsecase struct {
+	repository        repository.TypeEffectivenessRepository
+	typeRepository    repository.TypeRepository
+	monsterRepository repository.MonsterRepository
+	audit             AuditUsecase
+}
+
+func NewUsecaseTypeEffectiveness(repository repository.TypeEffectivenessRepository, typeRepository repository.TypeRepository, monsterRepository repository.MonsterRepository, audit AuditUsecase) *typeEffectivenessUsecase {
+	return &typeEffectivenessUsecase{repository, typeRepository, monsterRepository, audit}
+}
+
+func (u *typeEffectivenessUsecase) Matchups(ctx context.Context, typeID string) (domain.Type, []domain.TypeEffectiveness, error) {
+	// Find by id
+	types, err := u.typeRepository.FindByID(ctx, typeID)
+	if err != nil {
+		return types, nil, err
+	}
+
+	// Find matchups as attacker and as defender
+	effectiveness, err := u.repository.FindByType(ctx, types.ID)
+	if err != nil {
+		return types, nil, err
+	}
+
+	return types, effectiveness, nil
+}
+
+func (u *typeEffectivenessUsecase) Save(ctx context.Context, attackerTypeID string, defenderTypeID string, req web.TypeEffectivenessRequest) (domain.TypeEffectiveness, error) {
+	// Both of type must exist
+	attacker, err := u.typeRepository.FindByID(ctx, attackerTypeID)
+	if err != nil {
+		return domain.TypeEffectiveness{}, err
+	}
+
+	defender, err := u.typeRepository.FindByID(ctx, defenderTypeID)
+	if err != nil {
+		return domain.TypeEffectiveness{}, err
+	}
+
+	// Current multiplier for audit log, save is create when not found
+	before, err := u.findEffectiveness(ctx, attacker.ID, defender.ID)
+	if err != nil {
+		return domain.TypeEffectiveness{}, err
+	}
+
+	effectiveness := domain.TypeEffectiveness{
+		AttackerTypeID: attacker.ID,
+		DefenderTypeID: defender.ID,
+		Multiplier:     *req.Multiplier,
+	}
+
+	// Save
+	effectiveness, err = u.repository.Save(ctx, effectiveness)
+	if err != nil {
+		return effectiveness, err
+	}
+
+	entityID := attacker.ID + ":" + defender.ID
+	if before == nil {
+		u.audit.Record(ctx, domain.AuditActionCreate, domain.AuditEntityTypeEffectiveness, entityID, nil, effectiveness)
+	} else {
+		u.audit.Record(ctx, domain.AuditActionUpdate, domain.AuditEntityTypeEffectiveness, entityID, before, effectiveness)
+	}
+
+	effectiveness.AttackerType = attacker
+	effectiveness.DefenderType = defender
+	return effectiveness, nil
+}
+
+func (u *typeEffectivenessUsecase) Delete(ctx context.Context, attackerTypeID string, defenderTypeID string) (bool, error) {
+	// Current multiplier for audit log
+	before, err := u.findEffectiveness(ctx, attackerTypeID, defenderTypeID)
+	if err != nil {
+		return false, err
+	}
+
+	// Delete
+	ok, err := u.repository.Delete(ctx, attackerTypeID, defenderTypeID)
+	if err != nil {
+		return false, err
+	}
+
+	if ok && before != nil {
+		u.audit.Record(ctx, domain.AuditActionDelete, domain.AuditEntityTypeEffectiveness, attackerTypeID+":"+defenderTypeID, before, nil)
+	}
+
+	return ok, nil
+}
+
+func (u *typeEffectivenessUsecase) Weaknesses(ctx context.Context, monsterID string) (domain.Monster, domain.MonsterWeaknesses, error) {
+	// Find monster with the types
+	monster, err := u.monsterRepository.FindByID(ctx, monsterID)
+	if err != nil {
+		return monster, domain.MonsterWeaknesses{}, err
+	}
+
+	var defenderTypeIDs []string
+	for _, t := range monster.Types {
+		defenderTypeIDs = append(defenderTypeIDs, t.ID)
+	}
+
+	weaknesses := domain.MonsterWeaknesses{
+		Weaknesses:  []domain.TypeMultiplier{},
+		Resistances: []domain.TypeMultiplier{},
+		Immunities:  []domain.TypeMultiplier{},
+	}
+
+	// Monster without type is hit normally by all types
+	if len(defenderTypeIDs) == 0 {
+		return monster, weaknesses, nil
+	}
+
+	effectiveness, err := u.repository.FindByDefenders(ctx, defenderTypeIDs)
+	if err != nil {
+		return monster, domain.MonsterWeaknesses{}, err
+	}
+
+	// Multiplier from each defender type is multiplied, pair without row is 1
+	multipliers := map[string]float64{}
+	for _, e := range effectiveness {
+		multiplier, ok := multipliers[e.AttackerTypeID]
+		if !ok {
+			multiplier = 1
+		}
+		multipliers[e.AttackerTypeID] = multiplier * e.Multiplier
+	}
+
+	// Attacker types
+	types, err := u.typeRepository.FindAll(ctx)
+	if err != nil {
+		return monster, domain.MonsterWeaknesses{}, err
+	}
+
+	for _, t := range types {
+		multiplier, ok := multipliers[t.ID]
+		if !ok {
+			continue
+		}
+
+		typeMultiplier := domain.TypeMultiplier{Type: t, Multiplier: multiplier}
+		switch {
+		case multiplier == 0:
+			weaknesses.Immunities = append(weaknesses.Immunities, typeMultiplier)
+		case multiplier < 1:
+			weaknesses.Resistances = append(weaknesses.Resistances, typeMultiplier)
+		case multiplier > 1:
+			weaknesses.Weaknesses = append(weaknesses.Weaknesses, typeMultiplier)
+		}
+	}
+
+	// Most effective first
+	sortTypeMultipliers(weaknesses.Weaknesses)
+	sortTypeMultipliers(weaknesses.Resistances)
+	sortTypeMultipliers(weaknesses.Immunities)
+
+	return monster, weaknesses, nil
+}
+
+// sortTypeMultipliers sort by multiplier descending, then name of type
+func sortTypeMultipliers(multipliers []domain.TypeMultiplier) {
+	sort.SliceStable(multipliers, func(i, j int) bool {
+		if multipliers[i].Multiplier != multipliers[j].Multiplier {
+			return multipliers[i].Multiplier > multipliers[j].Multiplier
+		}
+		return multipliers[i].Type.Name < multipliers[j].Type.Name
+	})
+}
+
+// findEffectiveness return multiplier from attacker type to defender type, nil when not set
+func (u *typeEffectivenessUsecase) findEffectiveness(ctx context.Context, attackerTypeID string, defenderTypeID string) (interface{}, error) {
+	effectiveness, err := u.repository.FindByType(ctx, attackerTypeID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range effectiveness {
+		if e.AttackerTypeID == attackerTypeID && e.DefenderTypeID == defenderTypeID {
+			return e, nil
+		}
+	}
+	return nil, nil
+}
@@ -2,21 +2,30 @@ package usecase
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/letenk/pokedex/models/domain"
+	"github.com/letenk/pokedex/models/web"
 	"github.com/letenk/pokedex/repository"
 )
 
 type TypeUsecase interface {
 	FindAll(ctx context.Context) ([]domain.Type, error)
+	FindByID(ctx context.Context, ID string) (domain.Type, error)
+	Create(ctx context.Context, req web.TypeRequest) (domain.Type, error)
+	Update(ctx context.Context, ID string, req web.TypeRequest) (domain.Type, error)
+	Delete(ctx context.Context, ID string, cascade bool) (bool, error)
 }
 
 type typeUsecase struct {
 	repository repository.TypeRepository
+	audit      AuditUsecase
 }
 
-func NewUsecaseType(repository repository.TypeRepository) *typeUsecase {
-	return &typeUsecase{repository}
+func NewUsecaseType(repository repository.TypeRepository, audit AuditUsecase) *typeUsecase {
+	return &typeUsecase{repository, audit}
 }
 
 func (u *typeUsecase) FindAll(ctx context.Context) ([]domain.Type, error) {
@@ -29,3 +38,90 @@ func (u *typeUsecase) FindAll(ctx context.Context) ([]domain.Type, error) {
 
 	return types, nil
 }
+
+func (u *typeUsecase) FindByID(ctx context.Context, ID string) (domain.Type, error) {
+	// Find by id
+	types, err := u.repository.FindByID(ctx, ID)
+	if err != nil {
+		return types, err
+	}
+
+	return types, nil
+}
+
+func (u *typeUsecase) Create(ctx context.Context, req web.TypeRequest) (domain.Type, error) {
+	// Name of type is upper case, e.g. GRASS
+	types := domain.Type{
+		Name: strings.ToUpper(strings.TrimSpace(req.Name)),
+	}
+
+	if types.Name == "" {
+		return types, errors.New("name can not be empty")
+	}
+
+	// Create
+	types, err := u.repository.Create(ctx, types)
+	if err != nil {
+		return types, err
+	}
+
+	u.audit.Record(ctx, domain.AuditActionCreate, domain.AuditEntityType, types.ID, nil, types)
+
+	return types, nil
+}
+
+func (u *typeUsecase) Update(ctx context.Context, ID string, req web.TypeRequest) (domain.Type, error) {
+	// Find by id
+	types, err := u.repository.FindByID(ctx, ID)
+	if err != nil {
+		return types, err
+	}
+	before := types
+
+	types.Name = strings.ToUpper(strings.TrimSpace(req.Name))
+	if types.Name == "" {
+		return types, errors.New("name can not be empty")
+	}
+
+	// Rename
+	types, err = u.repository.Update(ctx, types)
+	if err != nil {
+		return types, err
+	}
+
+	u.audit.Record(ctx, domain.AuditActionUpdate, domain.AuditEntityType, types.ID, before, types)
+
+	return types, nil
+}
+
+func (u *typeUsecase) Delete(ctx context.Context, ID string, cascade bool) (bool, error) {
+	// Find by id
+	types, err := u.repository.FindByID(ctx, ID)
+	if err != nil {
+		return false, err
+	}
+
+	// Refuse delete type which is still used, except cascade is requested
+	if !cascade {
+		count, err := u.repository.CountMonsters(ctx, types.ID)
+		if err != nil {
+			return false, err
+		}
+
+		if count != 0 {
+			return false, fmt.Errorf("type is still used by %d monsters, please delete with cascade for remove the type from monsters", count)
+		}
+	}
+
+	// Delete
+	ok, err := u.repository.Delete(ctx, types, cascade)
+	if err != nil {
+		return false, err
+	}
+
+	if ok {
+		u.audit.Record(ctx, domain.AuditActionDelete, domain.AuditEntityType, types.ID, types, nil)
+	}
+
+	return ok, nil
+}
@@ -5,9 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/letenk/pokedex/models/domain"
 	"github.com/letenk/pokedex/models/web"
 	"github.com/letenk/pokedex/repository"
@@ -16,74 +16,100 @@ import (
 )
 
 type UserUsecase interface {
-	Login(ctx context.Context, req web.UserLoginRequest) (string, error)
+	Login(ctx context.Context, req web.UserLoginRequest) (domain.AuthToken, error)
+	RefreshToken(ctx context.Context, refreshToken string) (domain.AuthToken, error)
+	Logout(ctx context.Context, userID string, refreshToken string, claim Claim) (bool, error)
+	Authenticate(ctx context.Context, tokenString string) (domain.User, Claim, error)
+	JSONWebKeys() []web.JSONWebKey
 	FindOneByID(ctx context.Context, id string) (domain.User, error)
-}
-
-type Claim struct {
-	UserID string `json:"user_id"`
-	jwt.StandardClaims
+	Register(ctx context.Context, req web.UserRegisterRequest) (domain.User, error)
+	UpdateProfile(ctx context.Context, id string, req web.UserUpdateRequest) (domain.User, error)
+	ChangePassword(ctx context.Context, id string, req web.UserChangePasswordRequest) (bool, error)
+	FindAll(ctx context.Context, reqQuery web.UserQueryRequest) ([]domain.User, web.Pagination, error)
+	Create(ctx context.Context, req web.UserCreateRequest) (domain.User, error)
+	ChangeRole(ctx context.Context, id string, req web.UserRoleRequest) (domain.User, error)
+	ResetPassword(ctx context.Context, id string, req web.UserResetPasswordRequest) (bool, error)
+	Deactivate(ctx context.Context, id string, currentUserID string) (domain.User, error)
+	Activate(ctx context.Context, id string) (domain.User, error)
+	Unlock(ctx context.Context, id string) (domain.User, error)
+	EnrollMFA(ctx context.Context, id string, req web.MFAEnrollRequest) (web.MFAEnrollResponse, error)
+	ConfirmMFA(ctx context.Context, id string, req web.MFACodeRequest) ([]string, error)
+	DisableMFA(ctx context.Context, id string, req web.MFADisableRequest) (bool, error)
+	EnrollMFAWithToken(ctx context.Context, req web.MFAEnrollLoginRequest) (web.MFAEnrollResponse, error)
+	LoginMFA(ctx context.Context, req web.MFALoginRequest) (domain.AuthToken, []string, error)
+	IssueAuthToken(ctx context.Context, user domain.User, mfaVerified bool) (domain.AuthToken, error)
 }
 
 type userUsecase struct {
-	repository repository.UserRepository
+	repository             repository.UserRepository
+	tokenRepository        repository.TokenRepository
+	permissionRepository   repository.PermissionRepository
+	loginAttemptRepository repository.LoginAttemptRepository
+	mfaRepository          repository.MFARepository
+	audit                  AuditUsecase
+	keySet                 *KeySet
+	config                 util.Config
 }
 
-func NewUsecaseUser(repository repository.UserRepository) *userUsecase {
-	return &userUsecase{repository}
+func NewUsecaseUser(repository repository.UserRepository, tokenRepository repository.TokenRepository, permissionRepository repository.PermissionRepository, loginAttemptRepository repository.LoginAttemptRepository, mfaRepository repository.MFARepository, audit AuditUsecase, keySet *KeySet, config util.Config) *userUsecase {
+	return &userUsecase{repository, tokenRepository, permissionRepository, loginAttemptRepository, mfaRepository, audit, keySet, config}
 }
 
-func (s *userUsecase) Login(ctx context.Context, req web.UserLoginRequest) (string, error) {
+func (s *userUsecase) Login(ctx context.Context, req web.UserLoginRequest) (domain.AuthToken, error) {
 	// Get payload
 	username := req.Username
 	password := req.Password
 
+	// Refuse before compare password when username or ip has too many failed attempts,
+	// the attempt is counted as failure until the password is matched
+	limits := s.loginAttemptLimits(username, req.IP)
+	err := s.reserveLoginAttempt(ctx, limits)
+	if err != nil {
+		return domain.AuthToken{}, err
+	}
+
 	// Find user by username
 	user, err := s.repository.FindByUsername(ctx, username)
-	if user.ID == "" {
-		return "", errors.New("username or password incorrect")
+	if err != nil {
+		s.releaseLoginAttempt(ctx, limits)
+		return domain.AuthToken{}, err
 	}
 
-	// Other error
-	if err != nil {
-		log.Println(err)
+	if user.ID == "" {
+		// Compare with dummy hash, so unknown username takes the same time as wrong password
+		checkPassword(domain.User{Password: dummyPasswordHash}, password)
+		// Actor is unknown, the username is saved as change
+		s.audit.Record(ctx, domain.AuditActionLoginFailed, domain.AuditEntityUser, "", nil, map[string]string{"Username": username})
+		return domain.AuthToken{}, errors.New("username or password incorrect")
 	}
 
 	// If user is available, compare password hash with password from request use bcrypt
-	err = bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+	err = checkPassword(user, password)
 	if err != nil {
-		return "", errors.New("username or password incorrect")
+		s.audit.Record(ContextWithAuditActor(ctx, user), domain.AuditActionLoginFailed, domain.AuditEntityUser, user.ID, nil, nil)
+		return domain.AuthToken{}, errors.New("username or password incorrect")
 	}
 
-	// If username and password is matched, generate token
-	// Create 1 day
-	expirationTime := time.Now().AddDate(0, 0, 1)
-
-	// Create clain for payload token
-	claim := Claim{
-		UserID: user.ID,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: expirationTime.Unix(),
-		},
+	// Failures of username start again after success login, failures of ip expire with the lockout
+	s.releaseLoginAttempt(ctx, limits)
+	err = s.loginAttemptRepository.Delete(ctx, usernameAttemptKey(username))
+	if err != nil {
+		log.Println(err)
 	}
 
-	// Create token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claim)
-
-	// Load Config
-	config, err := util.LoadConfig("../.")
-	if err != nil {
-		log.Fatal("cannot load config:", err)
+	if user.DeactivatedAt != nil {
+		return domain.AuthToken{}, errors.New("user is deactivated")
 	}
 
-	// Signed token with secret key
-	signedToken, err := token.SignedString([]byte(config.JWT_SECRET_KEY))
-	if err != nil {
-		return signedToken, err
+	// Second factor is needed, the pending token must be exchanged with code
+	if user.MFAEnabledAt != nil || s.mfaRequired(user) {
+		return s.generateMFAToken(user)
 	}
 
-	// If success, return token
-	return signedToken, nil
+	s.audit.Record(ContextWithAuditActor(ctx, user), domain.AuditActionLogin, domain.AuditEntityUser, user.ID, nil, nil)
+
+	// If username and password is matched, generate token with new family of refresh token
+	return s.generateAuthToken(ctx, user, "")
 }
 
 func (s *userUsecase) FindOneByID(ctx context.Context, id string) (domain.User, error) {
@@ -102,3 +128,307 @@ func (s *userUsecase) FindOneByID(ctx context.Context, id string) (domain.User,
 
 	return user, nil
 }
+
+func (s *userUsecase) Register(ctx context.Context, req web.UserRegisterRequest) (domain.User, error) {
+	// Hash password
+	passwordHash, err := hashPassword(req.Password)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	// New user always has role user
+	user := domain.User{
+		Fullname: strings.TrimSpace(req.Fullname),
+		Username: req.Username,
+		Password: passwordHash,
+		Role:     "user",
+	}
+
+	if user.Fullname == "" {
+		return user, errors.New("fullname can not be empty")
+	}
+
+	// Create, username is unique on db
+	user, err = s.repository.Create(ctx, user)
+	if err != nil {
+		return user, err
+	}
+
+	s.audit.Record(ContextWithAuditActor(ctx, user), domain.AuditActionRegister, domain.AuditEntityUser, user.ID, nil, user)
+
+	return user, nil
+}
+
+func (s *userUsecase) UpdateProfile(ctx context.Context, id string, req web.UserUpdateRequest) (domain.User, error) {
+	// Find user login
+	user, err := s.FindOneByID(ctx, id)
+	if err != nil {
+		return user, err
+	}
+	before := user
+
+	// Update field which is not empty
+	if req.Fullname != "" {
+		user.Fullname = strings.TrimSpace(req.Fullname)
+		if user.Fullname == "" {
+			return user, errors.New("fullname can not be empty")
+		}
+	}
+
+	if req.Username != "" {
+		user.Username = req.Username
+	}
+
+	// Update
+	user, err = s.repository.Update(ctx, user)
+	if err != nil {
+		return user, err
+	}
+
+	s.audit.Record(ctx, domain.AuditActionUpdate, domain.AuditEntityUser, user.ID, before, user)
+
+	return user, nil
+}
+
+func (s *userUsecase) ChangePassword(ctx context.Context, id string, req web.UserChangePasswordRequest) (bool, error) {
+	// Find user login
+	user, err := s.FindOneByID(ctx, id)
+	if err != nil {
+		return false, err
+	}
+
+	// Old password must match, same as login
+	err = checkPassword(user, req.OldPassword)
+	if err != nil {
+		return false, errors.New("old password incorrect")
+	}
+
+	// Hash new password
+	user.Password, err = hashPassword(req.NewPassword)
+	if err != nil {
+		return false, err
+	}
+
+	// Update
+	_, err = s.repository.Update(ctx, user)
+	if err != nil {
+		return false, err
+	}
+
+	// Sessions with the old password must login again
+	err = s.tokenRepository.RevokeByUserID(ctx, user.ID)
+	if err != nil {
+		return false, err
+	}
+
+	s.audit.Record(ctx, domain.AuditActionPasswordChange, domain.AuditEntityUser, user.ID, nil, nil)
+
+	return true, nil
+}
+
+func (s *userUsecase) FindAll(ctx context.Context, reqQuery web.UserQueryRequest) ([]domain.User, web.Pagination, error) {
+	// Find all
+	users, pagination, err := s.repository.FindAll(ctx, reqQuery)
+	if err != nil {
+		return users, pagination, err
+	}
+
+	return users, pagination, nil
+}
+
+func (s *userUsecase) Create(ctx context.Context, req web.UserCreateRequest) (domain.User, error) {
+	// Role must be one of role in permissions
+	err := s.checkRole(ctx, req.Role)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	// Hash password
+	passwordHash, err := hashPassword(req.Password)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	user := domain.User{
+		Fullname: strings.TrimSpace(req.Fullname),
+		Username: req.Username,
+		Password: passwordHash,
+		Role:     req.Role,
+	}
+
+	if user.Fullname == "" {
+		return user, errors.New("fullname can not be empty")
+	}
+
+	// Create
+	user, err = s.repository.Create(ctx, user)
+	if err != nil {
+		return user, err
+	}
+
+	s.audit.Record(ctx, domain.AuditActionCreate, domain.AuditEntityUser, user.ID, nil, user)
+
+	return user, nil
+}
+
+func (s *userUsecase) ChangeRole(ctx context.Context, id string, req web.UserRoleRequest) (domain.User, error) {
+	// Find by id
+	user, err := s.FindOneByID(ctx, id)
+	if err != nil {
+		return user, err
+	}
+
+	// Role must be one of role in permissions
+	err = s.checkRole(ctx, req.Role)
+	if err != nil {
+		return user, err
+	}
+
+	// Update
+	before := user
+	user.Role = req.Role
+	user, err = s.repository.UpdateKeepAdmin(ctx, user)
+	if err != nil {
+		return user, err
+	}
+
+	s.audit.Record(ctx, domain.AuditActionRoleChange, domain.AuditEntityUser, user.ID, before, user)
+
+	return user, nil
+}
+
+func (s *userUsecase) ResetPassword(ctx context.Context, id string, req web.UserResetPasswordRequest) (bool, error) {
+	// Find by id
+	user, err := s.FindOneByID(ctx, id)
+	if err != nil {
+		return false, err
+	}
+
+	// Hash new password
+	user.Password, err = hashPassword(req.Password)
+	if err != nil {
+		return false, err
+	}
+
+	// Update
+	_, err = s.repository.Update(ctx, user)
+	if err != nil {
+		return false, err
+	}
+
+	// Login again with the new password
+	err = s.tokenRepository.RevokeByUserID(ctx, user.ID)
+	if err != nil {
+		return false, err
+	}
+
+	s.audit.Record(ctx, domain.AuditActionPasswordReset, domain.AuditEntityUser, user.ID, nil, nil)
+
+	return true, nil
+}
+
+func (s *userUsecase) Deactivate(ctx context.Context, id string, currentUserID string) (domain.User, error) {
+	if id == currentUserID {
+		return domain.User{}, errors.New("can not deactivate your own account")
+	}
+
+	// Find by id
+	user, err := s.FindOneByID(ctx, id)
+	if err != nil {
+		return user, err
+	}
+
+	if user.DeactivatedAt != nil {
+		return user, nil
+	}
+
+	// Update
+	before := user
+	now := time.Now()
+	user.DeactivatedAt = &now
+	user, err = s.repository.UpdateKeepAdmin(ctx, user)
+	if err != nil {
+		return user, err
+	}
+
+	// Refresh token can not be used anymore, access token is rejected by auth middleware
+	err = s.tokenRepository.RevokeByUserID(ctx, user.ID)
+	if err != nil {
+		return user, err
+	}
+
+	s.audit.Record(ctx, domain.AuditActionDeactivate, domain.AuditEntityUser, user.ID, before, user)
+
+	return user, nil
+}
+
+func (s *userUsecase) Activate(ctx context.Context, id string) (domain.User, error) {
+	// Find by id
+	user, err := s.FindOneByID(ctx, id)
+	if err != nil {
+		return user, err
+	}
+
+	// Update
+	before := user
+	user.DeactivatedAt = nil
+	user, err = s.repository.Update(ctx, user)
+	if err != nil {
+		return user, err
+	}
+
+	s.audit.Record(ctx, domain.AuditActionActivate, domain.AuditEntityUser, user.ID, before, user)
+
+	return user, nil
+}
+
+// Unlock clear failed login attempts of user, so the user can login again before lockout expire.
+// Lockout of ip is not cleared, ip is not known by the user and it expires with the lockout
+func (s *userUsecase) Unlock(ctx context.Context, id string) (domain.User, error) {
+	// Find by id
+	user, err := s.FindOneByID(ctx, id)
+	if err != nil {
+		return user, err
+	}
+
+	err = s.loginAttemptRepository.Delete(ctx, usernameAttemptKey(user.Username))
+	if err != nil {
+		return user, err
+	}
+
+	s.audit.Record(ctx, domain.AuditActionUnlock, domain.AuditEntityUser, user.ID, nil, nil)
+
+	return user, nil
+}
+
+// checkRole return error when role doesn't have any permission
+func (s *userUsecase) checkRole(ctx context.Context, role string) error {
+	ok, err := s.permissionRepository.RoleExists(ctx, role)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return fmt.Errorf("role %s does not exist", role)
+	}
+
+	return nil
+}
+
+// dummyPasswordHash is bcrypt hash with default cost, compared when username is not found
+const dummyPasswordHash = "$2a$10$niXY.d6Rn35cU1/Zv1vmveo2.ufRz9CbYypG8RWszdy4uYM3TIyjy"
+
+// checkPassword compare password hash of user with password use bcrypt
+func checkPassword(user domain.User, password string) error {
+	return bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+}
+
+// hashPassword generate password hash use bcrypt
+func hashPassword(password string) (string, error) {
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(passwordHash), nil
+}
@@ -1,6 +1,10 @@
 package util
 
-import "github.com/spf13/viper"
+import (
+	"time"
+
+	"github.com/spf13/viper"
+)
 
 // Config stores all configuration of the application
 type Config struct {
@@ -13,6 +17,61 @@ type Config struct {
 	AWS_KEY_ID      string `mapstructure:"AWS_KEY_ID"`
 	AWS_SECRET_KEY  string `mapstructure:"AWS_SECRET_KEY"`
 	AWS_BUCKET_NAME string `mapstructure:"AWS_BUCKET_NAME"`
+	IMAGE_STORAGE   string `mapstructure:"IMAGE_STORAGE"`
+	IMAGE_LOCAL_DIR string `mapstructure:"IMAGE_LOCAL_DIR"`
+	IMAGE_BASE_URL  string `mapstructure:"IMAGE_BASE_URL"`
+
+	// Lifetime of access token and refresh token, e.g. 15m or 168h
+	ACCESS_TOKEN_DURATION  time.Duration `mapstructure:"ACCESS_TOKEN_DURATION"`
+	REFRESH_TOKEN_DURATION time.Duration `mapstructure:"REFRESH_TOKEN_DURATION"`
+
+	// Directory of private keys `<kid>.pem` and kid of key for sign token, empty means HS256 with JWT_SECRET_KEY
+	JWT_KEYS_DIR   string `mapstructure:"JWT_KEYS_DIR"`
+	JWT_ACTIVE_KID string `mapstructure:"JWT_ACTIVE_KID"`
+
+	// Failed login before lockout per username and per ip, and how long the lockout is
+	LOGIN_MAX_ATTEMPTS        int           `mapstructure:"LOGIN_MAX_ATTEMPTS"`
+	LOGIN_MAX_ATTEMPTS_PER_IP int           `mapstructure:"LOGIN_MAX_ATTEMPTS_PER_IP"`
+	LOGIN_LOCKOUT_DURATION    time.Duration `mapstructure:"LOGIN_LOCKOUT_DURATION"`
+
+	// Ip or cidr of reverse proxies allowed to set X-Forwarded-For, separated by comma, empty trust nothing
+	TRUSTED_PROXIES []string `mapstructure:"TRUSTED_PROXIES"`
+
+	// Roles which must login with TOTP, separated by comma, and issuer shown on authenticator app
+	MFA_REQUIRED_ROLES []string `mapstructure:"MFA_REQUIRED_ROLES"`
+	MFA_ISSUER         string   `mapstructure:"MFA_ISSUER"`
+
+	// OpenID Connect login, disabled when OIDC_ISSUER is empty.
+	// OIDC_GROUP_ROLES map group of claim OIDC_GROUPS_CLAIM to role, e.g. pokedex-admins=admin,pokedex-users=user
+	OIDC_ISSUER         string   `mapstructure:"OIDC_ISSUER"`
+	OIDC_CLIENT_ID      string   `mapstructure:"OIDC_CLIENT_ID"`
+	OIDC_CLIENT_SECRET  string   `mapstructure:"OIDC_CLIENT_SECRET"`
+	OIDC_REDIRECT_URL   string   `mapstructure:"OIDC_REDIRECT_URL"`
+	OIDC_SCOPES         []string `mapstructure:"OIDC_SCOPES"`
+	OIDC_AUTO_PROVISION bool     `mapstructure:"OIDC_AUTO_PROVISION"`
+	OIDC_DEFAULT_ROLE   string   `mapstructure:"OIDC_DEFAULT_ROLE"`
+	OIDC_GROUPS_CLAIM   string   `mapstructure:"OIDC_GROUPS_CLAIM"`
+	OIDC_GROUP_ROLES    []string `mapstructure:"OIDC_GROUP_ROLES"`
+
+	// Second factor of OIDC login for user which needs MFA. `local` (default) ask TOTP of pokedex like login with password,
+	// `provider` trust the provider and reject id token without claim amr `mfa` or claim acr of OIDC_MFA_ACR_VALUES
+	OIDC_MFA_MODE       string   `mapstructure:"OIDC_MFA_MODE"`
+	OIDC_MFA_ACR_VALUES []string `mapstructure:"OIDC_MFA_ACR_VALUES"`
+
+	// Cache of responses, memory is only shared inside one instance. CACHE_SIZE_LIMIT is max of keys
+	// in memory, size of redis is limited by its maxmemory, every key is set with time to live
+	CACHE_DRIVER     string `mapstructure:"CACHE_DRIVER"`
+	CACHE_SIZE_LIMIT int    `mapstructure:"CACHE_SIZE_LIMIT"`
+	REDIS_ADDR       string `mapstructure:"REDIS_ADDR"`
+	REDIS_PASSWORD   string `mapstructure:"REDIS_PASSWORD"`
+	REDIS_DB         int    `mapstructure:"REDIS_DB"`
+	REDIS_KEY_PREFIX string `mapstructure:"REDIS_KEY_PREFIX"`
+
+	// Deleted monsters stay in trash for retention period, trash is purged on every interval
+	// by instances which enable the purge
+	MONSTER_PURGE_ENABLED   bool          `mapstructure:"MONSTER_PURGE_ENABLED"`
+	MONSTER_TRASH_RETENTION time.Duration `mapstructure:"MONSTER_TRASH_RETENTION"`
+	MONSTER_PURGE_INTERVAL  time.Duration `mapstructure:"MONSTER_PURGE_INTERVAL"`
 }
 
 // LoadConfig reads configuration from file or environment variables.
@@ -0,0 +1,97 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP of RFC 6238 with SHA1, 6 digits and 30 seconds period, supported by common authenticator apps
+const (
+	totpPeriod = 30
+	totpDigits = 6
+	// Code of previous and next period is accepted, for clock drift of phone
+	totpSkew = 1
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret generates random secret encoded with base32
+func GenerateTOTPSecret() (string, error) {
+	b := make([]byte, 20)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return totpEncoding.EncodeToString(b), nil
+}
+
+// TOTPStep return counter of period at time t
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / totpPeriod
+}
+
+// TOTPCode generates code of secret for counter step
+func TOTPCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000), nil
+}
+
+// ValidateTOTP check code at time t, return the step of code for prevent the code used again
+func ValidateTOTP(secret string, code string, t time.Time) (int64, bool) {
+	current := TOTPStep(t)
+
+	for skew := int64(-totpSkew); skew <= totpSkew; skew++ {
+		expected, err := TOTPCode(secret, current+skew)
+		if err != nil {
+			return 0, false
+		}
+
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return current + skew, true
+		}
+	}
+
+	return 0, false
+}
+
+// TOTPURI return uri `otpauth://` for QR code of authenticator apps
+func TOTPURI(issuer string, account string, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(totpPeriod))
+
+	uri := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + issuer + ":" + account,
+		RawQuery: query.Encode(),
+	}
+	return uri.String()
+}